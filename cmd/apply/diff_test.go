@@ -0,0 +1,110 @@
+package apply
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/schema"
+)
+
+func TestResolveDiffSide(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.sql")
+	snapshot := &schema.Snapshot{Schemas: []string{"billing"}}
+	if err := snapshot.WriteFile(path); err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+	t.Setenv("DIFF_TEST_DATABASE_URL", "postgres://env@localhost/db")
+
+	testCases := []struct {
+		name       string
+		value      string
+		snapshot   bool
+		connString string
+		expected   any
+	}{
+		{name: "Snapshot_File", value: path, snapshot: true},
+		{name: "Url", value: "postgres://user@localhost/db", connString: "postgres://user@localhost/db"},
+		{name: "Key_Value", value: "host=localhost dbname=db", connString: "host=localhost dbname=db"},
+		{name: "Env_Key", value: "DIFF_TEST_DATABASE_URL", connString: "postgres://env@localhost/db"},
+		{name: "Unknown", value: "NOT_A_FILE_OR_KEY", expected: "0124"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			side, err := ResolveSchemaSource(tc.value)
+			if tc.expected != nil {
+				var actual *er.SchemerErr
+				if !errors.As(err, &actual) || actual.Code != tc.expected {
+					t.Fatalf("expected %v recieved %v", tc.expected, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (side.Snapshot != nil) != tc.snapshot || side.ConnString != tc.connString {
+				t.Fatalf("unexpected side: %+v", side)
+			}
+		})
+	}
+}
+
+func TestExecuteDiffCommand_Snapshots(t *testing.T) {
+	dir := t.TempDir()
+	source := &schema.Snapshot{Schemas: []string{"public"}, Objects: []schema.Object{
+		{Kind: schema.KindTable, Name: "public.users", Definition: "CREATE TABLE public.users (\n    id integer\n);"},
+	}}
+	target := &schema.Snapshot{Schemas: []string{"public"}, Objects: []schema.Object{
+		{Kind: schema.KindTable, Name: "public.users", Definition: "CREATE TABLE public.users (\n    id bigint\n);"},
+	}}
+	if err := source.WriteFile(filepath.Join(dir, "source.sql")); err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+	if err := target.WriteFile(filepath.Join(dir, "target.sql")); err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+
+	diffOptions = DiffOptions{Source: filepath.Join(dir, "source.sql"), Target: filepath.Join(dir, "target.sql")}
+	t.Cleanup(func() { diffOptions = DiffOptions{} })
+
+	changes, err := executeDiffCommand(false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Type != schema.ChangeChanged {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+
+	var text bytes.Buffer
+	if err := writeDiff(&text, "text", changes); err != nil {
+		t.Fatalf("failed to write text diff: %v", err)
+	}
+	if !strings.Contains(text.String(), "~ TABLE public.users\n") || !strings.Contains(text.String(), "+     id bigint") {
+		t.Fatalf("unexpected text diff:\n%s", text.String())
+	}
+
+	var report bytes.Buffer
+	if err := writeDiff(&report, "json", changes); err != nil {
+		t.Fatalf("failed to write json diff: %v", err)
+	}
+	var decoded struct {
+		Changes []schema.Change `json:"changes"`
+	}
+	if err := json.Unmarshal(report.Bytes(), &decoded); err != nil || len(decoded.Changes) != 1 {
+		t.Fatalf("unexpected json diff: %s", report.String())
+	}
+
+	diffOptions.Target = ""
+	if _, err := executeDiffCommand(false); err == nil {
+		t.Fatalf("expected error when --target is missing")
+	}
+	if err := writeDiff(os.Stdout, "yaml", nil); err == nil {
+		t.Fatalf("expected error for unknown format")
+	}
+}
@@ -27,9 +27,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
-	"regexp"
-	"sort"
-	"strconv"
+	"slices"
 	"strings"
 
 	"github.com/jackc/pgx/v5"
@@ -59,35 +57,26 @@ Examples:
   schemer down --from 005 --to 003    # Roll back from 005 down to 003
   schemer down --cherry-pick 001,004  # Roll back only 001 and 004
 `,
-		Run: func(command *cobra.Command, args []string) {
-			if cmd.RootCmd.PersistentPreRun != nil {
-				cmd.RootCmd.PersistentPreRun(command, args)
-			}
-
-			_, err := utils.LoadDotEnv()
-			if err != nil {
-				glog.Error("%v", err)
+		RunE: func(command *cobra.Command, args []string) error {
+			if _, err := utils.LoadDotEnv(); err != nil {
+				return err
 			}
 
 			if err := parseApplyCommand(&downRequest); err != nil {
-				glog.Error("%v", err)
-				return
+				return err
 			}
 
-			if shouldOnlyApplyLast() {
-				err := utils.WithConn(downRequest.connString, applyForLastUpDelta)
-				if err != nil {
-					glog.Error("%v", err)
-					return
+			// A dry run only prints the deltas, see reportDownDryRun.
+			if !downRequest.dryRun {
+				if err := cmd.CheckProtected("down"); err != nil {
+					return err
 				}
-				return
 			}
 
-			err = utils.WithConn(downRequest.connString, executeDownCommand)
-			if err != nil {
-				glog.Error("%v", err)
-				return
+			if shouldOnlyApplyLast() {
+				return utils.WithConn(downRequest.connString, withSnapshot(&downRequest, applyForLastUpDelta))
 			}
+			return utils.WithConn(downRequest.connString, withSnapshot(&downRequest, executeDownCommand))
 		},
 	}
 )
@@ -100,22 +89,26 @@ func init() {
 	downCmd.PersistentFlags().BoolVar(&downRequest.PruneNoOp, "prune", false, `Enable no-operation file prunning. Scan delta files and skip applying files
 that only contains comments and empty lines. This can be useful for large replays to avoid unnessecarry database calls.`)
 	downCmd.PersistentFlags().StringVarP(&downRequest.toTag, "to", "t", "", `Specify the version to end at. Accepted formats are: 
-  4   - No Padding
-  004 - Padded zeros`)
+  4           - No Padding
+  004         - Padded zeros
+  release-2.3 - Label defined in the deltas directory labels.txt`)
 
 	downCmd.PersistentFlags().StringVarP(&downRequest.fromTag, "from", "f", "", `Specify the version to begin at. Accepted formats are:
-  4   - No Padding
-  004 - Padded zeros`)
+  4           - No Padding
+  004         - Padded zeros
+  release-2.3 - Label defined in the deltas directory labels.txt`)
 	downCmd.PersistentFlags().StringArrayVarP(&downRequest.cherryPickedVersions, "cherry-pick", "c", nil, `Specify deltas to execute againg the database.
 It is possible to cherry pick non-consecutive deltas. This is not reccomended and do so at your own risk.
 Accepted formats are:
-  4   - No Padding
-  004 - Padded zeros
+  4           - No Padding
+  004         - Padded zeros
+  release-2.3 - Label defined in the deltas directory labels.txt
 		`)
 
 	downCmd.PersistentFlags().BoolVarP(&downForce, "force", "", false, `Force applying down deltas even if the corresponding up delta is not applied.
 This can be useful for rolling back changes that were applied outside of schemer or for recovering from an inconsistent state, 
 but use with caution as it can lead to an inconsistent state between the database and schemer's tracking.`)
+	addSnapshotFlags(downCmd, &downRequest)
 }
 
 func shouldOnlyApplyLast() bool {
@@ -133,6 +126,10 @@ func shouldOnlyApplyLast() bool {
 // Returns:
 //   - error: non-nil if any delta fails to apply or if the schemer table update fails
 func executeDownCommand(connection *pgx.Conn, ctx context.Context) error {
+	if err := utils.CheckNotDirty(connection, ctx); err != nil {
+		return err
+	}
+
 	applied, err := GetAppliedDeltas(connection, ctx)
 	if err != nil {
 		return err
@@ -151,10 +148,10 @@ func executeDownCommand(connection *pgx.Conn, ctx context.Context) error {
 	}
 
 	if downRequest.PruneNoOp {
-		PruneNoOp(&statements)
+		PruneNoOpDown(&statements)
 	}
 
-	var deltasToApply []int
+	var deltasToApply []int64
 	var skippedDeltas []string
 
 	for tag := range statements {
@@ -170,61 +167,112 @@ func executeDownCommand(connection *pgx.Conn, ctx context.Context) error {
 		glog.Warn("The following deltas were skipped because they are not currently applied: %s \n use --force to apply them", strings.Join(skippedDeltas, ", "))
 	}
 
-	sort.Sort(sort.Reverse(sort.IntSlice(deltasToApply)))
+	if downRequest.dryRun {
+		return reportDownDryRun(deltasToApply, statements)
+	}
+	return applyDownDeltas(deltasToApply, statements, connection, ctx)
+}
+
+// reportDownDryRun logs the rendered down deltas that would be rolled back, highest tag first.
+//
+// Params:
+//   - tags: tags of the deltas to roll back
+//   - statements: map of tag numbers to their corresponding DownDelta
+//
+// Returns:
+//   - error: non-nil if a delta cannot be rendered
+func reportDownDryRun(tags []int64, statements map[int64]DownDelta) error {
+	slices.Sort(tags)
+	slices.Reverse(tags)
+
+	for _, tag := range tags {
+		if err := logDryRun(utils.DirectionDown, &tag, statements[tag].File, statements[tag].Data, ""); err != nil {
+			return err
+		}
+	}
+	if len(tags) == 0 {
+		glog.Info("Nothing to roll back")
+	}
+	return nil
+}
 
-	var executedDeltas []any
-	var placeholders []string
-	var executeErr error = nil
-	for index, tag := range deltasToApply {
-		statement := string(statements[tag])
+// applyDownDeltas executes down deltas from the highest tag to the lowest and removes each
+// one from the schemer table as soon as it succeeds, stopping on the first failure.
+//
+// Params:
+//   - deltasToApply: tags of the deltas to roll back
+//   - statements: map of tag numbers to their corresponding DownDelta
+//   - connection: pointer to a pgx.Conn for executing SQL statements
+//   - ctx: context for controlling query execution
+//
+// Returns:
+//   - error: non-nil if any delta fails to apply or if the schemer table update fails
+func applyDownDeltas(deltasToApply []int64, statements map[int64]DownDelta, connection *pgx.Conn, ctx context.Context) error {
+	if err := utils.EnsureTrackingTables(connection, ctx); err != nil {
+		return err
+	}
 
-		_, err = connection.Exec(ctx, statement)
+	slices.Sort(deltasToApply)
+	slices.Reverse(deltasToApply)
+
+	for _, tag := range deltasToApply {
+		err := executeDelta(connection, ctx, deltaExecution{
+			Direction:    utils.DirectionDown,
+			Tag:          tag,
+			File:         statements[tag].File,
+			Data:         statements[tag].Data,
+			Environments: statements[tag].Environments,
+			Record:       recordDownDelta(tag),
+		})
 		if err != nil {
-			// It is possible that previous deltas were executed.
-			// Record error and break to allow for updating schemer table
-			// with applied deltas.
-			executeErr = &errschemer.SchemerErr{
+			// The deltas reverted before it are already removed from the schemer table.
+			return &errschemer.SchemerErr{
 				Code:    "0028",
+				Kind:    errschemer.KindExecution,
 				Message: "failed to apply delta: " + utils.ToPrefix(tag),
 				Err:     err,
 			}
-			break
 		}
-		glog.Info("Successfully applied down delta %s", utils.ToPrefix(tag))
-		executedDeltas = append(executedDeltas, tag)
-		placeholders = append(placeholders, fmt.Sprintf("$%d", index+1))
+		if runsInEnvironment(statements[tag].Environments) {
+			glog.Info("Successfully applied down delta %s", utils.ToPrefix(tag))
+		}
 	}
-	if len(executedDeltas) > 0 {
-		schemerStatement := fmt.Sprintf(`DELETE FROM schemer WHERE (tag) IN (%s)`, strings.Join(placeholders, ", "))
-		_, err = connection.Exec(ctx, schemerStatement, executedDeltas...)
-		// If there is a migration error, wrap both errors.
-		if err != nil {
-			tableErr := &errschemer.SchemerErr{
+
+	return nil
+}
+
+// recordDownDelta returns the deltaExecution.Record of a down delta, which removes its tag
+// from the schemer table.
+//
+// Params:
+//   - tag: tag of the delta
+//
+// Returns:
+//   - func(*pgx.Conn, context.Context) error: deletes the tracking row
+func recordDownDelta(tag int64) func(*pgx.Conn, context.Context) error {
+	return func(connection *pgx.Conn, ctx context.Context) error {
+		statement := fmt.Sprintf(`DELETE FROM %s WHERE tag = $1`, utils.TrackingTable)
+		if _, err := connection.Exec(ctx, statement, tag); err != nil {
+			return &errschemer.SchemerErr{
 				Code:    "0029",
-				Message: "failed to update schemer table",
+				Message: "failed to update schemer table for delta: " + utils.ToPrefix(tag),
 				Err:     err,
 			}
-			if executeErr != nil {
-				return fmt.Errorf("table error: %v\nexecution error: %w", tableErr, executeErr)
-			}
-			return tableErr
 		}
-		glog.Info("Successfully updated schemer table")
+		return nil
 	}
-
-	return executeErr
 }
 
 // loadDownDeltas loads all eligible down deltas from the delta directory.
 // Filters and parses .down.sql files based on the provided DeltaRequest range or cherry-picked tags.
 //
 // Returns:
-//   - map[int][]byte: a map of tag numbers to raw SQL data for down deltas
+//   - map[int64]DownDelta: a map of tag numbers to corresponding DownDelta structs
 //   - error: non-nil if delta path resolution, file parsing, or tag extraction fails
-func loadDownDeltas(request *DeltaRequest) (map[int][]byte, error) {
+func loadDownDeltas(request *DeltaRequest) (map[int64]DownDelta, error) {
 	if request == nil {
 		return nil, &errschemer.SchemerErr{
-			Code:    "load-down-deltas-001",
+			Code:    "0200",
 			Message: "expected valid deltaRequest, recieved nil",
 		}
 	}
@@ -234,13 +282,12 @@ func loadDownDeltas(request *DeltaRequest) (map[int][]byte, error) {
 		return nil, err
 	}
 
-	expression := regexp.MustCompile(`^(\d+)_.*\.down\.sql$`)
-	result := make(map[int][]byte)
+	result := make(map[int64]DownDelta)
 
 	err = filepath.WalkDir(deltaPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return &errschemer.SchemerErr{
-				Code:    "load-down-deltas-002",
+				Code:    "0201",
 				Message: "failed to access path: " + path,
 				Err:     err,
 			}
@@ -250,19 +297,18 @@ func loadDownDeltas(request *DeltaRequest) (map[int][]byte, error) {
 			return nil
 		}
 
-		matches := expression.FindStringSubmatch(d.Name())
-		if matches == nil || len(matches) < 2 {
-			return nil
-		}
-
-		tag, err := strconv.Atoi(matches[1])
+		parsed, err := utils.ParseDeltaFilename(d.Name())
 		if err != nil {
 			return &errschemer.SchemerErr{
-				Code:    "load-down-deltas-003",
+				Code:    "0202",
 				Message: "malformed filename: " + d.Name(),
 				Err:     err,
 			}
 		}
+		if parsed == nil || parsed.Direction != utils.DirectionDown {
+			return nil
+		}
+		tag := parsed.Tag
 
 		if request.LastTag != nil {
 			if tag != *request.LastTag {
@@ -272,13 +318,23 @@ func loadDownDeltas(request *DeltaRequest) (map[int][]byte, error) {
 			contents, err := os.ReadFile(path)
 			if err != nil {
 				return &errschemer.SchemerErr{
-					Code:    "load-down-deltas-004",
+					Code:    "0203",
 					Message: "failed to read file at path: " + path,
 					Err:     err,
 				}
 			}
 
-			result[tag] = contents
+			environments, err := utils.GroupEnvironments(filepath.Dir(path), parsed.Base)
+			if err != nil {
+				return err
+			}
+
+			result[tag] = DownDelta{
+				Tag:          tag,
+				File:         relativeDeltaPath(deltaPath, path),
+				Data:         contents,
+				Environments: environments,
+			}
 
 			return filepath.SkipDir
 		}
@@ -299,7 +355,7 @@ func loadDownDeltas(request *DeltaRequest) (map[int][]byte, error) {
 		contents, err := os.ReadFile(path)
 		if err != nil {
 			return &errschemer.SchemerErr{
-				Code:    "load-down-deltas-005",
+				Code:    "0204",
 				Message: "failed to read file at path: " + path,
 				Err:     err,
 			}
@@ -307,12 +363,22 @@ func loadDownDeltas(request *DeltaRequest) (map[int][]byte, error) {
 
 		if _, exists := result[tag]; exists {
 			return &errschemer.SchemerErr{
-				Code:    "load-down-deltas-006",
+				Code:    "0205",
 				Message: fmt.Sprintf("duplicate down delta tag found: %03d", tag),
 			}
 		}
 
-		result[tag] = contents
+		environments, err := utils.GroupEnvironments(filepath.Dir(path), parsed.Base)
+		if err != nil {
+			return err
+		}
+
+		result[tag] = DownDelta{
+			Tag:          tag,
+			File:         relativeDeltaPath(deltaPath, path),
+			Data:         contents,
+			Environments: environments,
+		}
 		return nil
 	})
 	if err != nil {
@@ -332,6 +398,10 @@ func loadDownDeltas(request *DeltaRequest) (map[int][]byte, error) {
 // Returns:
 //   - error: non-nil if no deltas are applied, the down delta is missing, or execution fails
 func applyForLastUpDelta(connection *pgx.Conn, ctx context.Context) error {
+	if err := utils.CheckNotDirty(connection, ctx); err != nil {
+		return err
+	}
+
 	appliedDeltas, err := GetAppliedDeltas(connection, ctx)
 	if err != nil {
 		return err
@@ -344,7 +414,7 @@ func applyForLastUpDelta(connection *pgx.Conn, ctx context.Context) error {
 		}
 	}
 
-	var lastTag int = -1
+	var lastTag int64 = -1
 	for tag := range appliedDeltas {
 		if tag > lastTag {
 			lastTag = tag
@@ -358,7 +428,7 @@ func applyForLastUpDelta(connection *pgx.Conn, ctx context.Context) error {
 		return err
 	}
 
-	data, ok := deltaFile[lastTag]
+	delta, ok := deltaFile[lastTag]
 	if !ok {
 		return &errschemer.SchemerErr{
 			Code:    "0036",
@@ -366,37 +436,33 @@ func applyForLastUpDelta(connection *pgx.Conn, ctx context.Context) error {
 		}
 	}
 
-	var executeErr error = nil
-	statement := string(data)
+	if downRequest.dryRun {
+		return reportDownDryRun([]int64{lastTag}, deltaFile)
+	}
+	if err := utils.EnsureTrackingTables(connection, ctx); err != nil {
+		return err
+	}
 
-	_, err = connection.Exec(ctx, statement)
+	err = executeDelta(connection, ctx, deltaExecution{
+		Direction:    utils.DirectionDown,
+		Tag:          lastTag,
+		File:         delta.File,
+		Data:         delta.Data,
+		Environments: delta.Environments,
+		Record:       recordDownDelta(lastTag),
+	})
 	if err != nil {
-		executeErr = &errschemer.SchemerErr{
+		// The delta did not finish, keep it recorded as applied.
+		return &errschemer.SchemerErr{
 			Code:    "0037",
+			Kind:    errschemer.KindExecution,
 			Message: "failed to apply delta: " + utils.ToPrefix(lastTag),
 			Err:     err,
 		}
 	}
 
-	glog.Info("Successfully applied down delta %s", utils.ToPrefix(lastTag))
-
-	schemerStatement := `DELETE FROM schemer WHERE (tag) IN ($1)`
-	_, err = connection.Exec(ctx, schemerStatement, lastTag)
-	// If there is a execution error, wrap both errors.
-	if err != nil {
-		tableErr := &errschemer.SchemerErr{
-			Code:    "0038",
-			Message: "failed to update schemer table.",
-			Err:     err,
-		}
-
-		if executeErr != nil {
-			return fmt.Errorf("table error: %v\nexecution error: %w", executeErr, err)
-		}
-
-		return tableErr
+	if runsInEnvironment(delta.Environments) {
+		glog.Info("Successfully applied down delta %s", utils.ToPrefix(lastTag))
 	}
-	glog.Info("Successfully updated schemer table")
-
-	return executeErr
+	return nil
 }
@@ -11,6 +11,9 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/spf13/cobra"
 
+	"github.com/inskribe/schemer/cmd"
+	"github.com/inskribe/schemer/internal/config"
+	er "github.com/inskribe/schemer/internal/errschemer"
 	"github.com/inskribe/schemer/internal/utils"
 	tu "github.com/inskribe/schemer/internal/utils/testutils"
 )
@@ -111,7 +114,9 @@ func TestDownCommand(t *testing.T) {
 
 			downRequest = tc.request
 
-			downCmd.Run(&cobra.Command{}, []string{})
+			if err := downCmd.RunE(&cobra.Command{}, []string{}); err != nil {
+				t.Fatalf("down failed: %v", err)
+			}
 
 			tc.verify(t)
 		})
@@ -120,6 +125,62 @@ func TestDownCommand(t *testing.T) {
 	parseApplyCommand = originalState
 }
 
+func TestDownCommand_DryRunProtected(t *testing.T) {
+	tempDir := tu.CreateTestDeltaFiles(t)
+	utils.GetDeltaPath = func() (string, error) {
+		return tempDir, nil
+	}
+
+	configFile := filepath.Join(t.TempDir(), "schemer.yaml")
+	if err := os.WriteFile(configFile, []byte("profiles:\n  prod:\n    protected: true\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	protected, err := config.Load(configFile, "prod")
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	originalConfig, originalParse, originalConn := cmd.ActiveConfig, parseApplyCommand, utils.WithConn
+	t.Cleanup(func() {
+		cmd.ActiveConfig, parseApplyCommand, utils.WithConn = originalConfig, originalParse, originalConn
+		downRequest = CommandArgs{}
+	})
+	cmd.ActiveConfig = protected
+	parseApplyCommand = func(request *CommandArgs) error {
+		return nil
+	}
+	utils.WithConn = func(connString string, fn func(*pgx.Conn, context.Context) error) error {
+		return fn(tu.SharedConnection, context.Background())
+	}
+
+	for _, request := range []CommandArgs{{dryRun: true}, {dryRun: true, fromTag: "003"}} {
+		tu.SetupTestTable(t)
+		if _, err := tu.SharedConnection.Exec(context.Background(), `INSERT INTO schemer (tag) VALUES (0),(1),(2),(3)`); err != nil {
+			t.Fatalf("failed to insert tags: %v", err)
+		}
+
+		downRequest = request
+		if err := downCmd.RunE(&cobra.Command{}, []string{}); err != nil {
+			t.Fatalf("dry run failed: %v", err)
+		}
+
+		var count int
+		if err := tu.SharedConnection.QueryRow(context.Background(), `SELECT COUNT(*) FROM schemer`).Scan(&count); err != nil {
+			t.Fatalf("failed to count rows: %v", err)
+		}
+		if count != 4 {
+			t.Fatalf("expected a dry run to roll back nothing, %d of 4 tags remain", count)
+		}
+	}
+
+	// Without --dry-run the protected profile still has to be confirmed.
+	downRequest = CommandArgs{}
+	err = downCmd.RunE(&cobra.Command{}, []string{})
+	if schemerErr, ok := err.(*er.SchemerErr); !ok || schemerErr.Code != "0170" {
+		t.Fatalf("expected 0170 recieved %v", err)
+	}
+}
+
 func TestLoadDownDeltas(t *testing.T) {
 	tempDir := tu.CreateTestDeltaFiles(t)
 
@@ -140,7 +201,7 @@ func TestLoadDownDeltas(t *testing.T) {
 				if err != nil {
 					t.Fatalf("loadUpDeltas failed: %v", err)
 				}
-				for _, tag := range []int{0, 1, 2, 3} {
+				for _, tag := range []int64{0, 1, 2, 3} {
 					if _, ok := deltas[tag]; !ok {
 						t.Fatalf("expected delta tag %03d", tag)
 					}
@@ -150,7 +211,7 @@ func TestLoadDownDeltas(t *testing.T) {
 		{
 			name: "Cherry_Pick",
 			request: &DeltaRequest{
-				Cherries: &map[int]bool{1: true, 3: true},
+				Cherries: &map[int64]bool{1: true, 3: true},
 			},
 			verify: func(args *DeltaRequest) {
 				deltas, err := loadDownDeltas(args)
@@ -170,7 +231,7 @@ func TestLoadDownDeltas(t *testing.T) {
 		},
 		{
 			name:    "From_002",
-			request: &DeltaRequest{From: tu.Ptr(2)},
+			request: &DeltaRequest{From: tu.Ptr[int64](2)},
 			verify: func(args *DeltaRequest) {
 				deltas, err := loadDownDeltas(args)
 				if err != nil {
@@ -192,7 +253,7 @@ func TestLoadDownDeltas(t *testing.T) {
 		},
 		{
 			name:    "To_001",
-			request: &DeltaRequest{To: tu.Ptr(1)},
+			request: &DeltaRequest{To: tu.Ptr[int64](1)},
 			verify: func(args *DeltaRequest) {
 				deltas, err := loadDownDeltas(args)
 				if err != nil {
@@ -214,7 +275,7 @@ func TestLoadDownDeltas(t *testing.T) {
 		},
 		{
 			name:    "Last",
-			request: &DeltaRequest{LastTag: tu.Ptr(3)},
+			request: &DeltaRequest{LastTag: tu.Ptr[int64](3)},
 			verify: func(args *DeltaRequest) {
 				deltas, err := loadDownDeltas(args)
 				if err != nil {
@@ -258,7 +319,7 @@ func TestApplyForLastUpDelta(t *testing.T) {
 	verifyStatement := `SELECT tag FROM schemer WHERE tag = 2`
 
 	row := tu.SharedConnection.QueryRow(context.Background(), verifyStatement)
-	var tag int
+	var tag int64
 	var status PostStatusEnum
 	var createdAt time.Time
 	if err := row.Scan(&tag, &status, &createdAt); err != nil {
@@ -461,7 +522,7 @@ func TestLoadDownDeltas_Recursive(t *testing.T) {
 		t.Fatalf("expected 3 down deltas, received %d", len(deltas))
 	}
 
-	for _, tag := range []int{1, 2, 3} {
+	for _, tag := range []int64{1, 2, 3} {
 		if _, ok := deltas[tag]; !ok {
 			t.Fatalf("expected delta %03d to be loaded", tag)
 		}
@@ -492,8 +553,8 @@ func TestLoadDownDeltas_Recursive_FromTo(t *testing.T) {
 	}
 
 	deltas, err := loadDownDeltas(&DeltaRequest{
-		From: tu.Ptr(2),
-		To:   tu.Ptr(2),
+		From: tu.Ptr[int64](2),
+		To:   tu.Ptr[int64](2),
 	})
 	if err != nil {
 		t.Fatalf("failed to load down deltas: %v", err)
@@ -538,7 +599,7 @@ func TestLoadDownDeltas_LastTag_Recursive(t *testing.T) {
 	}
 
 	deltas, err := loadDownDeltas(&DeltaRequest{
-		LastTag: tu.Ptr(3),
+		LastTag: tu.Ptr[int64](3),
 	})
 	if err != nil {
 		t.Fatalf("failed to load down deltas: %v", err)
@@ -0,0 +1,169 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package apply
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/spf13/cobra"
+
+	"github.com/inskribe/schemer/cmd"
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/output"
+	"github.com/inskribe/schemer/internal/schema"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+var (
+	dumpOptions DumpOptions
+	dumpRequest CommandArgs
+	dumpOutput  io.Writer = os.Stdout
+
+	dumpCmd = &cobra.Command{
+		Use:   "dump [options]",
+		Short: "Write a deterministic schema snapshot built from the database catalog",
+		Long: `The dump command reads pg_catalog and writes the schema as sorted SQL, suitable for
+committing as schema.sql and reviewing schema changes as a diff. pg_dump is not required.
+
+The snapshot covers extensions, enum types, sequences, tables and columns, constraints,
+indexes, views, materialized views and functions. The schemer tracking tables and objects
+owned by extensions are left out. Data is never included.
+
+The same snapshot can be written after every successful up, down or post with --snapshot.
+
+Examples:
+  schemer dump
+  schemer dump --file schema.sql
+  schemer dump --schema public --schema billing -f schema.sql
+  schemer up --snapshot schema.sql
+`,
+		RunE: func(command *cobra.Command, args []string) error {
+			if _, err := utils.LoadDotEnv(); err != nil {
+				return err
+			}
+
+			if err := parseApplyCommand(&dumpRequest); err != nil {
+				return err
+			}
+
+			return utils.WithConn(dumpRequest.connString, executeDumpCommand)
+		},
+	}
+)
+
+func init() {
+	cmd.RootCmd.AddCommand(dumpCmd)
+	dumpCmd.PersistentFlags().StringVarP(&dumpRequest.connKey, "conn-key", "k", "", "The key to fetch the environment variable value for the database connection string.")
+	dumpCmd.PersistentFlags().StringVarP(&dumpRequest.connString, "conn-string", "s", "", "The driver specific connection string. If passed the connection key will be ignored.")
+	dumpCmd.Flags().StringVarP(&dumpOptions.File, "file", "f", "", "File to write the snapshot to. Defaults to stdout.")
+	dumpCmd.Flags().StringArrayVar(&dumpOptions.Schemas, "schema", schema.DefaultSchemas, "Schema to include in the snapshot, may be repeated.")
+}
+
+// addSnapshotFlags registers the flags used to write a snapshot after a successful run.
+//
+// Params:
+//   - command: the up, down or post command
+//   - request: the command's parsed arguments
+func addSnapshotFlags(command *cobra.Command, request *CommandArgs) {
+	command.PersistentFlags().StringVar(&request.snapshotPath, "snapshot", "", `Write a schema snapshot to this file after a successful run, see [schemer dump].
+Skipped on dry runs.`)
+	command.PersistentFlags().StringArrayVar(&request.snapshotSchemas, "snapshot-schema", schema.DefaultSchemas, "Schema to include in the snapshot, may be repeated.")
+}
+
+// dumpEvent is the document emitted by the dump command with --output json.
+type dumpEvent struct {
+	output.Header
+	Objects  int    `json:"objects"`            // number of objects in the snapshot
+	File     string `json:"file,omitempty"`     // file the snapshot was written to with --file
+	Snapshot string `json:"snapshot,omitempty"` // the snapshot itself, unless written to a file
+}
+
+// executeDumpCommand introspects the database and writes the snapshot.
+//
+// Params:
+//   - connection: pointer to a pgx.Conn for executing SQL statements
+//   - ctx: context for query execution
+//
+// Returns:
+//   - error: non-nil if the catalog cannot be read or the snapshot cannot be written
+func executeDumpCommand(connection *pgx.Conn, ctx context.Context) error {
+	snapshot, err := schema.Introspect(connection, ctx, dumpOptions.Schemas)
+	if err != nil {
+		return err
+	}
+
+	if dumpOptions.File == "" {
+		if output.JSON() {
+			output.Emit("dump", &dumpEvent{Objects: len(snapshot.Objects), Snapshot: snapshot.Render()})
+			return nil
+		}
+		if _, err := io.WriteString(dumpOutput, snapshot.Render()); err != nil {
+			return &errschemer.SchemerErr{
+				Code:    "0122",
+				Message: "failed to write snapshot to stdout",
+				Err:     err,
+			}
+		}
+		return nil
+	}
+
+	if err := snapshot.WriteFile(dumpOptions.File); err != nil {
+		return err
+	}
+	glog.Info("Wrote schema snapshot with %d object(s) to %s", len(snapshot.Objects), dumpOptions.File)
+	output.Emit("dump", &dumpEvent{Objects: len(snapshot.Objects), File: dumpOptions.File})
+	return nil
+}
+
+// withSnapshot wraps a command so that a schema snapshot is written once it succeeds.
+// Nothing is written if the command fails, no snapshot path is set, or on dry runs.
+//
+// Params:
+//   - request: the command's parsed arguments
+//   - execute: the command to run
+//
+// Returns:
+//   - func: the wrapped command, suitable for utils.WithConn
+func withSnapshot(request *CommandArgs, execute func(*pgx.Conn, context.Context) error) func(*pgx.Conn, context.Context) error {
+	return func(connection *pgx.Conn, ctx context.Context) error {
+		if err := execute(connection, ctx); err != nil {
+			return err
+		}
+		if request.snapshotPath == "" || request.dryRun {
+			return nil
+		}
+
+		snapshot, err := schema.Introspect(connection, ctx, request.snapshotSchemas)
+		if err != nil {
+			return err
+		}
+		if err := snapshot.WriteFile(request.snapshotPath); err != nil {
+			return err
+		}
+		glog.Info("Wrote schema snapshot to %s", request.snapshotPath)
+		return nil
+	}
+}
@@ -0,0 +1,134 @@
+package apply
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/inskribe/schemer/internal/schema"
+	"github.com/inskribe/schemer/internal/utils"
+	tu "github.com/inskribe/schemer/internal/utils/testutils"
+)
+
+func setupDumpSchema(t *testing.T) {
+	ctx := context.Background()
+	_, err := tu.SharedConnection.Exec(ctx, `
+		DROP SCHEMA IF EXISTS dump_test CASCADE;
+		CREATE SCHEMA dump_test;
+		CREATE TYPE dump_test.mood AS ENUM ('sad', 'ok', 'happy');
+		CREATE SEQUENCE dump_test.invoice_number START WITH 100;
+		CREATE TABLE dump_test.users (
+			id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+			email TEXT NOT NULL UNIQUE,
+			mood dump_test.mood DEFAULT 'ok',
+			email_lower TEXT GENERATED ALWAYS AS (lower(email)) STORED
+		);
+		CREATE INDEX users_mood_idx ON dump_test.users (mood);
+		CREATE VIEW dump_test.happy_users AS SELECT id, email FROM dump_test.users WHERE mood = 'happy';
+		CREATE FUNCTION dump_test.user_count() RETURNS bigint LANGUAGE sql AS $$ SELECT count(*) FROM dump_test.users $$;
+	`)
+	if err != nil {
+		t.Fatalf("failed to create dump schema: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = tu.SharedConnection.Exec(ctx, `DROP SCHEMA IF EXISTS dump_test CASCADE`)
+	})
+}
+
+func TestIntrospect(t *testing.T) {
+	setupDumpSchema(t)
+
+	snapshot, err := schema.Introspect(tu.SharedConnection, context.Background(), []string{"dump_test"})
+	if err != nil {
+		t.Fatalf("failed to introspect: %v", err)
+	}
+
+	expected := []string{
+		"TYPE dump_test.mood",
+		"SEQUENCE dump_test.invoice_number",
+		"TABLE dump_test.users",
+		"CONSTRAINT dump_test.users.users_email_key",
+		"CONSTRAINT dump_test.users.users_pkey",
+		"INDEX dump_test.users_mood_idx",
+		"VIEW dump_test.happy_users",
+		"FUNCTION dump_test.user_count()",
+	}
+	if len(snapshot.Objects) != len(expected) {
+		t.Fatalf("expected %d objects, got %+v", len(expected), snapshot.Objects)
+	}
+	for i, object := range snapshot.Objects {
+		if object.Key() != expected[i] {
+			t.Fatalf("expected object %d to be %s, got %s", i, expected[i], object.Key())
+		}
+	}
+
+	table := snapshot.Objects[2].Definition
+	for _, column := range []string{
+		"id bigint GENERATED ALWAYS AS IDENTITY NOT NULL",
+		"email text NOT NULL",
+		"mood dump_test.mood DEFAULT 'ok'::dump_test.mood",
+		"email_lower text GENERATED ALWAYS AS (lower(email)) STORED",
+	} {
+		if !strings.Contains(table, column) {
+			t.Errorf("expected table definition to contain %q, got:\n%s", column, table)
+		}
+	}
+
+	again, err := schema.Introspect(tu.SharedConnection, context.Background(), []string{"dump_test"})
+	if err != nil {
+		t.Fatalf("failed to introspect: %v", err)
+	}
+	if again.Render() != snapshot.Render() {
+		t.Fatalf("expected snapshot to be deterministic")
+	}
+}
+
+func TestIntrospect_SkipsTrackingSequences(t *testing.T) {
+	ctx := context.Background()
+	if err := utils.EnsureTrackingTables(tu.SharedConnection, ctx); err != nil {
+		t.Fatalf("failed to create tracking tables: %v", err)
+	}
+
+	snapshot, err := schema.Introspect(tu.SharedConnection, ctx, []string{"public"})
+	if err != nil {
+		t.Fatalf("failed to introspect: %v", err)
+	}
+	for _, object := range snapshot.Objects {
+		for _, table := range utils.TrackingTables() {
+			if strings.Contains(object.Name, table) {
+				t.Errorf("expected no schemer objects in snapshot, got %s", object.Key())
+			}
+		}
+	}
+}
+
+func TestWithSnapshot(t *testing.T) {
+	setupDumpSchema(t)
+	path := filepath.Join(t.TempDir(), "db", "schema.sql")
+
+	request := CommandArgs{snapshotPath: path, snapshotSchemas: []string{"dump_test"}, dryRun: true}
+	noop := func(*pgx.Conn, context.Context) error { return nil }
+
+	if err := withSnapshot(&request, noop)(tu.SharedConnection, context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected no snapshot on dry run")
+	}
+
+	request.dryRun = false
+	if err := withSnapshot(&request, noop)(tu.SharedConnection, context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	snapshot, err := schema.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read snapshot: %v", err)
+	}
+	if len(snapshot.Objects) == 0 || snapshot.Schemas[0] != "dump_test" {
+		t.Fatalf("unexpected snapshot: %+v", snapshot)
+	}
+}
@@ -0,0 +1,393 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package apply
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/inskribe/schemer/cmd"
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/output"
+	"github.com/inskribe/schemer/internal/sqltoken"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+// deltaExecution describes a single delta about to be executed by executeDelta.
+type deltaExecution struct {
+	Direction utils.Direction // up, down or post
+	Tag       int64           // tag of the delta being executed
+	File      string          // delta file relative to the deltas directory
+	Data      []byte          // raw SQL contents of the delta file, checksummed before rendering
+	Note      string          // optional detail recorded in the history entry
+
+	Environments []string // environments the delta group is limited to, empty for all
+
+	// Record updates the tracking table once the delta succeeded. It runs in the transaction
+	// that clears the dirty marker, so a delta is never left both unrecorded and not dirty.
+	Record func(*pgx.Conn, context.Context) error
+}
+
+// activeEnvironment returns the environment deltas are run for, the active config profile.
+var activeEnvironment = cmd.Profile
+
+// runsInEnvironment reports whether a delta group limited to environments runs in the active environment.
+func runsInEnvironment(environments []string) bool {
+	return utils.MatchesEnvironment(environments, activeEnvironment())
+}
+
+// executeDelta runs a single delta statement guarded by a dirty marker.
+// A delta limited to other environments is not executed, only recorded in the history as
+// skipped, and recorded in the tracking table like an executed delta so it is not pending forever.
+// The delta is rendered with the template variables first, a rendering error leaves no marker.
+// The marker is written before execution and removed once the statement succeeds, in the same
+// transaction that records the delta with delta.Record.
+// On failure the marker is kept with the error attached so the next run refuses to
+// continue until the operator resolves it with [schemer repair]. A delta that failed inside
+// the transaction runDelta opened with --fail-on-warning, or that the server rolled back after
+// lock contention, left no changes, its marker is removed so the run can simply be retried.
+// Every execution, successful or not, is appended to the schemer_history table.
+//
+// Params:
+//   - connection: pointer to a pgx.Conn for executing SQL statements
+//   - ctx: context for query execution
+//   - delta: the delta to execute
+//
+// Returns:
+//   - error: the execution error, or a SchemerErr if rendering fails, the dirty marker could not be
+//     maintained or the delta could not be recorded
+func executeDelta(connection *pgx.Conn, ctx context.Context, delta deltaExecution) error {
+	if len(delta.Environments) > 0 {
+		env := activeEnvironment()
+		if env == "" {
+			return &errschemer.SchemerErr{
+				Code: "0175",
+				Kind: errschemer.KindUsage,
+				Message: fmt.Sprintf("delta %s only runs in %s, select the environment with --env or SCHEMER_PROFILE",
+					delta.File, strings.Join(delta.Environments, ", ")),
+			}
+		}
+		if !utils.MatchesEnvironment(delta.Environments, env) {
+			return skipDelta(connection, ctx, delta, env)
+		}
+	}
+
+	statement, err := utils.RenderDelta(delta.File, delta.Data)
+	if err != nil {
+		return err
+	}
+
+	if err := utils.MarkDirty(connection, ctx, delta.Tag, delta.Direction, delta.File); err != nil {
+		return err
+	}
+
+	entry := utils.HistoryEntry{
+		Direction: delta.Direction,
+		Tag:       delta.Tag,
+		File:      delta.File,
+		Checksum:  utils.Checksum(delta.Data),
+		StartedAt: time.Now().UTC(),
+		Note:      delta.Note,
+	}
+
+	scope := utils.TraceScope{Direction: delta.Direction, Tag: &delta.Tag, File: delta.File}
+	notices, rolledBack, execErr := runDelta(connection, ctx, scope, string(statement))
+	entry.FinishedAt = time.Now().UTC()
+	entry.Success = execErr == nil
+	entry.Notices = notices
+	if execErr != nil {
+		entry.Error = execErr.Error()
+	}
+	reportExecution(delta.Direction, &delta.Tag, delta.File, entry.FinishedAt.Sub(entry.StartedAt), notices, execErr)
+
+	// History is an audit trail, failing to write it must not hide the outcome of the delta.
+	if err := utils.RecordHistory(connection, ctx, entry); err != nil {
+		glog.Warn("%v", err)
+	}
+
+	if execErr != nil && (rolledBack || rolledBackByServer(string(statement), execErr)) {
+		// Nothing was applied, so the delta can simply be run again.
+		if err := utils.ClearDirty(connection, ctx, delta.Tag, delta.Direction); err != nil {
+			glog.Error("%v", err)
+		}
+		return execErr
+	}
+	if execErr != nil {
+		if err := utils.RecordDirtyError(connection, ctx, delta.Tag, delta.Direction, execErr); err != nil {
+			glog.Error("%v", err)
+		}
+		return execErr
+	}
+
+	return pgx.BeginFunc(ctx, connection, func(tx pgx.Tx) error {
+		if delta.Record != nil {
+			if err := delta.Record(tx.Conn(), ctx); err != nil {
+				return err
+			}
+		}
+		return utils.ClearDirty(tx.Conn(), ctx, delta.Tag, delta.Direction)
+	})
+}
+
+// runDelta executes the statements of a delta. With --fail-on-warning they run in an explicit
+// transaction so a WARNING rolls them back, unless the delta controls transactions itself or
+// contains a statement that cannot run inside a transaction block, e.g. CREATE INDEX CONCURRENTLY.
+//
+// Params:
+//   - connection: pointer to a pgx.Conn for executing SQL statements
+//   - ctx: context for query execution
+//   - scope: the delta being executed
+//   - statement: the rendered SQL of the delta
+//
+// Returns:
+//   - []utils.Notice: notices the server raised while the delta ran
+//   - bool: true if the delta failed inside the transaction and left no changes behind
+//   - error: the execution error
+func runDelta(connection *pgx.Conn, ctx context.Context, scope utils.TraceScope, statement string) ([]utils.Notice, bool, error) {
+	if !utils.FailOnWarning || controlsTransaction(statement) {
+		notices, err := utils.ExecDelta(connection, ctx, scope, statement)
+		return notices, false, err
+	}
+
+	var notices []utils.Notice
+	err := pgx.BeginFunc(ctx, connection, func(tx pgx.Tx) error {
+		var execErr error
+		notices, execErr = utils.ExecDelta(tx.Conn(), ctx, scope, statement)
+		return execErr
+	})
+	// 25001 is active_sql_transaction, e.g. "CREATE INDEX CONCURRENTLY cannot run inside a transaction block".
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "25001" {
+		glog.Warn("%s cannot run inside a transaction, running it without one; a WARNING will not roll it back", scope.File)
+		notices, err = utils.ExecDelta(connection, ctx, scope, statement)
+		return notices, false, err
+	}
+	return notices, err != nil, err
+}
+
+// rolledBackByServer reports whether a delta that failed on lock contention left no changes.
+// The statements of a delta are sent in one query, which the server runs as a single implicit
+// transaction unless the delta controls transactions itself. CREATE INDEX CONCURRENTLY and
+// similar statements commit part of their work and may leave an invalid index behind.
+//
+// Params:
+//   - statement: the rendered SQL of the delta
+//   - err: the execution error
+//
+// Returns:
+//   - bool: true if the server rolled back every statement of the delta
+func rolledBackByServer(statement string, err error) bool {
+	if !cmd.IsLockError(err) || controlsTransaction(statement) {
+		return false
+	}
+	for _, token := range sqltoken.Tokenize(statement) {
+		if token.IsKeyword("CONCURRENTLY") {
+			return false
+		}
+	}
+	return true
+}
+
+// skipDelta records a delta that is limited to other environments as skipped.
+//
+// Params:
+//   - connection: pointer to a pgx.Conn for recording the history entry
+//   - ctx: context for query execution
+//   - delta: the delta that is skipped
+//   - env: the active environment
+//
+// Returns:
+//   - error: non-nil if the delta could not be recorded, failing to write the history entry is only logged
+func skipDelta(connection *pgx.Conn, ctx context.Context, delta deltaExecution, env string) error {
+	environments := strings.Join(delta.Environments, ", ")
+	glog.Info("Skipping %s delta %s, it only runs in %s", delta.Direction, delta.File, environments)
+	reportDelta(delta.Direction, &delta.Tag, delta.File, output.StatusSkipped, "only runs in "+environments)
+
+	now := time.Now().UTC()
+	entry := utils.HistoryEntry{
+		Direction:  utils.DirectionSkip,
+		Tag:        delta.Tag,
+		File:       delta.File,
+		Checksum:   utils.Checksum(delta.Data),
+		StartedAt:  now,
+		FinishedAt: now,
+		Success:    true,
+		Note:       fmt.Sprintf("%s delta only runs in %s, environment is %s", delta.Direction, environments, env),
+	}
+	if err := utils.RecordHistory(connection, ctx, entry); err != nil {
+		glog.Warn("%v", err)
+	}
+	if delta.Record != nil {
+		return delta.Record(connection, ctx)
+	}
+	return nil
+}
+
+// trackedExecution describes a seed or repeatable delta about to be executed by executeTracked.
+type trackedExecution struct {
+	Direction utils.Direction                        // seed or repeatable
+	File      string                                 // file recorded in the history entry
+	Data      []byte                                 // raw SQL contents of the file
+	Checksum  string                                 // sha256 of the file
+	Note      string                                 // optional detail recorded in the history entry
+	Record    func(*pgx.Conn, context.Context) error // stores the checksum, runs inside the transaction
+}
+
+// executeTracked runs a file and records its checksum in a single transaction, so a failed
+// file leaves neither changes nor a record behind and no dirty marker is needed.
+// The file is rendered with the template variables before the transaction starts.
+// Every execution, successful or not, is appended to the schemer_history table.
+//
+// Params:
+//   - connection: pointer to a pgx.Conn for executing SQL statements
+//   - ctx: context for query execution
+//   - execution: the file to execute
+//
+// Returns:
+//   - error: the rendering, execution or record error
+func executeTracked(connection *pgx.Conn, ctx context.Context, execution trackedExecution) error {
+	statement, err := utils.RenderDelta(execution.File, execution.Data)
+	if err != nil {
+		return err
+	}
+
+	entry := utils.HistoryEntry{
+		Direction: execution.Direction,
+		File:      execution.File,
+		Checksum:  execution.Checksum,
+		StartedAt: time.Now().UTC(),
+		Note:      execution.Note,
+	}
+
+	var notices []utils.Notice
+	execErr := pgx.BeginFunc(ctx, connection, func(tx pgx.Tx) error {
+		scope := utils.TraceScope{Direction: execution.Direction, File: execution.File}
+		var err error
+		if notices, err = utils.ExecDelta(tx.Conn(), ctx, scope, string(statement)); err != nil {
+			return err
+		}
+		return execution.Record(tx.Conn(), ctx)
+	})
+
+	entry.FinishedAt = time.Now().UTC()
+	entry.Success = execErr == nil
+	entry.Notices = notices
+	if execErr != nil {
+		entry.Error = execErr.Error()
+	}
+	reportExecution(execution.Direction, nil, execution.File, entry.FinishedAt.Sub(entry.StartedAt), notices, execErr)
+
+	// History is an audit trail, failing to write it must not hide the outcome of the file.
+	if err := utils.RecordHistory(connection, ctx, entry); err != nil {
+		glog.Warn("%v", err)
+	}
+	return execErr
+}
+
+// logDryRun logs the SQL a dry run would execute, rendered with the template variables.
+//
+// Params:
+//   - direction: up, down, post, repeatable or seed
+//   - tag: tag of versioned deltas, nil for repeatable deltas and seeds
+//   - file: file relative to its directory
+//   - data: raw contents of the file
+//   - reason: optional detail shown after the file name
+//
+// Returns:
+//   - error: non-nil if the file cannot be rendered
+func logDryRun(direction utils.Direction, tag *int64, file string, data []byte, reason string) error {
+	statement, err := utils.RenderDelta(file, data)
+	if err != nil {
+		return err
+	}
+	reportDelta(direction, tag, file, output.StatusPlanned, reason)
+
+	kind := string(direction) + " delta"
+	if direction == utils.DirectionSeed {
+		kind = "seed"
+	}
+	if reason != "" {
+		file += " (" + reason + ")"
+	}
+	glog.Info("Would apply %s %s:\n%s", kind, file, strings.TrimSpace(string(statement)))
+	return nil
+}
+
+// reportDelta emits a delta event for --output json.
+//
+// Params:
+//   - direction: direction of the delta
+//   - tag: tag of versioned deltas, nil for repeatable deltas and seeds
+//   - file: file relative to its directory, may be empty
+//   - status: one of the output.Status constants
+//   - reason: why the delta was skipped, may be empty
+func reportDelta(direction utils.Direction, tag *int64, file string, status string, reason string) {
+	output.Emit("delta", &output.DeltaEvent{
+		Direction: string(direction),
+		Tag:       tag,
+		File:      file,
+		Status:    status,
+		Reason:    reason,
+	})
+}
+
+// reportExecution emits the delta event of an executed delta for --output json.
+//
+// Params:
+//   - direction: direction of the delta
+//   - tag: tag of versioned deltas, nil for repeatable deltas and seeds
+//   - file: file relative to its directory
+//   - duration: how long the execution took
+//   - notices: notices the server raised while the delta ran
+//   - execErr: the execution error, nil on success
+func reportExecution(direction utils.Direction, tag *int64, file string, duration time.Duration, notices []utils.Notice, execErr error) {
+	status := output.StatusApplied
+	if execErr != nil {
+		status = output.StatusFailed
+	}
+	durationMs := duration.Milliseconds()
+	output.Emit("delta", &output.DeltaEvent{
+		Direction:  string(direction),
+		Tag:        tag,
+		File:       file,
+		Status:     status,
+		DurationMs: &durationMs,
+		Notices:    noticeInfos(notices),
+		Error:      output.NewErrorInfo(execErr),
+	})
+}
+
+// noticeInfos converts notices for a delta event.
+func noticeInfos(notices []utils.Notice) []output.Notice {
+	var infos []output.Notice
+	for _, notice := range notices {
+		infos = append(infos, output.Notice(notice))
+	}
+	return infos
+}
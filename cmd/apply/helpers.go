@@ -22,10 +22,10 @@ THE SOFTWARE.
 package apply
 
 import (
-	"bufio"
 	"context"
 	"errors"
-	"strconv"
+	"fmt"
+	"path/filepath"
 	"strings"
 	"sync"
 
@@ -34,6 +34,8 @@ import (
 
 	"github.com/inskribe/schemer/internal/errschemer"
 	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/output"
+	"github.com/inskribe/schemer/internal/sqltoken"
 	"github.com/inskribe/schemer/internal/utils"
 )
 
@@ -42,14 +44,14 @@ import (
 //
 // Params:
 //   - data: pointer to a map of delta tags to raw SQL bytes; will be mutated directly
-func PruneNoOpUp(data *map[int]UpDelta) {
+func PruneNoOpUp(data *map[int64]UpDelta) {
 	var group sync.WaitGroup
 
-	noOps := make(chan int, len(*data))
+	noOps := make(chan int64, len(*data))
 
 	for tag, deltas := range *data {
 		group.Add(1)
-		go func(tag int, contents string) {
+		go func(tag int64, contents string) {
 			defer group.Done()
 			if IsNoOpSql(contents) {
 				noOps <- tag
@@ -61,43 +63,46 @@ func PruneNoOpUp(data *map[int]UpDelta) {
 	close(noOps)
 
 	for tag := range noOps {
+		reportDelta(utils.DirectionUp, &tag, (*data)[tag].File, output.StatusSkipped, "no-op")
 		delete(*data, tag)
 		glog.Warn("Skipping delta %s, would be a no-op database call.", utils.ToPrefix(tag))
 	}
 
 }
 
-// PruneNoOp removes no-op SQL deltas from the provided map in-place.
+// PruneNoOpDown removes no-op SQL deltas from the provided map in-place for DownDelta.
 // Uses concurrent workers to scan for and discard deltas that contain only comments or whitespace.
 //
 // Params:
-//   - data: pointer to a map of delta tags to raw SQL bytes; will be mutated directly
-func PruneNoOp(data *map[int][]byte) {
+//   - data: pointer to a map of delta tags to DownDelta; will be mutated directly
+func PruneNoOpDown(data *map[int64]DownDelta) {
 	var group sync.WaitGroup
 
-	noOps := make(chan int, len(*data))
+	noOps := make(chan int64, len(*data))
 
-	for tag, contents := range *data {
+	for tag, delta := range *data {
 		group.Add(1)
-		go func(tag int, contents string) {
+		go func(tag int64, contents string) {
 			defer group.Done()
 			if IsNoOpSql(contents) {
 				noOps <- tag
 			}
 
-		}(tag, string(contents))
+		}(tag, string(delta.Data))
 	}
 	group.Wait()
 	close(noOps)
 
 	for tag := range noOps {
+		reportDelta(utils.DirectionDown, &tag, (*data)[tag].File, output.StatusSkipped, "no-op")
 		delete(*data, tag)
 		glog.Warn("Skipping delta %s, would be a no-op database call.", utils.ToPrefix(tag))
 	}
 }
 
 // IsNoOpSql returns true if the SQL string contains no executable statements.
-// Ignores empty lines, line comments (--), and block comments (/* */).
+// Ignores whitespace, line comments (--), and block comments (/* */), including nested
+// block comments. Comment markers inside strings and quoted identifiers are not comments.
 //
 // Params:
 //   - data: the SQL content to evaluate
@@ -105,54 +110,94 @@ func PruneNoOp(data *map[int][]byte) {
 // Returns:
 //   - bool: true if the SQL contains only comments or whitespace; false otherwise
 func IsNoOpSql(data string) bool {
-	scanner := bufio.NewScanner(strings.NewReader(data))
-	var inBlockComment bool = false
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-
-		if line == "" || strings.HasPrefix(line, "--") {
-			continue
+	for _, token := range sqltoken.Tokenize(data) {
+		if !token.IsTrivia() {
+			return false
 		}
+	}
+	return true
+}
 
-		if inBlockComment {
-			if strings.Contains(line, "*/") {
-				inBlockComment = false
+// controlsTransaction reports whether a delta starts or ends transactions itself, e.g. with
+// BEGIN and COMMIT, so it cannot be wrapped in another transaction.
+//
+// Params:
+//   - data: the SQL content of the delta
+//
+// Returns:
+//   - bool: true if any statement is a transaction control statement
+func controlsTransaction(data string) bool {
+	for _, statement := range sqltoken.Statements(sqltoken.Tokenize(data)) {
+		for _, keyword := range []string{"BEGIN", "START", "COMMIT", "END", "ROLLBACK", "ABORT"} {
+			if statement[0].IsKeyword(keyword) {
+				return true
 			}
-			continue
 		}
+	}
+	return false
+}
 
-		if strings.HasPrefix(line, "/*") {
-			if !strings.Contains(line, "*/") {
-				inBlockComment = true
-				continue
-			}
-			parts := strings.SplitAfter(line, "*/")
-			if len(parts) > 1 {
-				if IsNoOpSql(parts[1]) {
-					continue
-				}
-			}
-			return false
+// findOutOfOrder returns the pending tags that are lower than the highest applied tag.
+//
+// Params:
+//   - applied: map of already applied delta tags
+//   - pending: sorted tags about to be applied
+//
+// Returns:
+//   - int64: the highest applied tag, -1 if nothing is applied
+//   - []int64: pending tags lower than the highest applied tag, in ascending order
+func findOutOfOrder(applied map[int64]bool, pending []int64) (int64, []int64) {
+	highest := int64(-1)
+	for tag := range applied {
+		if tag > highest {
+			highest = tag
 		}
-		return false
 	}
-	return true
+
+	var outOfOrder []int64
+	for _, tag := range pending {
+		if tag < highest {
+			outOfOrder = append(outOfOrder, tag)
+		}
+	}
+	return highest, outOfOrder
+}
+
+// joinTags formats tags as a comma separated list of zero padded prefixes.
+func joinTags(tags []int64) string {
+	prefixes := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		prefixes = append(prefixes, utils.ToPrefix(tag))
+	}
+	return strings.Join(prefixes, ", ")
+}
+
+// relativeDeltaPath returns path relative to the deltas directory for display and tracking.
+// Falls back to the given path if it cannot be made relative.
+func relativeDeltaPath(deltaPath string, path string) string {
+	relative, err := filepath.Rel(deltaPath, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(relative)
 }
 
 // GetRequestedDeltas parses the user's migration flags into a DeltaRequest.
 // Converts --from, --to, and --cherry-pick CLI inputs into a structured request.
 //
+// Tags may be given as release labels defined in the labels file.
+//
 // Returns:
 //   - *deltaRequest: the constructed delta request with range or specific tags
-//   - error: non-nil if any tag is not a valid integer
+//   - error: non-nil if any tag is neither a valid integer nor a known label
 func (args CommandArgs) GetRequestedDeltas() (*DeltaRequest, error) {
 	var result DeltaRequest
 	if args.fromTag != "" {
-		val, err := strconv.Atoi(args.fromTag)
+		val, err := utils.ResolveTag(args.fromTag)
 		if err != nil {
 			return nil, &errschemer.SchemerErr{
 				Code:    "0027",
+				Kind:    errschemer.KindUsage,
 				Message: "failed to convert --from tag" + args.fromTag,
 				Err:     err,
 			}
@@ -161,10 +206,11 @@ func (args CommandArgs) GetRequestedDeltas() (*DeltaRequest, error) {
 	}
 
 	if args.toTag != "" {
-		val, err := strconv.Atoi(args.toTag)
+		val, err := utils.ResolveTag(args.toTag)
 		if err != nil {
 			return nil, &errschemer.SchemerErr{
 				Code:    "0026",
+				Kind:    errschemer.KindUsage,
 				Message: "failed to convert --to tag" + args.toTag,
 				Err:     err,
 			}
@@ -177,12 +223,13 @@ func (args CommandArgs) GetRequestedDeltas() (*DeltaRequest, error) {
 		return &result, nil
 	}
 
-	cherries := make(map[int]bool)
+	cherries := make(map[int64]bool)
 	for _, raw := range args.cherryPickedVersions {
-		val, err := strconv.Atoi(raw)
+		val, err := utils.ResolveTag(raw)
 		if err != nil {
 			return nil, &errschemer.SchemerErr{
 				Code:    "0025",
+				Kind:    errschemer.KindUsage,
 				Message: "invalid cherry-picked tag: " + raw,
 				Err:     err,
 			}
@@ -203,9 +250,9 @@ func (args CommandArgs) GetRequestedDeltas() (*DeltaRequest, error) {
 //   - ctx: context for controlling query timeout or cancellation
 //
 // Returns:
-//   - map[int]bool: a map of applied delta tags where the key is the tag version and value is true
+//   - map[int64]bool: a map of applied delta tags where the key is the tag version and value is true
 //   - error: non-nil if the schemer table is missing or a query/scan error occurs
-func GetAppliedDeltas(connection *pgx.Conn, ctx context.Context) (map[int]bool, error) {
+func GetAppliedDeltas(connection *pgx.Conn, ctx context.Context) (map[int64]bool, error) {
 	if connection == nil {
 		return nil, &errschemer.SchemerErr{
 			Code:    "0020",
@@ -213,7 +260,7 @@ func GetAppliedDeltas(connection *pgx.Conn, ctx context.Context) (map[int]bool,
 			Err:     nil,
 		}
 	}
-	statement := `SELECT tag FROM schemer ORDER BY tag`
+	statement := fmt.Sprintf(`SELECT tag FROM %s ORDER BY tag`, utils.TrackingTable)
 	rows, err := connection.Query(ctx, statement)
 	if err != nil {
 		var pgErr *pgconn.PgError
@@ -237,10 +284,10 @@ Ensure project was setup with [schemer] init`,
 	}
 	defer rows.Close()
 
-	applied := make(map[int]bool)
+	applied := make(map[int64]bool)
 
 	for rows.Next() {
-		var version int
+		var version int64
 		if err := rows.Scan(&version); err != nil {
 			return nil, &errschemer.SchemerErr{
 				Code:    "0023",
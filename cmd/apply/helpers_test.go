@@ -7,7 +7,11 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/inskribe/schemer/internal/errschemer"
 	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/utils"
 	"github.com/inskribe/schemer/internal/utils/testutils"
 )
 
@@ -130,6 +134,18 @@ SELECT * FROM users;
 `,
 			expected: false,
 		},
+		{
+			name: "nested block comment",
+			input: `
+/* outer /* inner */ SELECT * FROM users; */
+`,
+			expected: true,
+		},
+		{
+			name:     "comment marker inside string",
+			input:    `SELECT '/* not a comment */';`,
+			expected: false,
+		},
 	}
 
 	for _, mock := range mockData {
@@ -142,64 +158,10 @@ SELECT * FROM users;
 	}
 }
 
-func TestPruneNoOp(t *testing.T) {
-	glog.InitializeLogger(true)
-	testData := map[int][]byte{
-		1: []byte("-- comment only"),
-		2: []byte("SELECT * FROM users;"),
-		3: []byte("/* block */"),
-		4: []byte("INSERT INTO x VALUES (1);"),
-		5: []byte(`
-			-- only comment
-			/* and another */
-		`),
-	}
-
-	expectedRemaining := map[int]bool{
-		2: true,
-		4: true,
-	}
-
-	PruneNoOp(&testData)
-
-	for tag := range expectedRemaining {
-		if _, ok := testData[tag]; !ok {
-			t.Errorf("Expected tag %d to remain, but it was pruned", tag)
-		}
-	}
-
-	for tag := range testData {
-		if !expectedRemaining[tag] {
-			t.Errorf("Expected tag %d to be pruned, but it remained", tag)
-		}
-	}
-}
-
-func BenchmarkPruneNoOp(b *testing.B) {
-	glog.InitializeLogger(true)
-	data := make(map[int][]byte, 10000)
-	for i := 0; i < 10000; i++ {
-		if i%5 == 0 {
-			data[i] = []byte("SELECT * FROM table;")
-		} else {
-			data[i] = []byte("-- just a comment")
-		}
-	}
-
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		clone := make(map[int][]byte, len(data))
-		for k, v := range data {
-			clone[k] = v
-		}
-		PruneNoOp(&clone)
-	}
-}
-
 func TestPruneNoOpUp(t *testing.T) {
 	glog.InitializeLogger(true)
 
-	testData := map[int]UpDelta{
+	testData := map[int64]UpDelta{
 		1: {Tag: 1, Data: []byte("-- just a comment")},
 		2: {Tag: 2, Data: []byte("SELECT * FROM users;"), PostStatus: Pending},
 		3: {Tag: 3, Data: []byte("/* block comment */")},
@@ -210,7 +172,7 @@ func TestPruneNoOpUp(t *testing.T) {
 		`)},
 	}
 
-	expectedRemaining := map[int]bool{
+	expectedRemaining := map[int64]bool{
 		2: true,
 		4: true,
 	}
@@ -233,8 +195,8 @@ func TestPruneNoOpUp(t *testing.T) {
 func BenchmarkPruneNoOpUp(b *testing.B) {
 	glog.InitializeLogger(true)
 
-	base := make(map[int]UpDelta, 10000)
-	for i := 0; i < 10000; i++ {
+	base := make(map[int64]UpDelta, 10000)
+	for i := int64(0); i < 10000; i++ {
 		var sql []byte
 		if i%5 == 0 {
 			sql = []byte("SELECT * FROM users;")
@@ -246,7 +208,7 @@ func BenchmarkPruneNoOpUp(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		clone := make(map[int]UpDelta, len(base))
+		clone := make(map[int64]UpDelta, len(base))
 		for k, v := range base {
 			clone[k] = v
 		}
@@ -317,6 +279,30 @@ func TestGetRequestedDeltas(t *testing.T) {
 	}
 }
 
+func TestGetRequestedDeltas_Labels(t *testing.T) {
+	writeRepeatableTree(t, map[string]string{
+		utils.LabelsFile: "# releases\nrelease-1.0 = 002\nrelease-2.0 = 20261017143000\n",
+	})
+
+	request, err := CommandArgs{fromTag: "release-1.0", toTag: "release-2.0"}.GetRequestedDeltas()
+	if err != nil {
+		t.Fatalf("GetRequestedDeltas failed: %v", err)
+	}
+	if *request.From != 2 || *request.To != 20261017143000 {
+		t.Fatalf("expected labels to resolve to 002 and 20261017143000, got %d and %d", *request.From, *request.To)
+	}
+
+	request, err = CommandArgs{cherryPickedVersions: []string{"release-1.0", "004"}}.GetRequestedDeltas()
+	if err != nil || !(*request.Cherries)[2] || !(*request.Cherries)[4] {
+		t.Fatalf("expected cherry-picked label and tag, got %+v, %v", request, err)
+	}
+
+	_, err = CommandArgs{toTag: "release-3.0"}.GetRequestedDeltas()
+	if schemerErr, ok := err.(*errschemer.SchemerErr); !ok || schemerErr.Code != "0026" {
+		t.Fatalf("expected an unknown label to fail with 0026, got %v", err)
+	}
+}
+
 func TestGetAppliedDeltas(t *testing.T) {
 	testutils.SetupTestTable(t)
 
@@ -347,8 +333,108 @@ func TestGetAppliedDeltas(t *testing.T) {
 			t.Fatalf("tag is not an int: %v (type %T)", tag, tag)
 		}
 
-		if _, ok := appliedDeltas[tagInt]; !ok {
+		if _, ok := appliedDeltas[int64(tagInt)]; !ok {
 			t.Errorf("expected applied deltas to contain delta tag: %d", tag)
 		}
 	}
 }
+
+func TestControlsTransaction(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		expected bool
+	}{
+		{name: "plain", data: "CREATE TABLE users (id int); INSERT INTO users VALUES (1);", expected: false},
+		{name: "function body", data: "CREATE FUNCTION f() RETURNS void LANGUAGE plpgsql AS $$ BEGIN END $$;", expected: false},
+		{name: "begin commit", data: "BEGIN;\nCREATE TABLE users (id int);\nCOMMIT;", expected: true},
+		{name: "start transaction", data: "-- own transaction\nstart transaction; SELECT 1; end;", expected: true},
+		{name: "rollback", data: "SELECT 1; ROLLBACK;", expected: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := controlsTransaction(tc.data); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRolledBackByServer(t *testing.T) {
+	lockTimeout := fmt.Errorf("exec: %w", &pgconn.PgError{Code: "55P03"})
+	testCases := []struct {
+		name      string
+		statement string
+		err       error
+		expected  bool
+	}{
+		{name: "lock timeout", statement: "ALTER TABLE users ADD COLUMN email text; UPDATE users SET email = '';", err: lockTimeout, expected: true},
+		{name: "deadlock", statement: "UPDATE users SET email = '';", err: &pgconn.PgError{Code: "40P01"}, expected: true},
+		{name: "other error", statement: "ALTER TABLE users ADD COLUMN email text;", err: &pgconn.PgError{Code: "42701"}, expected: false},
+		{name: "own transaction", statement: "BEGIN; ALTER TABLE users ADD COLUMN email text; COMMIT;", err: lockTimeout, expected: false},
+		{name: "concurrently", statement: "CREATE INDEX CONCURRENTLY users_email_idx ON users (email);", err: lockTimeout, expected: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := rolledBackByServer(tc.statement, tc.err); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestFindOutOfOrder(t *testing.T) {
+	testCases := []struct {
+		name            string
+		applied         map[int64]bool
+		pending         []int64
+		expectedHighest int64
+		expected        []int64
+	}{
+		{
+			name:            "Nothing_Applied",
+			applied:         map[int64]bool{},
+			pending:         []int64{0, 1, 2},
+			expectedHighest: -1,
+			expected:        nil,
+		},
+		{
+			name:            "In_Order",
+			applied:         map[int64]bool{0: true, 1: true},
+			pending:         []int64{2, 3},
+			expectedHighest: 1,
+			expected:        nil,
+		},
+		{
+			name:            "Merged_Branch",
+			applied:         map[int64]bool{13: true, 15: true},
+			pending:         []int64{14, 16},
+			expectedHighest: 15,
+			expected:        []int64{14},
+		},
+		{
+			name:            "Timestamps_After_Legacy_Tags",
+			applied:         map[int64]bool{14: true, 20261017143000: true},
+			pending:         []int64{15, 20261016090000, 20261018120000},
+			expectedHighest: 20261017143000,
+			expected:        []int64{15, 20261016090000},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			highest, outOfOrder := findOutOfOrder(tc.applied, tc.pending)
+			if highest != tc.expectedHighest {
+				t.Fatalf("expected highest %d, got %d", tc.expectedHighest, highest)
+			}
+			if len(outOfOrder) != len(tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, outOfOrder)
+			}
+			for i := range outOfOrder {
+				if outOfOrder[i] != tc.expected[i] {
+					t.Fatalf("expected %v, got %v", tc.expected, outOfOrder)
+				}
+			}
+		})
+	}
+}
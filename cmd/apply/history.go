@@ -0,0 +1,222 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package apply
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/spf13/cobra"
+
+	"github.com/inskribe/schemer/cmd"
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/output"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+// historyEvent is the document emitted by the history command with --output json.
+type historyEvent struct {
+	output.Header
+	Entries []utils.HistoryEntry `json:"entries"` // newest first
+}
+
+// Represents user input for history command.
+type HistoryOptions struct {
+	Tag       string // only show entries for this tag
+	Direction string // only show entries for this direction
+	Since     string // only show entries started on or after this date
+	Until     string // only show entries started before this date
+	Limit     int    // maximum number of entries to show
+}
+
+var (
+	historyOptions HistoryOptions
+	historyRequest CommandArgs
+	historyOutput  io.Writer = os.Stdout
+
+	historyCmd = &cobra.Command{
+		Use:   "history [options]",
+		Short: "Show the audit trail of executed deltas",
+		Long: `The history command lists entries from the append-only schemer_history table.
+
+Every executed up, down and post delta is recorded along with its checksum, timing,
+the database and OS user that ran it, the host, the schemer version and the outcome.
+Resolutions made with [schemer repair] are recorded with the mark direction, and deltas
+limited to other environments with the skip direction.
+
+Entries are listed newest first and can be filtered by tag, direction and date.
+
+Examples:
+  schemer history
+  schemer history --tag 004
+  schemer history --direction down --since 2026-01-01
+  schemer history --since 2026-01-01 --until 2026-02-01 --limit 200
+`,
+		RunE: func(command *cobra.Command, args []string) error {
+			if _, err := utils.LoadDotEnv(); err != nil {
+				return err
+			}
+
+			if err := parseApplyCommand(&historyRequest); err != nil {
+				return err
+			}
+
+			return utils.WithConn(historyRequest.connString, executeHistoryCommand)
+		},
+	}
+)
+
+func init() {
+	cmd.RootCmd.AddCommand(historyCmd)
+	historyCmd.PersistentFlags().StringVarP(&historyRequest.connKey, "conn-key", "k", "", "The key to fetch the environment variable value for the database connection string.")
+	historyCmd.PersistentFlags().StringVarP(&historyRequest.connString, "conn-string", "s", "", "The driver specific connection string. If passed the connection key will be ignored.")
+	historyCmd.Flags().StringVar(&historyOptions.Tag, "tag", "", `Only show entries for this tag. Accepted formats are:
+  4           - No Padding
+  004         - Padded zeros
+  release-2.3 - Label defined in the deltas directory labels.txt`)
+	historyCmd.Flags().StringVar(&historyOptions.Direction, "direction", "", "Only show entries for this direction: up, down, post, baseline, mark, seed, repeatable or skip.")
+	historyCmd.Flags().StringVar(&historyOptions.Since, "since", "", "Only show entries started on or after this date (2006-01-02 or RFC3339).")
+	historyCmd.Flags().StringVar(&historyOptions.Until, "until", "", "Only show entries started before this date (2006-01-02 or RFC3339).")
+	historyCmd.Flags().IntVar(&historyOptions.Limit, "limit", 50, "Maximum number of entries to show. Use 0 to show all entries.")
+}
+
+// GetHistoryFilter converts the history command options into a utils.HistoryFilter.
+//
+// Returns:
+//   - utils.HistoryFilter: the filter to pass to utils.GetHistory
+//   - error: non-nil if the tag, direction or dates are malformed
+func (options HistoryOptions) GetHistoryFilter() (utils.HistoryFilter, error) {
+	filter := utils.HistoryFilter{Limit: options.Limit}
+
+	if options.Tag != "" {
+		tag, err := utils.ResolveTag(options.Tag)
+		if err != nil {
+			return filter, &errschemer.SchemerErr{
+				Code:    "0086",
+				Kind:    errschemer.KindUsage,
+				Message: "failed to convert --tag " + options.Tag,
+				Err:     err,
+			}
+		}
+		filter.Tag = &tag
+	}
+
+	if options.Direction != "" {
+		direction := utils.Direction(options.Direction)
+		switch direction {
+		case utils.DirectionUp, utils.DirectionDown, utils.DirectionPost, utils.DirectionBaseline, utils.DirectionMark, utils.DirectionSeed, utils.DirectionRepeatable, utils.DirectionSkip:
+			filter.Direction = direction
+		default:
+			return filter, &errschemer.SchemerErr{
+				Code:    "0087",
+				Kind:    errschemer.KindUsage,
+				Message: "unknown --direction " + options.Direction + ", expected up, down, post, baseline, mark, seed, repeatable or skip",
+			}
+		}
+	}
+
+	var err error
+	if filter.Since, err = parseHistoryTime(options.Since); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = parseHistoryTime(options.Until); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// parseHistoryTime parses a date or RFC3339 timestamp given on the command line.
+// An empty value returns the zero time.
+func parseHistoryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed.UTC(), nil
+	}
+	parsed, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, &errschemer.SchemerErr{
+			Code:    "0088",
+			Kind:    errschemer.KindUsage,
+			Message: "invalid date " + value + ", expected 2006-01-02 or RFC3339",
+			Err:     err,
+		}
+	}
+	return parsed, nil
+}
+
+// executeHistoryCommand queries the schemer_history table and prints the matching entries.
+//
+// Params:
+//   - connection: pointer to a pgx.Conn for executing SQL statements
+//   - ctx: context for query execution
+//
+// Returns:
+//   - error: non-nil if the options are malformed or the query fails
+func executeHistoryCommand(connection *pgx.Conn, ctx context.Context) error {
+	filter, err := historyOptions.GetHistoryFilter()
+	if err != nil {
+		return err
+	}
+
+	entries, err := utils.GetHistory(connection, ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if output.JSON() {
+		output.Emit("history", &historyEvent{Entries: append([]utils.HistoryEntry{}, entries...)})
+		return nil
+	}
+
+	if len(entries) == 0 {
+		glog.Info("No history entries found.")
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(historyOutput, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tSTARTED\tDIRECTION\tTAG\tFILE\tDURATION\tDB USER\tOS USER\tHOST\tVERSION\tRESULT")
+	for _, entry := range entries {
+		result := "ok"
+		if !entry.Success {
+			result = "error: " + entry.Error
+		} else if entry.Note != "" {
+			result = entry.Note
+		}
+		if len(entry.Notices) > 0 {
+			result += fmt.Sprintf(" (%d notice(s))", len(entry.Notices))
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.ID, entry.StartedAt.Format("2006-01-02 15:04:05"), entry.Direction,
+			utils.ToPrefix(entry.Tag), entry.File, entry.Duration, entry.DatabaseUser,
+			entry.OSUser, entry.Hostname, entry.SchemerVersion, result)
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,139 @@
+package apply
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/utils"
+	tu "github.com/inskribe/schemer/internal/utils/testutils"
+)
+
+func TestGetHistoryFilter(t *testing.T) {
+	testCases := []struct {
+		name     string
+		options  HistoryOptions
+		expected any
+		verify   func(t *testing.T, filter utils.HistoryFilter)
+	}{
+		{
+			name:     "Empty",
+			options:  HistoryOptions{},
+			expected: nil,
+			verify: func(t *testing.T, filter utils.HistoryFilter) {
+				if filter.Tag != nil || filter.Direction != "" || !filter.Since.IsZero() || !filter.Until.IsZero() {
+					t.Fatalf("expected empty filter, got %+v", filter)
+				}
+			},
+		},
+		{
+			name:     "Padded_Tag",
+			options:  HistoryOptions{Tag: "004"},
+			expected: nil,
+			verify: func(t *testing.T, filter utils.HistoryFilter) {
+				if filter.Tag == nil || *filter.Tag != 4 {
+					t.Fatalf("expected tag 4, got %v", filter.Tag)
+				}
+			},
+		},
+		{
+			name:     "Date_Range",
+			options:  HistoryOptions{Since: "2026-01-01", Until: "2026-02-01T00:00:00Z"},
+			expected: nil,
+			verify: func(t *testing.T, filter utils.HistoryFilter) {
+				if !filter.Since.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+					t.Fatalf("unexpected since: %v", filter.Since)
+				}
+				if !filter.Until.Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)) {
+					t.Fatalf("unexpected until: %v", filter.Until)
+				}
+			},
+		},
+		{
+			name:     "Invalid_Tag",
+			options:  HistoryOptions{Tag: "abc"},
+			expected: "0086",
+		},
+		{
+			name:     "Invalid_Direction",
+			options:  HistoryOptions{Direction: "sideways"},
+			expected: "0087",
+		},
+		{
+			name:     "Invalid_Date",
+			options:  HistoryOptions{Since: "01/01/2026"},
+			expected: "0088",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := tc.options.GetHistoryFilter()
+			if tc.expected == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				tc.verify(t, filter)
+				return
+			}
+
+			var actual *er.SchemerErr
+			if !errors.As(err, &actual) || actual.Code != tc.expected {
+				t.Fatalf("expected %v recieved %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestExecuteDelta_RecordsHistory(t *testing.T) {
+	tu.SetupTestTable(t)
+	setupDirtyTable(t)
+	ctx := context.Background()
+
+	if _, err := tu.SharedConnection.Exec(ctx, `TRUNCATE schemer_history`); err != nil {
+		t.Fatalf("failed to truncate schemer_history: %v", err)
+	}
+
+	if err := executeDelta(tu.SharedConnection, ctx, deltaExecution{
+		Direction: utils.DirectionUp,
+		Tag:       1,
+		File:      "001_ok.up.sql",
+		Data:      []byte("SELECT 1"),
+	}); err != nil {
+		t.Fatalf("failed to execute delta: %v", err)
+	}
+	if err := executeDelta(tu.SharedConnection, ctx, deltaExecution{
+		Direction: utils.DirectionDown,
+		Tag:       1,
+		File:      "001_ok.down.sql",
+		Data:      []byte("SELECT * FROM missing_table"),
+	}); err == nil {
+		t.Fatalf("expected execution error for missing table")
+	}
+
+	entries, err := utils.GetHistory(tu.SharedConnection, ctx, utils.HistoryFilter{Tag: tu.Ptr[int64](1)})
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 history entries, got %d", len(entries))
+	}
+
+	down, up := entries[0], entries[1]
+	if up.Direction != utils.DirectionUp || !up.Success || up.Checksum != utils.Checksum([]byte("SELECT 1")) {
+		t.Fatalf("unexpected up entry: %+v", up)
+	}
+	if down.Direction != utils.DirectionDown || down.Success || down.Error == "" {
+		t.Fatalf("unexpected down entry: %+v", down)
+	}
+
+	entries, err = utils.GetHistory(tu.SharedConnection, ctx, utils.HistoryFilter{Direction: utils.DirectionUp})
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 up entry, got %d", len(entries))
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package apply
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/scratch"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+// MigrateAll brings a database fully up to date with the deltas directory.
+// The schemer table is created if needed, then every unapplied up delta, every new or
+// changed repeatable delta and every pending post delta is applied in order.
+// Deltas limited with schemer:env run for the active profile like with up: without --env
+// they fail with 0175, in other environments they are recorded as applied without running.
+//
+// Params:
+//   - connection: pointer to a pgx.Conn for executing SQL statements
+//   - ctx: context for query execution
+//
+// Returns:
+//   - error: non-nil if any delta fails to apply
+func MigrateAll(connection *pgx.Conn, ctx context.Context) error {
+	if err := utils.CreateSchemerTable(connection, ctx); err != nil {
+		return err
+	}
+	if err := utils.CheckNotDirty(connection, ctx); err != nil {
+		return err
+	}
+
+	applied, err := GetAppliedDeltas(connection, ctx)
+	if err != nil {
+		return err
+	}
+
+	deltas, err := loadUpDeltas(&DeltaRequest{})
+	if err != nil {
+		return err
+	}
+	for tag := range applied {
+		delete(deltas, tag)
+	}
+	if len(deltas) > 0 {
+		if err := applyUpDeltas(applied, deltas, connection, ctx); err != nil {
+			return err
+		}
+	}
+
+	repeatables, err := loadRepeatableStates(connection, ctx)
+	if err != nil {
+		return err
+	}
+	if err := applyRepeatables(repeatables, connection, ctx); err != nil {
+		return err
+	}
+
+	posts, err := loadPostDeltas(&DeltaRequest{}, connection, ctx)
+	if err != nil {
+		return err
+	}
+	return applyPostDeltas(posts, connection, ctx)
+}
+
+// BuildScratch creates a scratch database on the server of adminConnString and applies
+// every delta to it. The database is dropped again if migrating fails.
+//
+// Params:
+//   - adminConnString: connection string of any database on the target server
+//   - template: optional database to clone before applying deltas, empty for an empty database
+//
+// Returns:
+//   - *scratch.Database: the migrated scratch database, the caller must Drop it
+//   - error: non-nil if the database cannot be created or a delta fails to apply
+func BuildScratch(adminConnString string, template string) (*scratch.Database, error) {
+	database, err := scratch.Create(adminConnString, scratch.Options{Template: template, Setup: MigrateAll})
+	if err != nil {
+		return nil, err
+	}
+	glog.Info("Created scratch database %s", database.Name)
+	return database, nil
+}
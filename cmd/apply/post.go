@@ -27,9 +27,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
-	"regexp"
-	"strconv"
-	"strings"
+	"slices"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/spf13/cobra"
@@ -37,6 +35,7 @@ import (
 	"github.com/inskribe/schemer/cmd"
 	"github.com/inskribe/schemer/internal/errschemer"
 	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/output"
 	"github.com/inskribe/schemer/internal/utils"
 )
 
@@ -61,26 +60,16 @@ Examples:
   schemer post --cherry-pick 003,006    # Apply only selected post deltas
   schemer post --cherry-pick 004 --force
 `,
-		Run: func(command *cobra.Command, args []string) {
-			if cmd.RootCmd.PersistentPreRun != nil {
-				cmd.RootCmd.PersistentPreRun(command, args)
-			}
-
-			_, err := utils.LoadDotEnv()
-			if err != nil {
-				glog.Error("%v", err)
-				return
+		RunE: func(command *cobra.Command, args []string) error {
+			if _, err := utils.LoadDotEnv(); err != nil {
+				return err
 			}
 
 			if err := parseApplyCommand(&postRequest); err != nil {
-				glog.Error("%v", err)
-				return
+				return err
 			}
 
-			if err := utils.WithConn(postRequest.connString, executePostCommand); err != nil {
-				glog.Error("%v", err)
-				return
-			}
+			return utils.WithConn(postRequest.connString, withSnapshot(&postRequest, executePostCommand))
 		},
 	}
 )
@@ -93,23 +82,27 @@ func init() {
 	postCmd.PersistentFlags().BoolVar(&postRequest.PruneNoOp, "prune", false, `Enable no-operation file prunning. Scan delta files and skip applying files
 that only contains comments and empty lines. This can be useful for large replays to avoid unnessecarry database calls.`)
 	postCmd.PersistentFlags().StringVarP(&postRequest.toTag, "to", "t", "", `Specify the version to end at. Accepted formats are: 
-  4   - No Padding
-  004 - Padded zeros`)
+  4           - No Padding
+  004         - Padded zeros
+  release-2.3 - Label defined in the deltas directory labels.txt`)
 
 	postCmd.PersistentFlags().StringVarP(&postRequest.fromTag, "from", "f", "", `Specify the version to begin at. Accepted formats are:
-  4   - No Padding
-  004 - Padded zeros`)
+  4           - No Padding
+  004         - Padded zeros
+  release-2.3 - Label defined in the deltas directory labels.txt`)
 	postCmd.PersistentFlags().StringArrayVarP(&postRequest.cherryPickedVersions, "cherry-pick", "c", nil, `Specify deltas to execute againg the database.
 It is possible to cherry pick non-consecutive deltas. This is not reccomended and do so at your own risk.
 Accepted formats are:
-  4   - No Padding
-  004 - Padded zeros
+  4           - No Padding
+  004         - Padded zeros
+  release-2.3 - Label defined in the deltas directory labels.txt
 		`)
 
 	postCmd.Flags().BoolVar(&postoptions.Force, "force", false, `Force will apply a post delta even if the corresponding up delta tag is not marked with
 a post delta. This is a convinece flag to recover from a unintended state. 
 Post file should be created with schemer create [name] --post which will attach the 
 post delta to the up delta. When using force schemer will attach the post to the corresponding up manualy. `)
+	addSnapshotFlags(postCmd, &postRequest)
 }
 
 // fetchPostStatuses retrieves all deltas with a post status from the schemer table.
@@ -120,10 +113,10 @@ post delta to the up delta. When using force schemer will attach the post to the
 //   - ctx: context for controlling query execution
 //
 // Returns:
-//   - map[int]PostStatusEnum: mapping of delta tags to their post status values
+//   - map[int64]PostStatusEnum: mapping of delta tags to their post status values
 //   - error: non-nil if the query, scan, or row iteration fails
-func fetchPostStatuses(conn *pgx.Conn, ctx context.Context) (map[int]PostStatusEnum, error) {
-	statement := `SELECT tag, post_status FROM schemer WHERE post_status > 0;`
+func fetchPostStatuses(conn *pgx.Conn, ctx context.Context) (map[int64]PostStatusEnum, error) {
+	statement := fmt.Sprintf(`SELECT tag, post_status FROM %s WHERE post_status > 0;`, utils.TrackingTable)
 	rows, err := conn.Query(ctx, statement)
 	if err != nil {
 		return nil, &errschemer.SchemerErr{
@@ -135,9 +128,9 @@ func fetchPostStatuses(conn *pgx.Conn, ctx context.Context) (map[int]PostStatusE
 
 	defer rows.Close()
 
-	result := make(map[int]PostStatusEnum)
+	result := make(map[int64]PostStatusEnum)
 	for rows.Next() {
-		var tag int
+		var tag int64
 		var postStatus PostStatusEnum
 		if err := rows.Scan(&tag, &postStatus); err != nil {
 			return nil, &errschemer.SchemerErr{
@@ -169,9 +162,9 @@ func fetchPostStatuses(conn *pgx.Conn, ctx context.Context) (map[int]PostStatusE
 //   - ctx: context for database queries and file operations
 //
 // Returns:
-//   - map[int]PostDelta: a map of tag numbers to their corresponding PostDelta
+//   - map[int64]PostDelta: a map of tag numbers to their corresponding PostDelta
 //   - error: non-nil if fetching statuses, reading deltas, or scanning tags fails
-func loadPostDeltas(request *DeltaRequest, conn *pgx.Conn, ctx context.Context) (map[int]PostDelta, error) {
+func loadPostDeltas(request *DeltaRequest, conn *pgx.Conn, ctx context.Context) (map[int64]PostDelta, error) {
 	deltaPath, err := utils.GetDeltaPath()
 	if err != nil {
 		return nil, err
@@ -182,13 +175,12 @@ func loadPostDeltas(request *DeltaRequest, conn *pgx.Conn, ctx context.Context)
 		return nil, err
 	}
 
-	expression := regexp.MustCompile(`^(\d+)_.*\.post\.sql$`)
-	result := make(map[int]PostDelta)
+	result := make(map[int64]PostDelta)
 
 	err = filepath.WalkDir(deltaPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return &errschemer.SchemerErr{
-				Code:    "load-post-001",
+				Code:    "0206",
 				Message: "failed to access path: " + path,
 				Err:     err,
 			}
@@ -198,19 +190,18 @@ func loadPostDeltas(request *DeltaRequest, conn *pgx.Conn, ctx context.Context)
 			return nil
 		}
 
-		matches := expression.FindStringSubmatch(d.Name())
-		if matches == nil || len(matches) < 2 {
-			return nil
-		}
-
-		tag, err := strconv.Atoi(matches[1])
+		parsed, err := utils.ParseDeltaFilename(d.Name())
 		if err != nil {
 			return &errschemer.SchemerErr{
-				Code:    "load-post-002",
+				Code:    "0207",
 				Message: "malformed delta tag: " + d.Name(),
 				Err:     err,
 			}
 		}
+		if parsed == nil || parsed.Direction != utils.DirectionPost {
+			return nil
+		}
+		tag := parsed.Tag
 
 		if request.Cherries != nil {
 			if !(*request.Cherries)[tag] {
@@ -228,7 +219,7 @@ func loadPostDeltas(request *DeltaRequest, conn *pgx.Conn, ctx context.Context)
 		contents, err := os.ReadFile(path)
 		if err != nil {
 			return &errschemer.SchemerErr{
-				Code:    "load-post-003",
+				Code:    "0208",
 				Message: "failed to read delta file at: " + path,
 				Err:     err,
 			}
@@ -239,23 +230,32 @@ func loadPostDeltas(request *DeltaRequest, conn *pgx.Conn, ctx context.Context)
 		if !exist && !postoptions.Force {
 			glog.Warn(`Skipping post delta %s: up delta %s has no knowledge of post.`,
 				utils.ToPrefix(tag), utils.ToPrefix(tag))
+			reportDelta(utils.DirectionPost, &tag, relativeDeltaPath(deltaPath, path), output.StatusSkipped, "up delta has no post")
 			return nil
 		} else if val == Applied {
 			glog.Warn("Skipping delta %s: post has already been applied.", utils.ToPrefix(tag))
+			reportDelta(utils.DirectionPost, &tag, relativeDeltaPath(deltaPath, path), output.StatusSkipped, "already applied")
 			return nil
 		}
 
 		if _, exists := result[tag]; exists {
 			return &errschemer.SchemerErr{
-				Code:    "load-post-004",
+				Code:    "0209",
 				Message: fmt.Sprintf("duplicate post delta tag found: %03d", tag),
 			}
 		}
 
+		environments, err := utils.GroupEnvironments(filepath.Dir(path), parsed.Base)
+		if err != nil {
+			return err
+		}
+
 		result[tag] = PostDelta{
-			Tag:        tag,
-			Data:       contents,
-			PostStatus: Applied,
+			Tag:          tag,
+			File:         relativeDeltaPath(deltaPath, path),
+			Data:         contents,
+			PostStatus:   Applied,
+			Environments: environments,
 		}
 
 		return nil
@@ -277,6 +277,10 @@ func loadPostDeltas(request *DeltaRequest, conn *pgx.Conn, ctx context.Context)
 // Returns:
 //   - error: non-nil if any post delta fails to apply or if schemer table update fails
 func executePostCommand(conn *pgx.Conn, ctx context.Context) error {
+	if err := utils.CheckNotDirty(conn, ctx); err != nil {
+		return err
+	}
+
 	request, err := postRequest.GetRequestedDeltas()
 	if err != nil {
 		return err
@@ -286,44 +290,102 @@ func executePostCommand(conn *pgx.Conn, ctx context.Context) error {
 		return err
 	}
 
-	var execErr error
-	var placeholders []string
-	var args []any
-	i := 0
-	for _, delta := range deltas {
-		_, err = conn.Exec(ctx, string(delta.Data))
+	if postRequest.dryRun {
+		return reportPostDryRun(deltas)
+	}
+	return applyPostDeltas(deltas, conn, ctx)
+}
+
+// reportPostDryRun logs the rendered post deltas that would be applied, in tag order.
+//
+// Params:
+//   - deltas: map of tag numbers to their corresponding PostDelta
+//
+// Returns:
+//   - error: non-nil if a delta cannot be rendered
+func reportPostDryRun(deltas map[int64]PostDelta) error {
+	tags := make([]int64, 0, len(deltas))
+	for tag := range deltas {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+
+	for _, tag := range tags {
+		if err := logDryRun(utils.DirectionPost, &tag, deltas[tag].File, deltas[tag].Data, ""); err != nil {
+			return err
+		}
+	}
+	if len(tags) == 0 {
+		glog.Info("Nothing to apply, no post delta is pending")
+	}
+	return nil
+}
+
+// applyPostDeltas executes post deltas in tag order and marks each one as applied in the
+// schemer table as soon as it succeeds, stopping on the first failure.
+//
+// Params:
+//   - deltas: map of tag numbers to their corresponding PostDelta
+//   - conn: pointer to a pgx.Conn for executing SQL statements
+//   - ctx: context for controlling database operations
+//
+// Returns:
+//   - error: non-nil if any post delta fails to apply or if schemer table update fails
+func applyPostDeltas(deltas map[int64]PostDelta, conn *pgx.Conn, ctx context.Context) error {
+	if err := utils.EnsureTrackingTables(conn, ctx); err != nil {
+		return err
+	}
+
+	tags := make([]int64, 0, len(deltas))
+	for tag := range deltas {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+
+	for _, tag := range tags {
+		delta := deltas[tag]
+		err := executeDelta(conn, ctx, deltaExecution{
+			Direction:    utils.DirectionPost,
+			Tag:          delta.Tag,
+			File:         delta.File,
+			Data:         delta.Data,
+			Environments: delta.Environments,
+			Record:       recordPostDelta(delta.Tag),
+		})
 		if err != nil {
-			execErr = &errschemer.SchemerErr{
+			return &errschemer.SchemerErr{
 				Code:    "0047",
+				Kind:    errschemer.KindExecution,
 				Message: "failed to apply post delta: " + utils.ToPrefix(delta.Tag),
 				Err:     err,
 			}
-			break
 		}
-		glog.Info("Successfully applied post delta %s", utils.ToPrefix(delta.Tag))
-
-		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
-		args = append(args, delta.Tag)
-		i++
+		if runsInEnvironment(delta.Environments) {
+			glog.Info("Successfully applied post delta %s", utils.ToPrefix(delta.Tag))
+		}
 	}
 
-	if len(placeholders) == 0 {
-		return execErr
-	}
+	return nil
+}
 
-	schemerStatment := fmt.Sprintf(`UPDATE schemer SET post_status = 2 WHERE tag IN (%s)`, strings.Join(placeholders, ","))
-	_, err = conn.Exec(ctx, schemerStatment, args...)
-	if err != nil {
-		tableErr := &errschemer.SchemerErr{
-			Code:    "0048",
-			Message: "failed to update schemer table.",
-			Err:     err,
-		}
-		if execErr != nil {
-			return fmt.Errorf("table error: %v\nexecution error: %w", err, execErr)
+// recordPostDelta returns the deltaExecution.Record of a post delta, which marks its post
+// status as applied.
+//
+// Params:
+//   - tag: tag of the delta
+//
+// Returns:
+//   - func(*pgx.Conn, context.Context) error: updates the tracking row
+func recordPostDelta(tag int64) func(*pgx.Conn, context.Context) error {
+	return func(connection *pgx.Conn, ctx context.Context) error {
+		statement := fmt.Sprintf(`UPDATE %s SET post_status = 2 WHERE tag = $1`, utils.TrackingTable)
+		if _, err := connection.Exec(ctx, statement, tag); err != nil {
+			return &errschemer.SchemerErr{
+				Code:    "0048",
+				Message: "failed to update schemer table for post delta: " + utils.ToPrefix(tag),
+				Err:     err,
+			}
 		}
-		return tableErr
+		return nil
 	}
-
-	return nil
 }
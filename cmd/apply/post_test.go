@@ -19,27 +19,27 @@ func TestLoadPostDeltas(t *testing.T) {
 	testCases := []struct {
 		name     string
 		request  *DeltaRequest
-		expected map[int]bool
+		expected map[int64]bool
 	}{
 		{
 			name:     "Load_All",
 			request:  &DeltaRequest{},
-			expected: map[int]bool{0: true, 1: true},
+			expected: map[int64]bool{0: true, 1: true},
 		},
 		{
 			name:     "From_001",
-			request:  &DeltaRequest{From: tu.Ptr(1)},
-			expected: map[int]bool{1: true},
+			request:  &DeltaRequest{From: tu.Ptr[int64](1)},
+			expected: map[int64]bool{1: true},
 		},
 		{
 			name:     "To_000",
-			request:  &DeltaRequest{To: tu.Ptr(0)},
-			expected: map[int]bool{0: true},
+			request:  &DeltaRequest{To: tu.Ptr[int64](0)},
+			expected: map[int64]bool{0: true},
 		},
 		{
 			name:     "Cherry_pick",
-			request:  &DeltaRequest{Cherries: &map[int]bool{1: true}},
-			expected: map[int]bool{1: true},
+			request:  &DeltaRequest{Cherries: &map[int64]bool{1: true}},
+			expected: map[int64]bool{1: true},
 		},
 	}
 
@@ -67,7 +67,7 @@ func TestFetchPostStatuses(t *testing.T) {
 		t.Fatalf("failed to insert mock data: %v", err)
 	}
 
-	expected := map[int]PostStatusEnum{0: 1, 1: 0, 2: 2}
+	expected := map[int64]PostStatusEnum{0: 1, 1: 0, 2: 2}
 	statuses, err := fetchPostStatuses(tu.SharedConnection, context.Background())
 	if err != nil {
 		t.Fatalf("failed to fetch post statuses: %v", err)
@@ -96,27 +96,27 @@ func TestExecutePostCommand(t *testing.T) {
 	testCases := []struct {
 		name     string
 		request  CommandArgs
-		expected map[int]PostStatusEnum
+		expected map[int64]PostStatusEnum
 	}{
 		{
 			name:     "Apply_All",
 			request:  CommandArgs{},
-			expected: map[int]PostStatusEnum{0: 2, 1: 2, 2: 0, 3: 0},
+			expected: map[int64]PostStatusEnum{0: 2, 1: 2, 2: 0, 3: 0},
 		},
 		{
 			name:     "From_001",
 			request:  CommandArgs{fromTag: "001"},
-			expected: map[int]PostStatusEnum{0: 1, 1: 2, 2: 0, 3: 0},
+			expected: map[int64]PostStatusEnum{0: 1, 1: 2, 2: 0, 3: 0},
 		},
 		{
 			name:     "To_000",
 			request:  CommandArgs{toTag: "000"},
-			expected: map[int]PostStatusEnum{0: 2, 1: 1, 2: 0, 3: 0},
+			expected: map[int64]PostStatusEnum{0: 2, 1: 1, 2: 0, 3: 0},
 		},
 		{
 			name:     "Cherry_pick",
 			request:  CommandArgs{cherryPickedVersions: []string{"000", "001"}},
-			expected: map[int]PostStatusEnum{0: 2, 1: 2, 2: 0, 3: 0},
+			expected: map[int64]PostStatusEnum{0: 2, 1: 2, 2: 0, 3: 0},
 		},
 	}
 
@@ -139,10 +139,10 @@ func TestExecutePostCommand(t *testing.T) {
 			}
 			defer rows.Close()
 
-			actual := map[int]PostStatusEnum{}
+			actual := map[int64]PostStatusEnum{}
 
 			for rows.Next() {
-				var tag int
+				var tag int64
 				var status PostStatusEnum
 				if err := rows.Scan(&tag, &status); err != nil {
 					t.Fatalf("failed to scan row: %v", err)
@@ -205,7 +205,7 @@ func TestLoadPostDeltas_Recursive(t *testing.T) {
 		t.Fatalf("expected 3 post deltas, received %d", len(deltas))
 	}
 
-	for _, tag := range []int{1, 2, 3} {
+	for _, tag := range []int64{1, 2, 3} {
 		if _, ok := deltas[tag]; !ok {
 			t.Fatalf("expected delta %03d to be loaded", tag)
 		}
@@ -241,8 +241,8 @@ func TestLoadPostDeltas_Recursive_FromTo(t *testing.T) {
 	}
 
 	deltas, err := loadPostDeltas(&DeltaRequest{
-		From: tu.Ptr(2),
-		To:   tu.Ptr(2),
+		From: tu.Ptr[int64](2),
+		To:   tu.Ptr[int64](2),
 	}, tu.SharedConnection, context.Background())
 	if err != nil {
 		t.Fatalf("failed to load post deltas: %v", err)
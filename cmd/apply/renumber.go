@@ -0,0 +1,467 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package apply
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/spf13/cobra"
+
+	"github.com/inskribe/schemer/cmd"
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/output"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+// renumberGroup is a delta group found in the deltas directory.
+type renumberGroup struct {
+	Tag   int64    // tag of the group
+	Dir   string   // directory of the group relative to the deltas directory
+	Base  string   // filename before the first dot, e.g. 015_add_users
+	Files []string // filenames of the up, down and post deltas
+	HasUp bool     // false for down and post deltas without an up delta
+}
+
+// renumbering moves a delta group to a new tag.
+type renumbering struct {
+	Group  renumberGroup // the group being moved
+	NewTag int64         // the tag the group is moved to
+	Reason string        // why the group conflicts with its current tag
+}
+
+var (
+	renumberOptions RenumberOptions
+	renumberRequest CommandArgs
+	renumberOutput  io.Writer = os.Stdout
+
+	renumberCmd = &cobra.Command{
+		Use:   "renumber [options]",
+		Short: "Move conflicting unapplied delta groups to the next free tags",
+		Long: `The renumber command resolves tag conflicts left behind by merging branches.
+
+A pending delta group conflicts when:
+  - another group uses the same tag, or
+  - its tag is lower than the highest tag applied to the database.
+
+Conflicting groups are moved to the next free tags after every existing tag, in tag order.
+Their up, down and post files are renamed together, wherever they sit in the deltas
+directory. Of several pending groups sharing a tag, the first by path keeps it. Applied
+tags are never moved: when an applied tag is shared, the group recorded in schemer_history
+keeps it and the others are moved.
+
+New tags follow --versioning, which is usually set as "versioning" in the project config.
+A summary of the renames is printed for the commit message, along with a warning for every
+release label in labels.txt that points to a moved tag. Run the command against the
+database the deltas were applied to, and use --dry-run to preview.
+
+Examples:
+  schemer renumber --dry-run
+  schemer renumber --conn-key STAGING_DATABASE_URL
+`,
+		RunE: func(command *cobra.Command, args []string) error {
+			if _, err := utils.LoadDotEnv(); err != nil {
+				return err
+			}
+
+			if err := parseApplyCommand(&renumberRequest); err != nil {
+				return err
+			}
+
+			return utils.WithConn(renumberRequest.connString, executeRenumberCommand)
+		},
+	}
+)
+
+func init() {
+	cmd.RootCmd.AddCommand(renumberCmd)
+	renumberCmd.PersistentFlags().StringVarP(&renumberRequest.connKey, "conn-key", "k", "", "The key to fetch the environment variable value for the database connection string.")
+	renumberCmd.PersistentFlags().StringVarP(&renumberRequest.connString, "conn-string", "s", "", "The driver specific connection string. If passed the connection key will be ignored.")
+	renumberCmd.Flags().BoolVarP(&renumberRequest.dryRun, "dry-run", "d", false, "Print the renames without renaming any file.")
+	renumberCmd.Flags().StringVar(&renumberOptions.Versioning, "versioning", string(utils.VersioningSequential), `How the moved groups are tagged:
+  sequential - the highest existing tag plus one, e.g. 015
+  timestamp  - the UTC time of the run, e.g. 20261017143000`)
+}
+
+// executeRenumberCommand finds conflicting delta groups and renames them to free tags.
+//
+// Params:
+//   - connection: pointer to a pgx.Conn for reading the applied deltas
+//   - ctx: context for query execution
+//
+// Returns:
+//   - error: non-nil if the deltas or applied tags cannot be read, or a file cannot be renamed
+func executeRenumberCommand(connection *pgx.Conn, ctx context.Context) error {
+	deltaPath, err := utils.GetDeltaPath()
+	if err != nil {
+		return err
+	}
+
+	groups, err := loadDeltaGroups(deltaPath)
+	if err != nil {
+		return err
+	}
+
+	applied, err := GetAppliedDeltas(connection, ctx)
+	if err != nil {
+		return err
+	}
+
+	appliedFiles, err := getAppliedFiles(connection, ctx, groups, applied)
+	if err != nil {
+		return err
+	}
+
+	plan, err := planRenumber(groups, applied, appliedFiles, utils.Versioning(renumberOptions.Versioning), time.Now())
+	if err != nil {
+		return err
+	}
+	if len(plan) == 0 {
+		glog.Info("No conflicting tags found. Nothing to renumber.")
+		output.Emit("renumber", &renumberEvent{DryRun: renumberRequest.dryRun, Moves: []renumberMove{}})
+		return nil
+	}
+
+	if !renumberRequest.dryRun {
+		if err := renameGroups(deltaPath, plan); err != nil {
+			return err
+		}
+	}
+	if err := writeRenumberSummary(renumberOutput, plan, renumberRequest.dryRun); err != nil {
+		return err
+	}
+
+	labels, err := utils.ReadLabels(deltaPath)
+	if err != nil {
+		return err
+	}
+	for _, move := range plan {
+		for name, tag := range labels {
+			if tag == move.Group.Tag {
+				glog.Warn("Label %s points to tag %s, which %s was moved from. Check %s.",
+					name, utils.ToPrefix(tag), move.Group.path(), utils.LabelsFile)
+			}
+		}
+	}
+	return nil
+}
+
+// loadDeltaGroups collects the delta groups, sorted by tag and path.
+//
+// Params:
+//   - deltaPath: the deltas directory
+//
+// Returns:
+//   - []renumberGroup: the delta groups
+//   - error: non-nil if the directory cannot be walked or a tag cannot be parsed
+func loadDeltaGroups(deltaPath string) ([]renumberGroup, error) {
+	byPath := make(map[string]*renumberGroup)
+
+	err := filepath.WalkDir(deltaPath, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return &errschemer.SchemerErr{
+				Code:    "0181",
+				Message: "failed to access path: " + file,
+				Err:     err,
+			}
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		parsed, err := utils.ParseDeltaFilename(d.Name())
+		if err != nil {
+			return &errschemer.SchemerErr{
+				Code:    "0182",
+				Message: "malformed delta tag in file: " + d.Name(),
+				Err:     err,
+			}
+		}
+		if parsed == nil {
+			return nil
+		}
+
+		dir := relativeDeltaPath(deltaPath, filepath.Dir(file))
+		key := path.Join(dir, parsed.Base)
+		group, ok := byPath[key]
+		if !ok {
+			group = &renumberGroup{Tag: parsed.Tag, Dir: dir, Base: parsed.Base}
+			byPath[key] = group
+		}
+		group.Files = append(group.Files, d.Name())
+		if parsed.Direction == utils.DirectionUp {
+			group.HasUp = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []renumberGroup
+	for _, group := range byPath {
+		groups = append(groups, *group)
+	}
+	slices.SortFunc(groups, func(a, b renumberGroup) int {
+		return cmp.Or(cmp.Compare(a.Tag, b.Tag), strings.Compare(a.path(), b.path()))
+	})
+	return groups, nil
+}
+
+// path returns the group's path relative to the deltas directory, e.g. users/015_add_users.
+func (group renumberGroup) path() string {
+	return path.Join(group.Dir, group.Base)
+}
+
+// getAppliedFiles looks up which group applied each applied tag shared by several groups.
+//
+// Params:
+//   - connection: pointer to a pgx.Conn for reading schemer_history
+//   - ctx: context for query execution
+//   - groups: the delta groups
+//   - applied: the applied tags
+//
+// Returns:
+//   - map[int64]string: the up delta recorded for each shared applied tag, relative to the deltas directory
+//   - error: non-nil if the history cannot be read
+func getAppliedFiles(connection *pgx.Conn, ctx context.Context, groups []renumberGroup, applied map[int64]bool) (map[int64]string, error) {
+	counts := make(map[int64]int)
+	for _, group := range groups {
+		if group.HasUp {
+			counts[group.Tag]++
+		}
+	}
+
+	result := make(map[int64]string)
+	for tag, count := range counts {
+		if count < 2 || !applied[tag] {
+			continue
+		}
+		entries, err := utils.GetHistory(connection, ctx, utils.HistoryFilter{Tag: &tag})
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Success && strings.HasSuffix(entry.File, ".up.sql") {
+				result[tag] = entry.File
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// planRenumber picks the groups that conflict with another group or with the applied
+// history and assigns them new tags after every existing tag.
+//
+// Params:
+//   - groups: the delta groups, sorted by tag and path
+//   - applied: the applied tags
+//   - appliedFiles: the up delta recorded for applied tags shared by several groups
+//   - versioning: how the new tags are picked
+//   - now: the time used for timestamp tags
+//
+// Returns:
+//   - []renumbering: the groups to move, in order of their new tags
+//   - error: non-nil if the group holding a shared applied tag is unknown or the versioning is unknown
+func planRenumber(groups []renumberGroup, applied map[int64]bool, appliedFiles map[int64]string, versioning utils.Versioning, now time.Time) ([]renumbering, error) {
+	byTag := make(map[int64][]renumberGroup)
+	var tags []int64
+	highest, highestApplied := int64(0), int64(-1)
+	for _, group := range groups {
+		highest = max(highest, group.Tag)
+		// down and post deltas without an up delta are reported by schemer validate
+		if !group.HasUp {
+			continue
+		}
+		if _, ok := byTag[group.Tag]; !ok {
+			tags = append(tags, group.Tag)
+		}
+		byTag[group.Tag] = append(byTag[group.Tag], group)
+	}
+	for tag := range applied {
+		highest = max(highest, tag)
+		highestApplied = max(highestApplied, tag)
+	}
+
+	var plan []renumbering
+	for _, tag := range tags {
+		shared := byTag[tag]
+
+		keep := -1
+		reason := ""
+		switch {
+		case applied[tag] && len(shared) == 1:
+			keep = 0
+		case applied[tag]:
+			for i, group := range shared {
+				if appliedFiles[tag] == path.Join(group.Dir, group.Base+".up.sql") {
+					keep = i
+				}
+			}
+			if keep < 0 {
+				var paths []string
+				for _, group := range shared {
+					paths = append(paths, group.path())
+				}
+				return nil, &errschemer.SchemerErr{
+					Code: "0183",
+					Message: fmt.Sprintf("tag %s is applied but schemer_history does not record which of %s applied it, rename the others by hand",
+						utils.ToPrefix(tag), strings.Join(paths, ", ")),
+				}
+			}
+			reason = "tag applied by " + shared[keep].path()
+		case tag < highestApplied:
+			reason = "lower than the highest applied tag " + utils.ToPrefix(highestApplied)
+		default:
+			keep = 0
+			reason = "tag used by " + shared[0].path()
+		}
+
+		for i, group := range shared {
+			if i == keep {
+				continue
+			}
+			next, err := utils.NextTag(highest, versioning, now)
+			if err != nil {
+				return nil, err
+			}
+			highest = next
+			plan = append(plan, renumbering{Group: group, NewTag: next, Reason: reason})
+		}
+	}
+	return plan, nil
+}
+
+// newPath returns the path of the group after it is moved, relative to the deltas directory.
+func (move renumbering) newPath() string {
+	_, name, _ := strings.Cut(move.Group.Base, "_")
+	return path.Join(move.Group.Dir, utils.ToPrefix(move.NewTag)+"_"+name)
+}
+
+// renameGroups renames every file of the moved groups. The new names are checked before
+// any file is renamed.
+//
+// Params:
+//   - deltaPath: the deltas directory
+//   - plan: the groups to move
+//
+// Returns:
+//   - error: non-nil if a new name is taken or a file cannot be renamed
+func renameGroups(deltaPath string, plan []renumbering) error {
+	type rename struct{ from, to string }
+	var renames []rename
+	for _, move := range plan {
+		dir := filepath.Join(deltaPath, filepath.FromSlash(move.Group.Dir))
+		for _, file := range move.Group.Files {
+			_, rest, _ := strings.Cut(file, "_")
+			target := filepath.Join(dir, utils.ToPrefix(move.NewTag)+"_"+rest)
+			if _, err := os.Stat(target); err == nil {
+				return &errschemer.SchemerErr{
+					Code:    "0184",
+					Message: "cannot renumber " + file + ", file already exists: " + target,
+				}
+			}
+			renames = append(renames, rename{filepath.Join(dir, file), target})
+		}
+	}
+
+	for _, r := range renames {
+		if err := os.Rename(r.from, r.to); err != nil {
+			return &errschemer.SchemerErr{
+				Code:    "0185",
+				Message: "failed to rename " + r.from,
+				Err:     err,
+			}
+		}
+	}
+	return nil
+}
+
+// renumberEvent is the document emitted by the renumber command with --output json.
+type renumberEvent struct {
+	output.Header
+	DryRun bool           `json:"dry_run"` // true if the groups were not renamed
+	Moves  []renumberMove `json:"moves"`
+}
+
+// renumberMove is a delta group moved to a new tag.
+type renumberMove struct {
+	From     int64  `json:"from"`
+	To       int64  `json:"to"`
+	FromPath string `json:"from_path"` // group path without direction and extension
+	ToPath   string `json:"to_path"`
+	Reason   string `json:"reason"`
+}
+
+// writeRenumberSummary prints one line per moved group, suitable for a commit message.
+//
+// Params:
+//   - out: writer the summary is printed to
+//   - plan: the moved groups
+//   - dryRun: true if the files were not renamed
+//
+// Returns:
+//   - error: non-nil if writing fails
+func writeRenumberSummary(out io.Writer, plan []renumbering, dryRun bool) error {
+	if output.JSON() {
+		event := &renumberEvent{DryRun: dryRun, Moves: make([]renumberMove, 0, len(plan))}
+		for _, move := range plan {
+			event.Moves = append(event.Moves, renumberMove{
+				From:     move.Group.Tag,
+				To:       move.NewTag,
+				FromPath: move.Group.path(),
+				ToPath:   move.newPath(),
+				Reason:   move.Reason,
+			})
+		}
+		output.Emit("renumber", event)
+		return nil
+	}
+
+	verb := "Renumbered"
+	if dryRun {
+		verb = "Would renumber"
+	}
+	if _, err := fmt.Fprintf(out, "%s %d delta group(s):\n", verb, len(plan)); err != nil {
+		return err
+	}
+	for _, move := range plan {
+		if _, err := fmt.Fprintf(out, "  %s -> %s  %s -> %s (%s)\n",
+			utils.ToPrefix(move.Group.Tag), utils.ToPrefix(move.NewTag),
+			move.Group.path(), move.newPath(), move.Reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package apply
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+func TestPlanRenumber(t *testing.T) {
+	tempDir := writeRepeatableTree(t, map[string]string{
+		"014_init.up.sql":                  "-- up",
+		"014_init.down.sql":                "-- down",
+		"users/015_add_email.up.sql":       "-- up",
+		"users/015_add_email.down.sql":     "-- down",
+		"billing/015_add_invoice.up.sql":   "-- up",
+		"billing/015_add_invoice.down.sql": "-- down",
+		"billing/015_add_invoice.post.sql": "-- post",
+		"016_add_phone.up.sql":             "-- up",
+		"017_add_orders.up.sql":            "-- up",
+		"017_add_refunds.up.sql":           "-- up",
+		"018_orphan.down.sql":              "-- down",
+	})
+
+	groups, err := loadDeltaGroups(tempDir)
+	if err != nil {
+		t.Fatalf("loadDeltaGroups failed: %v", err)
+	}
+	if len(groups) != 7 {
+		t.Fatalf("expected 7 groups, got %+v", groups)
+	}
+
+	applied := map[int64]bool{14: true, 15: true, 16: true}
+	appliedFiles := map[int64]string{15: "users/015_add_email.up.sql"}
+	plan, err := planRenumber(groups, applied, appliedFiles, utils.VersioningSequential, time.Now())
+	if err != nil {
+		t.Fatalf("planRenumber failed: %v", err)
+	}
+
+	expected := []string{
+		"billing/015_add_invoice -> billing/019_add_invoice",
+		"017_add_refunds -> 020_add_refunds",
+	}
+	if len(plan) != len(expected) {
+		t.Fatalf("expected %d moves, got %+v", len(expected), plan)
+	}
+	for i, move := range plan {
+		if got := move.Group.path() + " -> " + move.newPath(); got != expected[i] {
+			t.Errorf("move %d = %s, expected %s", i, got, expected[i])
+		}
+	}
+
+	delete(appliedFiles, 15)
+	_, err = planRenumber(groups, applied, appliedFiles, utils.VersioningSequential, time.Now())
+	if schemerErr, ok := err.(*er.SchemerErr); !ok || schemerErr.Code != "0183" {
+		t.Fatalf("expected an unknown applied group to fail with 0183, got %v", err)
+	}
+
+	plan, err = planRenumber(groups, map[int64]bool{14: true, 16: true}, nil, utils.VersioningSequential, time.Now())
+	if err != nil {
+		t.Fatalf("planRenumber failed: %v", err)
+	}
+	if len(plan) != 3 || plan[0].Group.path() != "billing/015_add_invoice" || plan[1].Group.path() != "users/015_add_email" {
+		t.Fatalf("expected both groups lower than the applied 016 to move, got %+v", plan)
+	}
+
+	if err := renameGroups(tempDir, plan[:1]); err != nil {
+		t.Fatalf("renameGroups failed: %v", err)
+	}
+	for _, name := range []string{"019_add_invoice.up.sql", "019_add_invoice.down.sql", "019_add_invoice.post.sql"} {
+		if _, err := os.Stat(filepath.Join(tempDir, "billing", name)); err != nil {
+			t.Errorf("expected billing/%s to exist: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "billing", "015_add_invoice.up.sql")); err == nil {
+		t.Errorf("expected the old files to be renamed")
+	}
+
+	var out bytes.Buffer
+	if err := writeRenumberSummary(&out, plan[:1], false); err != nil {
+		t.Fatalf("writeRenumberSummary failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "015 -> 019  billing/015_add_invoice -> billing/019_add_invoice (lower than the highest applied tag 016)") {
+		t.Errorf("unexpected summary:\n%s", out.String())
+	}
+}
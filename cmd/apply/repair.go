@@ -0,0 +1,232 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package apply
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/spf13/cobra"
+
+	"github.com/inskribe/schemer/cmd"
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+var (
+	repairOptions RepairOptions
+	repairRequest CommandArgs
+
+	repairCmd = &cobra.Command{
+		Use:   "repair [options]",
+		Short: "Inspect and resolve deltas that did not finish executing",
+		Long: `The repair command resolves a dirty database state.
+
+Before a delta is executed schemer writes a dirty marker, and removes it once the delta
+finishes. If a delta fails or the process dies part way through, the marker is kept and
+up, down and post will refuse to run until it is resolved.
+
+Without options, repair lists the dirty deltas along with when they started and the error
+reported by the database. After inspecting the database, record the outcome with either
+--mark-applied or --mark-rolled-back.
+
+Examples:
+  schemer repair                      # Show what was running and why it failed
+  schemer repair --mark-applied       # The delta's changes are fully present
+  schemer repair --mark-rolled-back   # The delta's changes are fully absent
+`,
+		RunE: func(command *cobra.Command, args []string) error {
+			if _, err := utils.LoadDotEnv(); err != nil {
+				return err
+			}
+
+			if err := parseApplyCommand(&repairRequest); err != nil {
+				return err
+			}
+
+			if repairOptions.MarkApplied && repairOptions.MarkRolledBack {
+				return &errschemer.SchemerErr{
+					Code:    "0077",
+					Kind:    errschemer.KindUsage,
+					Message: "flags --mark-applied and --mark-rolled-back cannot be used together.",
+				}
+			}
+
+			if repairOptions.MarkApplied || repairOptions.MarkRolledBack {
+				if err := cmd.CheckProtected("repair"); err != nil {
+					return err
+				}
+			}
+
+			return utils.WithConn(repairRequest.connString, executeRepairCommand)
+		},
+	}
+)
+
+func init() {
+	cmd.RootCmd.AddCommand(repairCmd)
+	repairCmd.PersistentFlags().StringVarP(&repairRequest.connKey, "conn-key", "k", "", "The key to fetch the environment variable value for the database connection string.")
+	repairCmd.PersistentFlags().StringVarP(&repairRequest.connString, "conn-string", "s", "", "The driver specific connection string. If passed the connection key will be ignored.")
+	repairCmd.Flags().BoolVar(&repairOptions.MarkApplied, "mark-applied", false, `Record the dirty deltas as successfully applied.
+Use when the changes made by the delta are fully present in the database.`)
+	repairCmd.Flags().BoolVar(&repairOptions.MarkRolledBack, "mark-rolled-back", false, `Record the dirty deltas as never executed.
+Use when the changes made by the delta are fully absent from the database.`)
+}
+
+// executeRepairCommand lists dirty deltas and, when requested, resolves them.
+//
+// Params:
+//   - connection: pointer to a pgx.Conn for executing SQL statements
+//   - ctx: context for query execution
+//
+// Returns:
+//   - error: non-nil if the schemer tables cannot be updated
+func executeRepairCommand(connection *pgx.Conn, ctx context.Context) error {
+	if err := utils.EnsureTrackingTables(connection, ctx); err != nil {
+		return err
+	}
+
+	dirty, err := utils.GetDirtyDeltas(connection, ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(dirty) == 0 {
+		glog.Info("No dirty deltas found. Nothing to repair.")
+		return nil
+	}
+
+	for _, delta := range dirty {
+		reason := delta.Error
+		if reason == "" {
+			reason = "no error recorded, the process may have been interrupted"
+		}
+		glog.Warn("Dirty %s delta %s (%s)\n  started: %s\n  error:   %s",
+			delta.Direction, utils.ToPrefix(delta.Tag), delta.File,
+			delta.StartedAt.Format("2006-01-02 15:04:05"), reason)
+	}
+
+	if !repairOptions.MarkApplied && !repairOptions.MarkRolledBack {
+		glog.Info("Inspect the database, then run repair with --mark-applied or --mark-rolled-back.")
+		return nil
+	}
+
+	for _, delta := range dirty {
+		// The schemer table and the marker change together, so a failure leaves the delta dirty.
+		err := pgx.BeginFunc(ctx, connection, func(tx pgx.Tx) error {
+			if err := resolveDirtyDelta(tx.Conn(), ctx, delta, repairOptions.MarkApplied); err != nil {
+				return err
+			}
+			return utils.ClearDirty(tx.Conn(), ctx, delta.Tag, delta.Direction)
+		})
+		if err != nil {
+			return err
+		}
+
+		outcome := "rolled back"
+		if repairOptions.MarkApplied {
+			outcome = "applied"
+		}
+
+		now := time.Now().UTC()
+		if err := utils.RecordHistory(connection, ctx, utils.HistoryEntry{
+			Direction:  utils.DirectionMark,
+			Tag:        delta.Tag,
+			File:       delta.File,
+			StartedAt:  now,
+			FinishedAt: now,
+			Success:    true,
+			Note:       fmt.Sprintf("%s delta marked as %s", delta.Direction, outcome),
+		}); err != nil {
+			glog.Warn("%v", err)
+		}
+
+		glog.Info("Marked %s delta %s as %s", delta.Direction, utils.ToPrefix(delta.Tag), outcome)
+	}
+
+	return nil
+}
+
+// resolveDirtyDelta brings the schemer table in line with the operator's decision.
+//
+//   - up delta marked applied: the tag is recorded in the schemer table
+//   - down delta marked applied: the tag is removed from the schemer table
+//   - post delta marked applied: the tag's post status is set to Applied
+//
+// Marking a delta as rolled back restores the state from before it was executed.
+//
+// Params:
+//   - connection: pointer to a pgx.Conn for executing SQL statements
+//   - ctx: context for query execution
+//   - delta: the dirty delta being resolved
+//   - applied: true if the delta should be recorded as applied, false for rolled back
+//
+// Returns:
+//   - error: non-nil if the schemer table update fails
+func resolveDirtyDelta(connection *pgx.Conn, ctx context.Context, delta utils.DirtyDelta, applied bool) error {
+	var statement string
+	var args []any
+
+	switch delta.Direction {
+	case utils.DirectionUp:
+		if applied {
+			deltas, err := loadUpDeltas(&DeltaRequest{Cherries: &map[int64]bool{delta.Tag: true}})
+			if err != nil {
+				return err
+			}
+			statement = fmt.Sprintf(`INSERT INTO %s (tag, post_status) VALUES ($1, $2) ON CONFLICT (tag) DO NOTHING`, utils.TrackingTable)
+			args = []any{delta.Tag, deltas[delta.Tag].PostStatus}
+		} else {
+			statement = fmt.Sprintf(`DELETE FROM %s WHERE tag = $1`, utils.TrackingTable)
+			args = []any{delta.Tag}
+		}
+	case utils.DirectionDown:
+		if !applied {
+			return nil
+		}
+		statement = fmt.Sprintf(`DELETE FROM %s WHERE tag = $1`, utils.TrackingTable)
+		args = []any{delta.Tag}
+	case utils.DirectionPost:
+		if !applied {
+			return nil
+		}
+		statement = fmt.Sprintf(`UPDATE %s SET post_status = $2 WHERE tag = $1`, utils.TrackingTable)
+		args = []any{delta.Tag, Applied}
+	default:
+		return &errschemer.SchemerErr{
+			Code:    "0078",
+			Message: "unknown delta direction in dirty marker: " + string(delta.Direction),
+		}
+	}
+
+	if _, err := connection.Exec(ctx, statement, args...); err != nil {
+		return &errschemer.SchemerErr{
+			Code:    "0079",
+			Message: "failed to update schemer table for delta: " + utils.ToPrefix(delta.Tag),
+			Err:     err,
+		}
+	}
+	return nil
+}
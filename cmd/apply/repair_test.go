@@ -0,0 +1,175 @@
+package apply
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/spf13/cobra"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/utils"
+	tu "github.com/inskribe/schemer/internal/utils/testutils"
+)
+
+func setupDirtyTable(t *testing.T) {
+	ctx := context.Background()
+	if _, err := tu.SharedConnection.Exec(ctx, `DROP TABLE IF EXISTS schemer_dirty`); err != nil {
+		t.Fatalf("failed to drop schemer_dirty: %v", err)
+	}
+	if err := utils.EnsureTrackingTables(tu.SharedConnection, ctx); err != nil {
+		t.Fatalf("failed to create schemer_dirty: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = tu.SharedConnection.Exec(ctx, `DROP TABLE IF EXISTS schemer_dirty`)
+	})
+}
+
+func TestExecuteDelta_FailureLeavesDirty(t *testing.T) {
+	tu.SetupTestTable(t)
+	setupDirtyTable(t)
+	ctx := context.Background()
+
+	if err := executeDelta(tu.SharedConnection, ctx, deltaExecution{
+		Direction: utils.DirectionUp,
+		Tag:       7,
+		File:      "007_bad.up.sql",
+		Data:      []byte("SELECT * FROM missing_table"),
+	}); err == nil {
+		t.Fatalf("expected execution error for missing table")
+	}
+
+	dirty, err := utils.GetDirtyDeltas(tu.SharedConnection, ctx)
+	if err != nil {
+		t.Fatalf("failed to get dirty deltas: %v", err)
+	}
+	if len(dirty) != 1 || dirty[0].Tag != 7 || dirty[0].Error == "" {
+		t.Fatalf("expected one dirty marker for 007 with an error, got %+v", dirty)
+	}
+
+	err = utils.CheckNotDirty(tu.SharedConnection, ctx)
+	var schemerErr *er.SchemerErr
+	if !errors.As(err, &schemerErr) || schemerErr.Code != "0073" {
+		t.Fatalf("expected dirty check to fail with 0073, got %v", err)
+	}
+}
+
+func TestExecuteDelta_SuccessClearsDirty(t *testing.T) {
+	tu.SetupTestTable(t)
+	setupDirtyTable(t)
+	ctx := context.Background()
+
+	if err := executeDelta(tu.SharedConnection, ctx, deltaExecution{
+		Direction: utils.DirectionUp,
+		Tag:       1,
+		File:      "001_ok.up.sql",
+		Data:      []byte("SELECT 1"),
+	}); err != nil {
+		t.Fatalf("failed to execute delta: %v", err)
+	}
+
+	if err := utils.CheckNotDirty(tu.SharedConnection, ctx); err != nil {
+		t.Fatalf("expected clean state, got %v", err)
+	}
+}
+
+func TestExecuteRepairCommand(t *testing.T) {
+	tempDir := tu.CreateTestDeltaFiles(t)
+	utils.GetDeltaPath = func() (string, error) {
+		return tempDir, nil
+	}
+
+	testCases := []struct {
+		name      string
+		direction utils.Direction
+		options   RepairOptions
+		insertSQL string
+		expected  int
+	}{
+		{
+			name:      "Up_Mark_Applied",
+			direction: utils.DirectionUp,
+			options:   RepairOptions{MarkApplied: true},
+			expected:  1,
+		},
+		{
+			name:      "Up_Mark_Rolled_Back",
+			direction: utils.DirectionUp,
+			options:   RepairOptions{MarkRolledBack: true},
+			expected:  0,
+		},
+		{
+			name:      "Down_Mark_Applied",
+			direction: utils.DirectionDown,
+			options:   RepairOptions{MarkApplied: true},
+			insertSQL: `INSERT INTO schemer (tag) VALUES (1)`,
+			expected:  0,
+		},
+		{
+			name:      "Down_Mark_Rolled_Back",
+			direction: utils.DirectionDown,
+			options:   RepairOptions{MarkRolledBack: true},
+			insertSQL: `INSERT INTO schemer (tag) VALUES (1)`,
+			expected:  1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tu.SetupTestTable(t)
+			setupDirtyTable(t)
+			ctx := context.Background()
+
+			if tc.insertSQL != "" {
+				if _, err := tu.SharedConnection.Exec(ctx, tc.insertSQL); err != nil {
+					t.Fatalf("failed to insert statement: %v", err)
+				}
+			}
+
+			if err := utils.MarkDirty(tu.SharedConnection, ctx, 1, tc.direction, "001_test.sql"); err != nil {
+				t.Fatalf("failed to mark dirty: %v", err)
+			}
+
+			repairOptions = tc.options
+			defer func() { repairOptions = RepairOptions{} }()
+
+			if err := executeRepairCommand(tu.SharedConnection, ctx); err != nil {
+				t.Fatalf("repair failed: %v", err)
+			}
+
+			var count int
+			if err := tu.SharedConnection.QueryRow(ctx, `SELECT COUNT(*) FROM schemer WHERE tag = 1`).Scan(&count); err != nil {
+				t.Fatalf("failed to count rows: %v", err)
+			}
+			if count != tc.expected {
+				t.Fatalf("expected %d rows for tag 001, found %d", tc.expected, count)
+			}
+
+			if err := utils.CheckNotDirty(tu.SharedConnection, ctx); err != nil {
+				t.Fatalf("expected dirty marker to be cleared, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRepairCommand_ConflictingFlags(t *testing.T) {
+	originalParse, originalConn, originalOptions := parseApplyCommand, utils.WithConn, repairOptions
+	t.Cleanup(func() {
+		parseApplyCommand, utils.WithConn, repairOptions = originalParse, originalConn, originalOptions
+	})
+	parseApplyCommand = func(request *CommandArgs) error {
+		return nil
+	}
+	utils.WithConn = func(connString string, fn func(*pgx.Conn, context.Context) error) error {
+		t.Fatal("expected conflicting flags to be refused before connecting")
+		return nil
+	}
+	repairOptions = RepairOptions{MarkApplied: true, MarkRolledBack: true}
+
+	err := repairCmd.RunE(&cobra.Command{}, []string{})
+	var schemerErr *er.SchemerErr
+	if !errors.As(err, &schemerErr) || schemerErr.Code != "0077" || er.KindOf(err) != er.KindUsage {
+		t.Fatalf("expected usage error 0077, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package apply
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+// RepeatableStatus describes a repeatable delta compared to the database.
+type RepeatableStatus string
+
+const (
+	RepeatableUpToDate RepeatableStatus = "up to date" // applied with the current contents
+	RepeatableNew      RepeatableStatus = "new"        // never applied
+	RepeatableChanged  RepeatableStatus = "changed"    // applied with different contents
+)
+
+// repeatableState is a repeatable delta and its status in the database.
+type repeatableState struct {
+	Delta  RepeatableDelta
+	Status RepeatableStatus
+}
+
+// loadRepeatables loads every repeatable delta from the deltas directory, including
+// subdirectories, in the order they are applied: by filename, then by path.
+//
+// Returns:
+//   - []RepeatableDelta: repeatable deltas in apply order
+//   - error: non-nil if the delta path cannot be resolved or a file cannot be read
+func loadRepeatables() ([]RepeatableDelta, error) {
+	deltaPath, err := utils.GetDeltaPath()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []RepeatableDelta
+	err = filepath.WalkDir(deltaPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return &errschemer.SchemerErr{
+				Code:    "0161",
+				Message: "failed to access path: " + path,
+				Err:     err,
+			}
+		}
+		if d.IsDir() || !utils.IsRepeatableFilename(d.Name()) {
+			return nil
+		}
+
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return &errschemer.SchemerErr{
+				Code:    "0162",
+				Message: "failed to read repeatable delta at: " + path,
+				Err:     err,
+			}
+		}
+		result = append(result, RepeatableDelta{
+			File:     relativeDeltaPath(deltaPath, path),
+			Data:     contents,
+			Checksum: utils.Checksum(contents),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		left, right := filepath.Base(result[i].File), filepath.Base(result[j].File)
+		if left != right {
+			return left < right
+		}
+		return result[i].File < result[j].File
+	})
+	return result, nil
+}
+
+// compareRepeatables pairs each repeatable delta with its status in the database.
+//
+// Params:
+//   - applied: checksums recorded in the schemer_repeatable table, keyed by file
+//   - deltas: repeatable deltas in apply order
+//
+// Returns:
+//   - []repeatableState: the deltas in the same order with their status
+func compareRepeatables(applied map[string]string, deltas []RepeatableDelta) []repeatableState {
+	states := make([]repeatableState, 0, len(deltas))
+	for _, delta := range deltas {
+		status := RepeatableUpToDate
+		if checksum, ok := applied[delta.File]; !ok {
+			status = RepeatableNew
+		} else if checksum != delta.Checksum {
+			status = RepeatableChanged
+		}
+		states = append(states, repeatableState{Delta: delta, Status: status})
+	}
+	return states
+}
+
+// loadRepeatableStates loads the repeatable deltas and compares them with the database.
+// Every repeatable delta is new if the schemer_repeatable table does not exist yet.
+//
+// Params:
+//   - connection: pointer to a pgx.Conn for querying the schemer_repeatable table
+//   - ctx: context for query execution
+//
+// Returns:
+//   - []repeatableState: every repeatable delta in apply order with its status
+//   - error: non-nil if the deltas or the recorded checksums cannot be read
+func loadRepeatableStates(connection *pgx.Conn, ctx context.Context) ([]repeatableState, error) {
+	deltas, err := loadRepeatables()
+	if err != nil {
+		return nil, err
+	}
+	// Runs before anything is applied, including dry runs and status, so it must not create the table.
+	exists, err := utils.TableExists(connection, ctx, utils.RepeatableTable())
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return compareRepeatables(map[string]string{}, deltas), nil
+	}
+	applied, err := utils.GetAppliedRepeatables(connection, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return compareRepeatables(applied, deltas), nil
+}
+
+// applyRepeatables applies every new or changed repeatable delta in order, each in its own
+// transaction together with its checksum, stopping on the first failure.
+//
+// Params:
+//   - states: repeatable deltas with their status, up to date deltas are skipped
+//   - connection: pointer to a pgx.Conn for executing SQL statements
+//   - ctx: context for query execution
+//
+// Returns:
+//   - error: non-nil if a repeatable delta fails to apply
+func applyRepeatables(states []repeatableState, connection *pgx.Conn, ctx context.Context) error {
+	if pendingRepeatables(states) == 0 {
+		return nil
+	}
+	if err := utils.EnsureTrackingTables(connection, ctx); err != nil {
+		return err
+	}
+
+	for _, state := range states {
+		if state.Status == RepeatableUpToDate {
+			continue
+		}
+		delta := state.Delta
+		err := executeTracked(connection, ctx, trackedExecution{
+			Direction: utils.DirectionRepeatable,
+			File:      delta.File,
+			Data:      delta.Data,
+			Checksum:  delta.Checksum,
+			Note:      string(state.Status),
+			Record: func(connection *pgx.Conn, ctx context.Context) error {
+				return utils.RecordRepeatable(connection, ctx, delta.File, delta.Checksum)
+			},
+		})
+		if err != nil {
+			return &errschemer.SchemerErr{
+				Code:    "0163",
+				Kind:    errschemer.KindExecution,
+				Message: "failed to apply repeatable delta: " + delta.File,
+				Err:     err,
+			}
+		}
+		glog.Info("Applied repeatable delta %s (%s)", delta.File, state.Status)
+	}
+	return nil
+}
+
+// pendingRepeatables counts the repeatable deltas that are new or changed.
+func pendingRepeatables(states []repeatableState) int {
+	count := 0
+	for _, state := range states {
+		if state.Status != RepeatableUpToDate {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,239 @@
+package apply
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inskribe/schemer/internal/output"
+	"github.com/inskribe/schemer/internal/templates"
+	"github.com/inskribe/schemer/internal/utils"
+	tu "github.com/inskribe/schemer/internal/utils/testutils"
+)
+
+func writeRepeatableTree(t *testing.T, files map[string]string) string {
+	tempDir := t.TempDir()
+	for rel, contents := range files {
+		full := filepath.Join(tempDir, rel)
+		if err := os.MkdirAll(filepath.Dir(full), os.ModePerm); err != nil {
+			t.Fatalf("failed to create dir for %s: %v", rel, err)
+		}
+		if err := os.WriteFile(full, []byte(contents), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", rel, err)
+		}
+	}
+	utils.GetDeltaPath = func() (string, error) {
+		return tempDir, nil
+	}
+	return tempDir
+}
+
+func TestLoadRepeatables(t *testing.T) {
+	writeRepeatableTree(t, map[string]string{
+		"R_02_functions.sql":    "-- functions",
+		"views/R_01_views.sql":  "-- views",
+		"R_01_views.sql":        "-- root views",
+		"R_bad.name.sql":        "-- ignored",
+		"001_init.up.sql":       "-- up",
+		"views/not_R_views.sql": "-- ignored",
+	})
+
+	deltas, err := loadRepeatables()
+	if err != nil {
+		t.Fatalf("loadRepeatables failed: %v", err)
+	}
+	expected := []string{"R_01_views.sql", "views/R_01_views.sql", "R_02_functions.sql"}
+	if len(deltas) != len(expected) {
+		t.Fatalf("expected %v, got %+v", expected, deltas)
+	}
+	for i, delta := range deltas {
+		if delta.File != expected[i] {
+			t.Errorf("expected repeatable %d to be %s, got %s", i, expected[i], delta.File)
+		}
+	}
+}
+
+func TestCompareRepeatables(t *testing.T) {
+	deltas := []RepeatableDelta{
+		{File: "R_a.sql", Checksum: "1"},
+		{File: "R_b.sql", Checksum: "2"},
+		{File: "R_c.sql", Checksum: "3"},
+	}
+	applied := map[string]string{"R_a.sql": "1", "R_b.sql": "old", "R_removed.sql": "4"}
+
+	states := compareRepeatables(applied, deltas)
+	expected := []RepeatableStatus{RepeatableUpToDate, RepeatableChanged, RepeatableNew}
+	for i, state := range states {
+		if state.Status != expected[i] {
+			t.Errorf("expected %s to be %s, got %s", state.Delta.File, expected[i], state.Status)
+		}
+	}
+	if pendingRepeatables(states) != 2 {
+		t.Errorf("expected 2 pending repeatables, got %d", pendingRepeatables(states))
+	}
+}
+
+func TestWriteStatus(t *testing.T) {
+	deltas := map[int64]UpDelta{
+		1: {Tag: 1, File: "001_users.up.sql"},
+		2: {Tag: 2, File: "002_orders.up.sql"},
+	}
+	applied := map[int64]bool{0: true, 1: true}
+	posts := map[int64]PostStatusEnum{1: Pending}
+	repeatables := []repeatableState{
+		{Delta: RepeatableDelta{File: "R_views.sql"}, Status: RepeatableChanged},
+	}
+
+	var out bytes.Buffer
+	if err := writeStatus(&out, applied, posts, deltas, repeatables, utils.Labels{"release-1": 1, "release-2": 2}); err != nil {
+		t.Fatalf("writeStatus failed: %v", err)
+	}
+
+	lines := strings.Split(out.String(), "\n")
+	for i, fields := range [][]string{
+		{"TAG", "FILE", "STATE", "POST"},
+		{"000", "-", "applied,", "file", "missing", "-"},
+		{"001", "001_users.up.sql", "applied", "pending"},
+		{"002", "002_orders.up.sql", "pending", "-"},
+	} {
+		if strings.Join(strings.Fields(lines[i]), " ") != strings.Join(fields, " ") {
+			t.Errorf("line %d = %q, expected fields %v", i, lines[i], fields)
+		}
+	}
+	for _, line := range []string{"R_views.sql  changed", "1 of 2 delta(s) pending, 1 repeatable delta(s) to apply", "Release label: release-1 (001)"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected status to contain %q, got:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestWriteStatus_JSON(t *testing.T) {
+	var out bytes.Buffer
+	previousWriter := output.Writer
+	t.Cleanup(func() {
+		output.Writer = previousWriter
+		_ = output.Configure(string(output.FormatText), "")
+	})
+	if err := output.Configure(string(output.FormatJSON), "status"); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	output.Writer = &out
+
+	deltas := map[int64]UpDelta{1: {Tag: 1, File: "001_users.up.sql"}, 2: {Tag: 2, File: "002_orders.up.sql"}}
+	if err := writeStatus(io.Discard, map[int64]bool{1: true}, nil, deltas, nil, nil); err != nil {
+		t.Fatalf("writeStatus failed: %v", err)
+	}
+
+	var event statusEvent
+	if err := json.Unmarshal(out.Bytes(), &event); err != nil {
+		t.Fatalf("expected a single JSON document, got %q: %v", out.String(), err)
+	}
+	if event.Event != "status" || event.Pending != 1 || event.Total != 2 || len(event.Deltas) != 2 || event.Release != nil {
+		t.Fatalf("unexpected status document %+v", event)
+	}
+	if event.Deltas[1].File != "002_orders.up.sql" || event.Deltas[1].State != "pending" {
+		t.Errorf("unexpected row %+v", event.Deltas[1])
+	}
+}
+
+func TestExecuteUpCommand_Repeatables(t *testing.T) {
+	tu.SetupTestTable(t)
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, _ = tu.SharedConnection.Exec(ctx, `
+			DROP VIEW IF EXISTS repeatable_view;
+			DROP TABLE IF EXISTS repeatable_test;
+			DROP TABLE IF EXISTS schemer_repeatable`)
+		upRequest = CommandArgs{}
+	})
+	if _, err := tu.SharedConnection.Exec(ctx, `DROP TABLE IF EXISTS schemer_repeatable`); err != nil {
+		t.Fatalf("failed to reset schemer_repeatable: %v", err)
+	}
+
+	tempDir := writeRepeatableTree(t, map[string]string{
+		"000_table.up.sql":   "CREATE TABLE repeatable_test (id integer);",
+		"000_table.down.sql": "DROP TABLE repeatable_test;",
+		"R_view.sql":         "CREATE OR REPLACE VIEW repeatable_view AS SELECT id FROM repeatable_test;",
+	})
+	schemerArgs := templates.SchemerTemplateArgs{TableName: "schemer"}
+	if err := schemerArgs.WriteTemplate(tempDir); err != nil {
+		t.Fatalf("failed to write table template: %v", err)
+	}
+
+	checksum := func() string {
+		var result string
+		err := tu.SharedConnection.QueryRow(ctx, `SELECT checksum FROM schemer_repeatable WHERE file_name = 'R_view.sql'`).Scan(&result)
+		if err != nil {
+			return ""
+		}
+		return result
+	}
+
+	upRequest = CommandArgs{dryRun: true}
+	if err := executeUpCommand(tu.SharedConnection, ctx); err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if checksum() != "" {
+		t.Fatalf("expected dry run not to apply the repeatable delta")
+	}
+
+	upRequest = CommandArgs{}
+	if err := executeUpCommand(tu.SharedConnection, ctx); err != nil {
+		t.Fatalf("up failed: %v", err)
+	}
+	first := checksum()
+	if first == "" {
+		t.Fatalf("expected the repeatable delta to be applied after the up delta")
+	}
+
+	changed := "CREATE OR REPLACE VIEW repeatable_view AS SELECT id, id * 2 AS doubled FROM repeatable_test;"
+	if err := os.WriteFile(filepath.Join(tempDir, "R_view.sql"), []byte(changed), 0o644); err != nil {
+		t.Fatalf("failed to change repeatable delta: %v", err)
+	}
+	if err := executeUpCommand(tu.SharedConnection, ctx); err != nil {
+		t.Fatalf("up with only a changed repeatable delta failed: %v", err)
+	}
+	if second := checksum(); second == first || second != utils.Checksum([]byte(changed)) {
+		t.Fatalf("expected the changed repeatable delta to be re-applied, checksum %s", second)
+	}
+
+	if err := executeUpCommand(tu.SharedConnection, ctx); err == nil {
+		t.Fatalf("expected up with nothing to apply to report that all deltas are applied")
+	}
+}
+
+func TestExecuteStatusCommand_ReadOnly(t *testing.T) {
+	writeRepeatableTree(t, map[string]string{
+		"001_users.up.sql": "CREATE TABLE users (id int);",
+		"R_views.sql":      "CREATE OR REPLACE VIEW v AS SELECT 1;",
+	})
+
+	original, originalOutput := utils.TrackingTable, statusOutput
+	t.Cleanup(func() { utils.TrackingTable, statusOutput = original, originalOutput })
+	utils.TrackingTable = "status_probe"
+	var out bytes.Buffer
+	statusOutput = &out
+
+	if err := executeStatusCommand(tu.SharedConnection, context.Background()); err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "pending") || !strings.Contains(out.String(), "new") {
+		t.Fatalf("expected the delta to be pending and the repeatable new, got:\n%s", out.String())
+	}
+
+	// Status must not create the tracking tables it reads.
+	for _, table := range utils.TrackingTables() {
+		exists, err := utils.TableExists(tu.SharedConnection, context.Background(), table)
+		if err != nil {
+			t.Fatalf("failed to look up %s: %v", table, err)
+		}
+		if exists {
+			t.Errorf("expected status not to create %s", table)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package apply
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/spf13/cobra"
+
+	"github.com/inskribe/schemer/cmd"
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/output"
+	"github.com/inskribe/schemer/internal/schema"
+	"github.com/inskribe/schemer/internal/scratch"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+// RoundtripStatus is the outcome of round-tripping a single delta.
+type RoundtripStatus string
+
+const (
+	RoundtripPassed  RoundtripStatus = "passed"  // down restored the schema the up started from
+	RoundtripFailed  RoundtripStatus = "failed"  // down is missing, failed, or left the schema changed
+	RoundtripSkipped RoundtripStatus = "skipped" // not tested because an earlier delta could not be applied
+)
+
+// RoundtripResult is the outcome of round-tripping a single delta.
+type RoundtripResult struct {
+	Tag      int64           `json:"tag"`               // tag of the delta
+	File     string          `json:"file"`              // up delta file relative to the deltas directory
+	Status   RoundtripStatus `json:"status"`            // passed, failed or skipped
+	Message  string          `json:"message,omitempty"` // reason for a failure or skip
+	Changes  []schema.Change `json:"changes,omitempty"` // differences left behind by the down delta
+	Duration time.Duration   `json:"-"`                 // time spent on the delta
+}
+
+// roundtripEvent is the document emitted by the round-trip test with --output json.
+type roundtripEvent struct {
+	output.Header
+	Results []roundtripEventResult `json:"results"`
+	Passed  int                    `json:"passed"`
+	Failed  int                    `json:"failed"`
+	Skipped int                    `json:"skipped"`
+}
+
+// roundtripEventResult is a RoundtripResult with its duration in milliseconds.
+type roundtripEventResult struct {
+	RoundtripResult
+	DurationMs int64 `json:"duration_ms"`
+}
+
+var (
+	roundtripOptions RoundtripOptions
+	roundtripRequest CommandArgs
+	roundtripOutput  io.Writer = os.Stdout
+
+	testCmd = &cobra.Command{
+		Use:   "test",
+		Short: "Test the deltas against a scratch database",
+	}
+
+	roundtripCmd = &cobra.Command{
+		Use:   "roundtrip [options]",
+		Short: "Verify that every down delta reverses its up delta",
+		Long: `The roundtrip command creates a scratch database on the given server and checks every
+delta in tag order:
+
+  1. snapshot the schema
+  2. apply the up delta
+  3. apply the down delta
+  4. snapshot the schema again and compare it with the first snapshot
+  5. apply the up delta and its post delta again before moving on
+
+A delta fails when its down file is missing, fails to apply, or leaves the schema
+different from before the up. Failures are reported with the schema diff. When a delta
+cannot be applied the remaining deltas are skipped. The scratch database is always dropped.
+
+The connection only needs permission to create databases, the deltas are never applied to it.
+
+Examples:
+  schemer test roundtrip -k DATABASE_URL
+  schemer test roundtrip -k DATABASE_URL --junit roundtrip.xml
+`,
+		Args: cobra.NoArgs,
+		RunE: func(command *cobra.Command, args []string) error {
+			if _, err := utils.LoadDotEnv(); err != nil {
+				return err
+			}
+
+			if err := parseApplyCommand(&roundtripRequest); err != nil {
+				return err
+			}
+
+			results, err := executeRoundtripCommand(roundtripRequest.connString)
+			if err != nil {
+				return err
+			}
+
+			failed, err := writeRoundtripReport(roundtripOutput, results)
+			if err != nil {
+				return err
+			}
+
+			if roundtripOptions.JUnit != "" {
+				if err := writeJUnitReport(roundtripOptions.JUnit, results); err != nil {
+					return err
+				}
+			}
+
+			if failed {
+				return &errschemer.SchemerErr{
+					Code:    "0193",
+					Message: "one or more tags failed or were skipped in the round-trip test",
+				}
+			}
+			return nil
+		},
+	}
+)
+
+func init() {
+	cmd.RootCmd.AddCommand(testCmd)
+	testCmd.AddCommand(roundtripCmd)
+	roundtripCmd.Flags().StringVarP(&roundtripRequest.connKey, "conn-key", "k", "", "The key to fetch the environment variable value for the server connection string.")
+	roundtripCmd.Flags().StringVarP(&roundtripRequest.connString, "conn-string", "s", "", "The driver specific connection string of the server. If passed the connection key will be ignored.")
+	roundtripCmd.Flags().StringArrayVar(&roundtripOptions.Schemas, "schema", schema.DefaultSchemas, "Schema to compare, may be repeated.")
+	roundtripCmd.Flags().StringVar(&roundtripOptions.JUnit, "junit", "", "Write a JUnit XML report to this file.")
+}
+
+// executeRoundtripCommand round-trips every delta on a scratch database created on the
+// server of adminConnString.
+//
+// Params:
+//   - adminConnString: connection string of any database on the target server
+//
+// Returns:
+//   - []RoundtripResult: one result per up delta in tag order
+//   - error: non-nil if the scratch database or the deltas cannot be prepared
+func executeRoundtripCommand(adminConnString string) ([]RoundtripResult, error) {
+	database, err := scratch.Create(adminConnString, scratch.Options{})
+	if err != nil {
+		return nil, err
+	}
+	glog.Info("Created scratch database %s", database.Name)
+	defer func() {
+		if err := database.Drop(); err != nil {
+			glog.Warn("%s", errschemer.FormatChain(err))
+		}
+	}()
+
+	var results []RoundtripResult
+	err = utils.WithConn(database.ConnString, func(connection *pgx.Conn, ctx context.Context) error {
+		results, err = roundtripDeltas(connection, ctx, roundtripOptions.Schemas)
+		return err
+	})
+	return results, err
+}
+
+// roundtripDeltas applies every delta to an empty database, checking that each down delta
+// restores the schema its up delta started from.
+//
+// Params:
+//   - connection: pointer to a pgx.Conn of an empty database
+//   - ctx: context for query execution
+//   - schemas: schemas compared before and after each down delta
+//
+// Returns:
+//   - []RoundtripResult: one result per up delta in tag order
+//   - error: non-nil if the deltas cannot be loaded or the schema cannot be read
+func roundtripDeltas(connection *pgx.Conn, ctx context.Context, schemas []string) ([]RoundtripResult, error) {
+	if err := utils.CreateSchemerTable(connection, ctx); err != nil {
+		return nil, err
+	}
+
+	ups, err := loadUpDeltas(&DeltaRequest{})
+	if err != nil {
+		return nil, err
+	}
+	downs, err := loadDownDeltas(&DeltaRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]int64, 0, len(ups))
+	for tag := range ups {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+
+	applied := make(map[int64]bool, len(tags))
+	results := make([]RoundtripResult, 0, len(tags))
+	for i, tag := range tags {
+		started := time.Now()
+		result, err := roundtripDelta(connection, ctx, schemas, applied, ups[tag], downs)
+		if err != nil {
+			return nil, err
+		}
+		result.Duration = time.Since(started)
+		results = append(results, result)
+
+		if _, ok := applied[tag]; ok {
+			glog.Info("Round-tripped delta %s: %s", utils.ToPrefix(tag), result.Status)
+			continue
+		}
+
+		// the database no longer matches the deltas, nothing after this tag can be tested
+		for _, skipped := range tags[i+1:] {
+			results = append(results, RoundtripResult{
+				Tag:     skipped,
+				File:    ups[skipped].File,
+				Status:  RoundtripSkipped,
+				Message: "delta " + utils.ToPrefix(tag) + " could not be applied",
+			})
+		}
+		break
+	}
+	return results, nil
+}
+
+// roundtripDelta applies up, down and up again for a single delta, followed by its post
+// delta. The tag is added to applied once the delta is left applied.
+//
+// Returns:
+//   - RoundtripResult: the outcome of the delta, Duration is not set
+//   - error: non-nil if the schema cannot be read
+func roundtripDelta(connection *pgx.Conn, ctx context.Context, schemas []string, applied map[int64]bool, up UpDelta, downs map[int64]DownDelta) (RoundtripResult, error) {
+	result := RoundtripResult{Tag: up.Tag, File: up.File, Status: RoundtripPassed}
+	single := map[int64]UpDelta{up.Tag: up}
+
+	before, err := schema.Introspect(connection, ctx, schemas)
+	if err != nil {
+		return result, err
+	}
+
+	if err := applyUpDeltas(applied, single, connection, ctx); err != nil {
+		result.Status, result.Message = RoundtripFailed, "up delta failed: "+errschemer.FormatChain(err)
+		return result, nil
+	}
+
+	down, ok := downs[up.Tag]
+	if ok {
+		if err := applyDownDeltas([]int64{up.Tag}, downs, connection, ctx); err != nil {
+			result.Status, result.Message = RoundtripFailed, "down delta failed: "+errschemer.FormatChain(err)
+			return result, nil
+		}
+
+		after, err := schema.Introspect(connection, ctx, schemas)
+		if err != nil {
+			return result, err
+		}
+		if result.Changes = schema.Diff(before, after); len(result.Changes) > 0 {
+			result.Status = RoundtripFailed
+			result.Message = fmt.Sprintf("%s did not restore the schema, %d difference(s) remain", down.File, len(result.Changes))
+		}
+
+		if err := applyUpDeltas(applied, single, connection, ctx); err != nil {
+			result.Status = RoundtripFailed
+			if result.Message == "" {
+				result.Message = "up delta failed after rolling back: " + errschemer.FormatChain(err)
+			}
+			return result, nil
+		}
+	} else {
+		result.Status, result.Message = RoundtripFailed, "no down delta found"
+	}
+
+	posts, err := loadPostDeltas(&DeltaRequest{Cherries: &map[int64]bool{up.Tag: true}}, connection, ctx)
+	if err != nil {
+		return result, err
+	}
+	if err := applyPostDeltas(posts, connection, ctx); err != nil {
+		result.Status, result.Message = RoundtripFailed, "post delta failed: "+errschemer.FormatChain(err)
+		return result, nil
+	}
+
+	applied[up.Tag] = true
+	return result, nil
+}
+
+// writeRoundtripReport writes one line per delta followed by the diff of failed deltas.
+//
+// Params:
+//   - out: writer the report is written to
+//   - results: the round-trip results
+//
+// Returns:
+//   - bool: true if any delta failed or was skipped
+//   - error: non-nil if writing fails
+func writeRoundtripReport(out io.Writer, results []RoundtripResult) (bool, error) {
+	var builder strings.Builder
+	counts := make(map[RoundtripStatus]int)
+	for _, result := range results {
+		counts[result.Status]++
+		fmt.Fprintf(&builder, "%-4s %s (%s)\n", strings.ToUpper(string(result.Status))[:4], result.File, result.Duration.Round(time.Millisecond))
+		if result.Message != "" {
+			fmt.Fprintf(&builder, "     %s\n", result.Message)
+		}
+		if len(result.Changes) > 0 {
+			for _, line := range strings.Split(strings.TrimRight(renderDiffText(result.Changes), "\n"), "\n") {
+				builder.WriteString("     " + line + "\n")
+			}
+		}
+	}
+	fmt.Fprintf(&builder, "\n%d passed, %d failed, %d skipped\n", counts[RoundtripPassed], counts[RoundtripFailed], counts[RoundtripSkipped])
+	failed := counts[RoundtripFailed]+counts[RoundtripSkipped] > 0
+
+	if output.JSON() {
+		event := &roundtripEvent{
+			Results: make([]roundtripEventResult, 0, len(results)),
+			Passed:  counts[RoundtripPassed],
+			Failed:  counts[RoundtripFailed],
+			Skipped: counts[RoundtripSkipped],
+		}
+		for _, result := range results {
+			event.Results = append(event.Results, roundtripEventResult{RoundtripResult: result, DurationMs: result.Duration.Milliseconds()})
+		}
+		output.Emit("roundtrip", event)
+		return failed, nil
+	}
+
+	if _, err := io.WriteString(out, builder.String()); err != nil {
+		return false, &errschemer.SchemerErr{
+			Code:    "0133",
+			Message: "failed to write round-trip report",
+			Err:     err,
+		}
+	}
+	return failed, nil
+}
+
+// junitSuites is the root element of a JUnit XML report.
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+// junitSuite is a JUnit testsuite element.
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+// junitCase is a JUnit testcase element.
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+// junitMessage is the body of a JUnit failure or skipped element.
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// writeJUnitReport writes the results as JUnit XML, one testcase per delta.
+//
+// Params:
+//   - path: file the report is written to
+//   - results: the round-trip results
+//
+// Returns:
+//   - error: non-nil if the report cannot be encoded or written
+func writeJUnitReport(path string, results []RoundtripResult) error {
+	suite := junitSuite{Name: "schemer roundtrip", Tests: len(results)}
+	var total time.Duration
+	for _, result := range results {
+		total += result.Duration
+		testCase := junitCase{
+			Name:      result.File,
+			ClassName: "schemer.roundtrip",
+			Time:      fmt.Sprintf("%.3f", result.Duration.Seconds()),
+		}
+		switch result.Status {
+		case RoundtripFailed:
+			suite.Failures++
+			testCase.Failure = &junitMessage{Message: result.Message, Body: renderDiffText(result.Changes)}
+		case RoundtripSkipped:
+			suite.Skipped++
+			testCase.Skipped = &junitMessage{Message: result.Message}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	suite.Time = fmt.Sprintf("%.3f", total.Seconds())
+
+	data, err := xml.MarshalIndent(junitSuites{Suites: []junitSuite{suite}}, "", "  ")
+	if err != nil {
+		return &errschemer.SchemerErr{
+			Code:    "0134",
+			Message: "failed to encode JUnit report",
+			Err:     err,
+		}
+	}
+	if err := os.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0644); err != nil {
+		return &errschemer.SchemerErr{
+			Code:    "0135",
+			Message: "failed to write JUnit report to " + path,
+			Err:     err,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package apply
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inskribe/schemer/internal/schema"
+	"github.com/inskribe/schemer/internal/utils"
+	tu "github.com/inskribe/schemer/internal/utils/testutils"
+)
+
+func TestRoundtripDeltas(t *testing.T) {
+	tu.SetupTestTable(t)
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, _ = tu.SharedConnection.Exec(ctx, `DROP SCHEMA IF EXISTS roundtrip_test CASCADE; DROP TABLE IF EXISTS schemer_dirty`)
+	})
+
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"000_schema.up.sql":    "CREATE SCHEMA roundtrip_test;",
+		"000_schema.down.sql":  "DROP SCHEMA roundtrip_test;",
+		"001_users.up.sql":     "CREATE TABLE roundtrip_test.users (id integer PRIMARY KEY);",
+		"001_users.down.sql":   "DROP TABLE roundtrip_test.users;",
+		"002_email.up.sql":     "ALTER TABLE roundtrip_test.users ADD COLUMN IF NOT EXISTS email text; CREATE INDEX users_email_idx ON roundtrip_test.users (email);",
+		"002_email.down.sql":   "DROP INDEX roundtrip_test.users_email_idx;",
+		"003_orders.up.sql":    "CREATE TABLE roundtrip_test.orders (id integer);",
+		"004_audit.up.sql":     "CREATE TABLE roundtrip_test.audit (id integer);",
+		"004_audit.down.sql":   "DROP TABLE roundtrip_test.audit;",
+		"005_broken.up.sql":    "ALTER TABLE roundtrip_test.missing ADD COLUMN id integer;",
+		"005_broken.down.sql":  "-- nothing to undo",
+		"006_skipped.up.sql":   "CREATE TABLE roundtrip_test.skipped (id integer);",
+		"006_skipped.down.sql": "DROP TABLE roundtrip_test.skipped;",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	utils.GetDeltaPath = func() (string, error) {
+		return tempDir, nil
+	}
+
+	results, err := roundtripDeltas(tu.SharedConnection, ctx, []string{"roundtrip_test"})
+	if err != nil {
+		t.Fatalf("roundtripDeltas failed: %v", err)
+	}
+
+	expected := []RoundtripStatus{RoundtripPassed, RoundtripPassed, RoundtripFailed, RoundtripFailed, RoundtripPassed, RoundtripFailed, RoundtripSkipped}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %+v", len(expected), results)
+	}
+	for i, result := range results {
+		if result.Status != expected[i] {
+			t.Errorf("expected %s to be %s, got %s: %s", result.File, expected[i], result.Status, result.Message)
+		}
+	}
+
+	email := results[2]
+	if len(email.Changes) != 1 || email.Changes[0].Kind != schema.KindTable || email.Changes[0].Name != "roundtrip_test.users" {
+		t.Fatalf("expected the leftover email column to be reported, got %+v", email.Changes)
+	}
+	if results[3].Message != "no down delta found" {
+		t.Fatalf("expected missing down delta to be reported, got %q", results[3].Message)
+	}
+}
+
+func TestWriteRoundtripReport(t *testing.T) {
+	results := []RoundtripResult{
+		{Tag: 1, File: "001_users.up.sql", Status: RoundtripPassed, Duration: 12 * time.Millisecond},
+		{
+			Tag: 2, File: "002_email.up.sql", Status: RoundtripFailed,
+			Message: "002_email.down.sql did not restore the schema, 1 difference(s) remain",
+			Changes: []schema.Change{{
+				Type: schema.ChangeChanged, Kind: schema.KindTable, Name: "public.users",
+				Source: "CREATE TABLE public.users (\n    id integer\n);",
+				Target: "CREATE TABLE public.users (\n    id integer,\n    email text\n);",
+			}},
+		},
+		{Tag: 3, File: "003_orders.up.sql", Status: RoundtripSkipped, Message: "delta 002 could not be applied"},
+	}
+
+	var out bytes.Buffer
+	failed, err := writeRoundtripReport(&out, results)
+	if err != nil {
+		t.Fatalf("writeRoundtripReport failed: %v", err)
+	}
+	if !failed {
+		t.Fatalf("expected the report to fail")
+	}
+	for _, line := range []string{
+		"PASS 001_users.up.sql (12ms)",
+		"FAIL 002_email.up.sql (0s)",
+		"+     email text",
+		"SKIP 003_orders.up.sql (0s)",
+		"1 passed, 1 failed, 1 skipped",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected report to contain %q, got:\n%s", line, out.String())
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "roundtrip.xml")
+	if err := writeJUnitReport(path, results); err != nil {
+		t.Fatalf("writeJUnitReport failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read JUnit report: %v", err)
+	}
+	for _, fragment := range []string{
+		`<testsuite name="schemer roundtrip" tests="3" failures="1" skipped="1"`,
+		`<testcase name="001_users.up.sql" classname="schemer.roundtrip" time="0.012"></testcase>`,
+		`<failure message="002_email.down.sql did not restore the schema, 1 difference(s) remain">`,
+		`<skipped message="delta 002 could not be applied"></skipped>`,
+	} {
+		if !strings.Contains(string(data), fragment) {
+			t.Errorf("expected JUnit report to contain %q, got:\n%s", fragment, data)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package apply
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/spf13/cobra"
+
+	"github.com/inskribe/schemer/cmd"
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/output"
+	"github.com/inskribe/schemer/internal/scratch"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+var (
+	scratchOptions ScratchOptions
+	scratchRequest CommandArgs
+	scratchOutput  io.Writer = os.Stdout
+
+	scratchCmd = &cobra.Command{
+		Use:   "scratch",
+		Short: "Manage throwaway databases for tests and CI",
+		Long: `The scratch commands create, list and drop uniquely named throwaway databases on the
+server given by --conn-key or --conn-string. The role needs the CREATEDB privilege.
+
+Scratch database names start with ` + scratch.NamePrefix + `, other databases are never dropped.
+
+Examples:
+  schemer scratch create -k ADMIN_DATABASE_URL --file scratch.url
+  schemer scratch create -k ADMIN_DATABASE_URL --template app_template
+  schemer scratch drop -k ADMIN_DATABASE_URL "$(cat scratch.url)"
+  schemer scratch drop -k ADMIN_DATABASE_URL --all
+`,
+		PersistentPreRunE: func(command *cobra.Command, args []string) error {
+			if err := cmd.RootCmd.PersistentPreRunE(command, args); err != nil {
+				return err
+			}
+
+			if _, err := utils.LoadDotEnv(); err != nil {
+				return err
+			}
+			return parseApplyCommand(&scratchRequest)
+		},
+	}
+
+	scratchCreateCmd = &cobra.Command{
+		Use:   "create [options]",
+		Short: "Create a scratch database with every delta applied and print its connection string",
+		Long: `The create command creates a uniquely named database, applies every up and post delta
+and prints its connection string, or writes it to the file given with --file.
+
+With --template the database is cloned from an existing database first, which is much faster
+than replaying a long delta history. Only deltas missing from the template are applied.
+The template must have no open connections while it is cloned.
+`,
+		Args: cobra.NoArgs,
+		RunE: func(command *cobra.Command, args []string) error {
+			return executeScratchCreateCommand(scratchRequest.connString)
+		},
+	}
+
+	scratchListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the scratch databases on the server",
+		Args:  cobra.NoArgs,
+		RunE: func(command *cobra.Command, args []string) error {
+			return executeScratchListCommand(scratchRequest.connString)
+		},
+	}
+
+	scratchDropCmd = &cobra.Command{
+		Use:   "drop [name|conn-string...] [options]",
+		Short: "Drop scratch databases",
+		Long: `The drop command drops the given scratch databases, disconnecting any open sessions.
+Each argument is a database name or a connection string returned by [schemer scratch create].
+Use --all to drop every scratch database on the server, e.g. to clean up after cancelled CI jobs.
+`,
+		RunE: func(command *cobra.Command, args []string) error {
+			return executeScratchDropCommand(scratchRequest.connString, args)
+		},
+	}
+)
+
+func init() {
+	cmd.RootCmd.AddCommand(scratchCmd)
+	scratchCmd.AddCommand(scratchCreateCmd, scratchListCmd, scratchDropCmd)
+	scratchCmd.PersistentFlags().StringVarP(&scratchRequest.connKey, "conn-key", "k", "", "The key to fetch the environment variable value for the server connection string.")
+	scratchCmd.PersistentFlags().StringVarP(&scratchRequest.connString, "conn-string", "s", "", "The driver specific connection string of the server. If passed the connection key will be ignored.")
+	scratchCreateCmd.Flags().StringVar(&scratchOptions.Template, "template", "", "Database to clone before applying deltas.")
+	scratchCreateCmd.Flags().StringVarP(&scratchOptions.File, "file", "f", "", "File to write the connection string to. Defaults to stdout.")
+	scratchCreateCmd.Flags().BoolVar(&scratchOptions.NoMigrate, "no-migrate", false, "Create the database without applying any deltas.")
+	scratchDropCmd.Flags().BoolVar(&scratchOptions.All, "all", false, "Drop every scratch database on the server.")
+}
+
+// scratchEvent is the document emitted by the scratch commands with --output json,
+// holding the created, listed or dropped databases.
+type scratchEvent struct {
+	output.Header
+	Databases []scratchDatabase `json:"databases"`
+	File      string            `json:"file,omitempty"` // file the connection string was written to with --file
+}
+
+// scratchDatabase is a scratch database reported by the scratch commands.
+type scratchDatabase struct {
+	Name       string `json:"name"`
+	ConnString string `json:"conn_string,omitempty"` // set by create unless written to a file
+}
+
+// executeScratchCreateCommand creates a scratch database and prints its connection string.
+//
+// Params:
+//   - adminConnString: connection string of any database on the target server
+//
+// Returns:
+//   - error: non-nil if the database cannot be created or migrated
+func executeScratchCreateCommand(adminConnString string) error {
+	var database *scratch.Database
+	var err error
+	if scratchOptions.NoMigrate {
+		database, err = scratch.Create(adminConnString, scratch.Options{Template: scratchOptions.Template})
+	} else {
+		database, err = BuildScratch(adminConnString, scratchOptions.Template)
+	}
+	if err != nil {
+		return err
+	}
+	if scratchOptions.File == "" {
+		if output.JSON() {
+			output.Emit("scratch", &scratchEvent{Databases: []scratchDatabase{{Name: database.Name, ConnString: database.ConnString}}})
+			return nil
+		}
+		return writeScratchLine(database.ConnString)
+	}
+	if err := os.WriteFile(scratchOptions.File, []byte(database.ConnString+"\n"), 0600); err != nil {
+		return &errschemer.SchemerErr{
+			Code:    "0142",
+			Message: "failed to write connection string to " + scratchOptions.File,
+			Err:     err,
+		}
+	}
+	glog.Info("Wrote connection string of %s to %s", database.Name, scratchOptions.File)
+	output.Emit("scratch", &scratchEvent{Databases: []scratchDatabase{{Name: database.Name}}, File: scratchOptions.File})
+	return nil
+}
+
+// executeScratchListCommand prints the name of every scratch database on the server.
+//
+// Params:
+//   - adminConnString: connection string of any database on the target server
+//
+// Returns:
+//   - error: non-nil if the server cannot be queried
+func executeScratchListCommand(adminConnString string) error {
+	names, err := scratch.List(adminConnString)
+	if err != nil {
+		return err
+	}
+	if output.JSON() {
+		event := &scratchEvent{Databases: []scratchDatabase{}}
+		for _, name := range names {
+			event.Databases = append(event.Databases, scratchDatabase{Name: name})
+		}
+		output.Emit("scratch", event)
+		return nil
+	}
+	for _, name := range names {
+		if err := writeScratchLine(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// executeScratchDropCommand drops the requested scratch databases, or all of them with --all.
+//
+// Params:
+//   - adminConnString: connection string of any database on the target server
+//   - args: database names or connection strings
+//
+// Returns:
+//   - error: non-nil if no database was requested, an argument is not a scratch database or a drop fails
+func executeScratchDropCommand(adminConnString string, args []string) error {
+	names := make([]string, 0, len(args))
+	for _, arg := range args {
+		name, err := scratchName(arg)
+		if err != nil {
+			return err
+		}
+		names = append(names, name)
+	}
+
+	if scratchOptions.All {
+		all, err := scratch.List(adminConnString)
+		if err != nil {
+			return err
+		}
+		names = append(names, all...)
+	} else if len(names) == 0 {
+		return &errschemer.SchemerErr{
+			Code:    "0139",
+			Kind:    errschemer.KindUsage,
+			Message: "pass the scratch databases to drop or use --all",
+		}
+	}
+
+	event := &scratchEvent{Databases: []scratchDatabase{}}
+	for _, name := range names {
+		database, err := scratch.Existing(adminConnString, name)
+		if err != nil {
+			return err
+		}
+		if err := database.Drop(); err != nil {
+			return err
+		}
+		glog.Info("Dropped scratch database %s", name)
+		event.Databases = append(event.Databases, scratchDatabase{Name: name})
+	}
+	output.Emit("scratch", event)
+	return nil
+}
+
+// scratchName returns the database name of a drop argument, which may be a plain name or
+// a connection string pointing at the database.
+//
+// Returns:
+//   - string: the database name
+//   - error: non-nil if the connection string cannot be parsed
+func scratchName(arg string) (string, error) {
+	if !isConnString(arg) {
+		return arg, nil
+	}
+	config, err := pgx.ParseConfig(arg)
+	if err != nil {
+		return "", &errschemer.SchemerErr{
+			Code:    "0140",
+			Message: "failed to parse connection string",
+			Err:     err,
+		}
+	}
+	return config.Database, nil
+}
+
+// writeScratchLine writes a single line to the scratch output.
+func writeScratchLine(line string) error {
+	if _, err := fmt.Fprintln(scratchOutput, line); err != nil {
+		return &errschemer.SchemerErr{
+			Code:    "0141",
+			Message: "failed to write to stdout",
+			Err:     err,
+		}
+	}
+	return nil
+}
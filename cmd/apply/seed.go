@@ -0,0 +1,278 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package apply
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/spf13/cobra"
+
+	"github.com/inskribe/schemer/cmd"
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+// productionEnvironments are never seeded unless --allow-production is passed.
+var productionEnvironments = []string{"prod", "production"}
+
+// defaultSeedEnvironment is seeded when no config profile is selected.
+const defaultSeedEnvironment = "dev"
+
+var (
+	seedOptions SeedOptions
+	seedRequest CommandArgs
+
+	seedCmd = &cobra.Command{
+		Use:   "seed [options]",
+		Short: "Apply reference and fixture data for an environment",
+		Long: `The seed command applies the .sql files in the seeds directory to the database.
+
+Files directly in seeds/ are applied for every environment, followed by the files in the
+folder named after the profile selected with --env, dev if none is selected. Within each
+folder files are applied in filename order.
+
+  seeds/
+    001_countries.sql      # every environment
+    dev/
+      001_users.sql        # only with --env dev
+    staging/
+      001_users.sql
+
+Every applied seed is recorded with its checksum in the schemer_seeds table, separately from
+deltas. Running seed again skips unchanged files and re-applies files whose contents changed,
+so seeds should be written to be re-runnable, e.g. with INSERT ... ON CONFLICT.
+Each seed runs in its own transaction.
+
+The prod and production environments are refused unless --allow-production is passed.
+
+Examples:
+  schemer seed
+  schemer seed --env staging
+  schemer seed --env staging --dry-run
+`,
+		Args: cobra.NoArgs,
+		RunE: func(command *cobra.Command, args []string) error {
+			if _, err := utils.LoadDotEnv(); err != nil {
+				return err
+			}
+
+			if err := parseApplyCommand(&seedRequest); err != nil {
+				return err
+			}
+
+			seedOptions.Env = cmd.Profile()
+			if seedOptions.Env == "" {
+				seedOptions.Env = defaultSeedEnvironment
+			}
+
+			if !seedRequest.dryRun {
+				if err := cmd.CheckProtected("seed"); err != nil {
+					return err
+				}
+			}
+
+			return utils.WithConn(seedRequest.connString, executeSeedCommand)
+		},
+	}
+)
+
+func init() {
+	cmd.RootCmd.AddCommand(seedCmd)
+	seedCmd.Flags().StringVarP(&seedRequest.connKey, "conn-key", "k", "", "The key to fetch the environment variable value for the database connection string.")
+	seedCmd.Flags().StringVarP(&seedRequest.connString, "conn-string", "s", "", "The driver specific connection string. If passed the connection key will be ignored.")
+	seedCmd.Flags().BoolVarP(&seedRequest.dryRun, "dry-run", "d", false, "Print the rendered SQL of the seeds that would be applied without applying them.")
+	seedCmd.Flags().BoolVar(&seedOptions.AllowProduction, "allow-production", false, "Allow seeding the prod and production environments.")
+}
+
+// executeSeedCommand applies every new or changed seed for the requested environment.
+//
+// Params:
+//   - connection: pointer to a pgx.Conn for executing SQL statements
+//   - ctx: context for query execution
+//
+// Returns:
+//   - error: non-nil if the environment is refused, the seeds cannot be read or a seed fails
+func executeSeedCommand(connection *pgx.Conn, ctx context.Context) error {
+	env := seedOptions.Env
+	if slices.Contains(productionEnvironments, strings.ToLower(env)) && !seedOptions.AllowProduction {
+		return &errschemer.SchemerErr{
+			Code:    "0150",
+			Message: fmt.Sprintf("refusing to seed the %s environment, pass --allow-production to seed it anyway", env),
+		}
+	}
+
+	seeds, err := loadSeeds(env)
+	if err != nil {
+		return err
+	}
+	if len(seeds) == 0 {
+		glog.Info("No seeds found for environment %s", env)
+		return nil
+	}
+
+	if err := utils.CheckNotDirty(connection, ctx); err != nil {
+		return err
+	}
+	if !seedRequest.dryRun {
+		if err := utils.EnsureTrackingTables(connection, ctx); err != nil {
+			return err
+		}
+	}
+
+	applied, err := utils.GetAppliedSeeds(connection, ctx)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, seed := range seeds {
+		checksum, ok := applied[seed.File]
+		if ok && checksum == seed.Checksum {
+			continue
+		}
+
+		reason := "new"
+		if ok {
+			reason = "changed"
+		}
+		count++
+
+		if seedRequest.dryRun {
+			if err := logDryRun(utils.DirectionSeed, nil, seed.File, seed.Data, reason); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := applySeed(connection, ctx, env, seed); err != nil {
+			return err
+		}
+		glog.Info("Applied seed %s (%s)", seed.File, reason)
+	}
+
+	if count == 0 {
+		glog.Info("All %d seed(s) for environment %s are up to date", len(seeds), env)
+	}
+	return nil
+}
+
+// loadSeeds reads the shared seed files followed by the seed files of env, each in filename order.
+//
+// Params:
+//   - env: name of the environment folder inside the seeds directory
+//
+// Returns:
+//   - []Seed: seeds in the order they are applied
+//   - error: non-nil if env is not a plain folder name or a file cannot be read
+func loadSeeds(env string) ([]Seed, error) {
+	if env == "" || env != filepath.Base(env) || strings.HasPrefix(env, ".") {
+		return nil, &errschemer.SchemerErr{
+			Code:    "0151",
+			Message: fmt.Sprintf("invalid --env %q, expected the name of a folder in the seeds directory", env),
+		}
+	}
+
+	seedPath, err := utils.GetSeedPath()
+	if err != nil {
+		return nil, err
+	}
+
+	var seeds []Seed
+	for _, directory := range []string{seedPath, filepath.Join(seedPath, env)} {
+		entries, err := os.ReadDir(directory)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, &errschemer.SchemerErr{
+				Code:    "0152",
+				Message: "failed to read seeds directory: " + directory,
+				Err:     err,
+			}
+		}
+
+		var names []string
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".sql") {
+				names = append(names, entry.Name())
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			path := filepath.Join(directory, name)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, &errschemer.SchemerErr{
+					Code:    "0153",
+					Message: "failed to read seed file at: " + path,
+					Err:     err,
+				}
+			}
+			seeds = append(seeds, Seed{
+				File:     relativeDeltaPath(seedPath, path),
+				Data:     data,
+				Checksum: utils.Checksum(data),
+			})
+		}
+	}
+	return seeds, nil
+}
+
+// applySeed executes a seed and records its checksum in a single transaction.
+//
+// Params:
+//   - connection: pointer to a pgx.Conn for executing SQL statements
+//   - ctx: context for query execution
+//   - env: environment the seed is applied for
+//   - seed: the seed to apply
+//
+// Returns:
+//   - error: non-nil if the seed fails or cannot be recorded
+func applySeed(connection *pgx.Conn, ctx context.Context, env string, seed Seed) error {
+	err := executeTracked(connection, ctx, trackedExecution{
+		Direction: utils.DirectionSeed,
+		File:      filepath.ToSlash(filepath.Join("seeds", seed.File)),
+		Data:      seed.Data,
+		Checksum:  seed.Checksum,
+		Note:      "env " + env,
+		Record: func(connection *pgx.Conn, ctx context.Context) error {
+			return utils.RecordSeed(connection, ctx, seed.File, env, seed.Checksum)
+		},
+	})
+	if err != nil {
+		return &errschemer.SchemerErr{
+			Code:    "0154",
+			Kind:    errschemer.KindExecution,
+			Message: "failed to apply seed: " + seed.File,
+			Err:     err,
+		}
+	}
+	return nil
+}
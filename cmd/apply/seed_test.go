@@ -0,0 +1,154 @@
+package apply
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/utils"
+	tu "github.com/inskribe/schemer/internal/utils/testutils"
+)
+
+func writeSeedFiles(t *testing.T, files map[string]string) string {
+	seedDir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(seedDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	utils.GetSeedPath = func() (string, error) {
+		return seedDir, nil
+	}
+	return seedDir
+}
+
+func TestLoadSeeds(t *testing.T) {
+	writeSeedFiles(t, map[string]string{
+		"002_currencies.sql":  "-- shared",
+		"001_countries.sql":   "-- shared",
+		"README.md":           "not a seed",
+		"dev/001_users.sql":   "-- dev",
+		"dev/nested/skip.sql": "-- ignored",
+		"staging/001_x.sql":   "-- staging",
+	})
+
+	seeds, err := loadSeeds("dev")
+	if err != nil {
+		t.Fatalf("loadSeeds failed: %v", err)
+	}
+	expected := []string{"001_countries.sql", "002_currencies.sql", "dev/001_users.sql"}
+	if len(seeds) != len(expected) {
+		t.Fatalf("expected %v, got %+v", expected, seeds)
+	}
+	for i, seed := range seeds {
+		if seed.File != expected[i] {
+			t.Errorf("expected seed %d to be %s, got %s", i, expected[i], seed.File)
+		}
+		if seed.Checksum != utils.Checksum(seed.Data) {
+			t.Errorf("expected checksum of %s to match its contents", seed.File)
+		}
+	}
+
+	if seeds, err := loadSeeds("qa"); err != nil || len(seeds) != 2 {
+		t.Fatalf("expected only shared seeds for an environment without a folder, got %+v, %v", seeds, err)
+	}
+
+	for _, env := range []string{"", "../dev", ".hidden"} {
+		if _, err := loadSeeds(env); err == nil {
+			t.Errorf("expected invalid environment %q to be refused", env)
+		}
+	}
+}
+
+func TestExecuteSeedCommand(t *testing.T) {
+	ctx := context.Background()
+	_, err := tu.SharedConnection.Exec(ctx, `
+		DROP TABLE IF EXISTS seed_test;
+		CREATE TABLE seed_test (name TEXT PRIMARY KEY, value INT);
+		DROP TABLE IF EXISTS schemer_seeds;`)
+	if err != nil {
+		t.Fatalf("failed to create seed table: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = tu.SharedConnection.Exec(ctx, `DROP TABLE IF EXISTS seed_test; DROP TABLE IF EXISTS schemer_seeds`)
+		seedOptions = SeedOptions{}
+	})
+
+	seedDir := writeSeedFiles(t, map[string]string{
+		"001_shared.sql":    "INSERT INTO seed_test VALUES ('shared', 1) ON CONFLICT (name) DO UPDATE SET value = seed_test.value + 1;",
+		"dev/001_users.sql": "INSERT INTO seed_test VALUES ('dev', 1) ON CONFLICT (name) DO UPDATE SET value = seed_test.value + 1;",
+	})
+	seedOptions = SeedOptions{Env: "dev"}
+
+	value := func(name string) int {
+		var result int
+		if err := tu.SharedConnection.QueryRow(ctx, `SELECT value FROM seed_test WHERE name = $1`, name).Scan(&result); err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		return result
+	}
+
+	for run := 0; run < 2; run++ {
+		if err := executeSeedCommand(tu.SharedConnection, ctx); err != nil {
+			t.Fatalf("seed run %d failed: %v", run, err)
+		}
+	}
+	if value("shared") != 1 || value("dev") != 1 {
+		t.Fatalf("expected unchanged seeds to be applied once, got shared=%d dev=%d", value("shared"), value("dev"))
+	}
+
+	changed := "-- changed\nINSERT INTO seed_test VALUES ('dev', 1) ON CONFLICT (name) DO UPDATE SET value = seed_test.value + 1;"
+	if err := os.WriteFile(filepath.Join(seedDir, "dev", "001_users.sql"), []byte(changed), 0644); err != nil {
+		t.Fatalf("failed to change seed: %v", err)
+	}
+	if err := executeSeedCommand(tu.SharedConnection, ctx); err != nil {
+		t.Fatalf("seed run after change failed: %v", err)
+	}
+	if value("shared") != 1 || value("dev") != 2 {
+		t.Fatalf("expected only the changed seed to re-run, got shared=%d dev=%d", value("shared"), value("dev"))
+	}
+
+	seedOptions = SeedOptions{Env: "production"}
+	err = executeSeedCommand(tu.SharedConnection, ctx)
+	if schemerErr, ok := err.(*er.SchemerErr); !ok || schemerErr.Code != "0150" {
+		t.Fatalf("expected production to be refused with 0150, got %v", err)
+	}
+}
+
+func TestExecuteSeedCommand_DryRunReadOnly(t *testing.T) {
+	ctx := context.Background()
+	original := utils.TrackingTable
+	t.Cleanup(func() {
+		utils.TrackingTable = original
+		seedOptions = SeedOptions{}
+		seedRequest.dryRun = false
+	})
+	utils.TrackingTable = "seed_probe"
+	seedRequest.dryRun = true
+	seedOptions = SeedOptions{Env: "dev"}
+
+	writeSeedFiles(t, map[string]string{
+		"001_shared.sql": "CREATE TABLE seed_probe_data (id int);",
+	})
+
+	if err := executeSeedCommand(tu.SharedConnection, ctx); err != nil {
+		t.Fatalf("seed dry run failed: %v", err)
+	}
+
+	// A dry run must neither run the seed nor create the tracking tables it reads.
+	for _, table := range append(utils.TrackingTables(), "seed_probe_data") {
+		exists, err := utils.TableExists(tu.SharedConnection, ctx, table)
+		if err != nil {
+			t.Fatalf("failed to look up %s: %v", table, err)
+		}
+		if exists {
+			t.Errorf("expected the dry run not to create %s", table)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package apply
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/spf13/cobra"
+
+	"github.com/inskribe/schemer/cmd"
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/output"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+var (
+	statusRequest CommandArgs
+	statusOutput  io.Writer = os.Stdout
+
+	statusCmd = &cobra.Command{
+		Use:   "status [options]",
+		Short: "Show which deltas and repeatable deltas are applied",
+		Long: `The status command compares the deltas directory with the database.
+
+Every up delta is listed with whether it is applied and the state of its post delta.
+Applied tags whose file no longer exists are listed as missing. Repeatable deltas are
+listed as up to date, new or changed; new and changed ones are applied by the next [schemer up].
+Deltas left dirty by a failed run are reported as warnings. When the deltas directory has a
+labels.txt, the highest release label whose deltas are all applied is shown.
+
+Examples:
+  schemer status
+  schemer status -k STAGING_DATABASE_URL
+`,
+		Args: cobra.NoArgs,
+		RunE: func(command *cobra.Command, args []string) error {
+			if _, err := utils.LoadDotEnv(); err != nil {
+				return err
+			}
+
+			if err := parseApplyCommand(&statusRequest); err != nil {
+				return err
+			}
+
+			return utils.WithConn(statusRequest.connString, executeStatusCommand)
+		},
+	}
+)
+
+func init() {
+	cmd.RootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().StringVarP(&statusRequest.connKey, "conn-key", "k", "", "The key to fetch the environment variable value for the database connection string.")
+	statusCmd.Flags().StringVarP(&statusRequest.connString, "conn-string", "s", "", "The driver specific connection string. If passed the connection key will be ignored.")
+}
+
+// executeStatusCommand writes the state of every up delta and repeatable delta.
+// It only reads the database: tracking tables that do not exist yet are treated as empty.
+//
+// Params:
+//   - connection: pointer to a pgx.Conn for querying the tracking tables
+//   - ctx: context for query execution
+//
+// Returns:
+//   - error: non-nil if the deltas or the tracking tables cannot be read
+func executeStatusCommand(connection *pgx.Conn, ctx context.Context) error {
+	hasDirty, err := utils.TableExists(connection, ctx, utils.DirtyTable())
+	if err != nil {
+		return err
+	}
+	if hasDirty {
+		dirty, err := utils.GetDirtyDeltas(connection, ctx)
+		if err != nil {
+			return err
+		}
+		for _, delta := range dirty {
+			glog.Warn("Delta %s (%s, %s) did not finish, resolve it with [schemer repair]",
+				utils.ToPrefix(delta.Tag), delta.Direction, delta.File)
+		}
+	}
+
+	applied, postStatuses := map[int64]bool{}, map[int64]PostStatusEnum{}
+	hasTracking, err := utils.TableExists(connection, ctx, utils.TrackingTable)
+	if err != nil {
+		return err
+	}
+	if hasTracking {
+		if applied, err = GetAppliedDeltas(connection, ctx); err != nil {
+			return err
+		}
+		if postStatuses, err = fetchPostStatuses(connection, ctx); err != nil {
+			return err
+		}
+	} else {
+		glog.Info("The %s table does not exist yet, no delta has been applied", utils.TrackingTable)
+	}
+
+	deltas, err := loadUpDeltas(&DeltaRequest{})
+	if err != nil {
+		return err
+	}
+	repeatables, err := loadRepeatableStates(connection, ctx)
+	if err != nil {
+		return err
+	}
+	labels, err := utils.LoadLabels()
+	if err != nil {
+		return err
+	}
+
+	return writeStatus(statusOutput, applied, postStatuses, deltas, repeatables, labels)
+}
+
+// statusRow is a line of the delta table printed by the status command.
+type statusRow struct {
+	Tag   int64  `json:"tag"`
+	File  string `json:"file,omitempty"` // empty if the delta file of an applied tag is missing
+	State string `json:"state"`          // pending, applied or skipped, with the environments the delta is limited to
+	Post  string `json:"post,omitempty"` // pending or applied, empty without a post delta
+}
+
+// statusRepeatable is a line of the repeatable delta table printed by the status command.
+type statusRepeatable struct {
+	File  string `json:"file"`
+	State string `json:"state"`
+}
+
+// statusEvent is the document emitted by the status command with --output json.
+type statusEvent struct {
+	output.Header
+	Deltas             []statusRow        `json:"deltas"`
+	Repeatables        []statusRepeatable `json:"repeatables"`
+	Pending            int                `json:"pending"`             // number of pending deltas
+	Total              int                `json:"total"`               // number of delta files
+	PendingRepeatables int                `json:"pending_repeatables"` // number of repeatable deltas to apply
+	Release            *statusRelease     `json:"release,omitempty"`   // set if any release labels are defined
+}
+
+// statusRelease is the highest release label satisfied by the database.
+type statusRelease struct {
+	Label string `json:"label,omitempty"` // empty if no label is satisfied
+	Tag   int64  `json:"tag,omitempty"`
+}
+
+// buildStatusRows builds the delta table of the status command.
+//
+// Params:
+//   - applied: map of applied delta tags
+//   - postStatuses: post status of every tag with a post delta
+//   - deltas: up deltas found in the deltas directory
+//
+// Returns:
+//   - []statusRow: a row for every tag with a delta file or an applied record, in tag order
+//   - []int64: tags of the pending deltas
+func buildStatusRows(applied map[int64]bool, postStatuses map[int64]PostStatusEnum, deltas map[int64]UpDelta) ([]statusRow, []int64) {
+	tagSet := make(map[int64]bool, len(deltas)+len(applied))
+	for tag := range deltas {
+		tagSet[tag] = true
+	}
+	for tag := range applied {
+		tagSet[tag] = true
+	}
+	tags := make([]int64, 0, len(tagSet))
+	for tag := range tagSet {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+
+	postLabels := map[PostStatusEnum]string{Pending: "pending", Applied: "applied"}
+
+	rows := make([]statusRow, 0, len(tags))
+	var pending []int64
+	for _, tag := range tags {
+		delta, exists := deltas[tag]
+		file, state := delta.File, "applied"
+		switch {
+		case !exists:
+			file, state = "", "applied, file missing"
+		case !applied[tag]:
+			state = "pending"
+			pending = append(pending, tag)
+		}
+		if exists && len(delta.Environments) > 0 {
+			only := "(" + strings.Join(delta.Environments, ", ") + " only)"
+			if applied[tag] && activeEnvironment() != "" && !runsInEnvironment(delta.Environments) {
+				state = "skipped " + only
+			} else {
+				state += " " + only
+			}
+		}
+		rows = append(rows, statusRow{Tag: tag, File: file, State: state, Post: postLabels[postStatuses[tag]]})
+	}
+	return rows, pending
+}
+
+// writeStatus writes the delta and repeatable delta tables, or the status document with --output json.
+//
+// Params:
+//   - out: writer the tables are written to
+//   - applied: map of applied delta tags
+//   - postStatuses: post status of every tag with a post delta
+//   - deltas: up deltas found in the deltas directory
+//   - repeatables: repeatable deltas with their status
+//   - labels: release labels, the satisfied one is shown if any are defined
+//
+// Returns:
+//   - error: non-nil if writing fails
+func writeStatus(out io.Writer, applied map[int64]bool, postStatuses map[int64]PostStatusEnum, deltas map[int64]UpDelta, repeatables []repeatableState, labels utils.Labels) error {
+	rows, pending := buildStatusRows(applied, postStatuses, deltas)
+
+	var release *statusRelease
+	if len(labels) > 0 {
+		name, tag := labels.Satisfied(applied, pending)
+		release = &statusRelease{Label: name, Tag: tag}
+	}
+
+	if output.JSON() {
+		event := &statusEvent{
+			Deltas:             rows,
+			Repeatables:        make([]statusRepeatable, 0, len(repeatables)),
+			Pending:            len(pending),
+			Total:              len(deltas),
+			PendingRepeatables: pendingRepeatables(repeatables),
+			Release:            release,
+		}
+		for _, state := range repeatables {
+			event.Repeatables = append(event.Repeatables, statusRepeatable{File: state.Delta.File, State: string(state.Status)})
+		}
+		output.Emit("status", event)
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "TAG\tFILE\tSTATE\tPOST")
+	for _, row := range rows {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", utils.ToPrefix(row.Tag), cmp.Or(row.File, "-"), row.State, cmp.Or(row.Post, "-"))
+	}
+
+	if err := writer.Flush(); err != nil {
+		return statusWriteErr(err)
+	}
+
+	if len(repeatables) > 0 {
+		writer = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "\nREPEATABLE\tSTATE")
+		for _, state := range repeatables {
+			fmt.Fprintf(writer, "%s\t%s\n", state.Delta.File, state.Status)
+		}
+		if err := writer.Flush(); err != nil {
+			return statusWriteErr(err)
+		}
+	}
+
+	if _, err := fmt.Fprintf(out, "\n%d of %d delta(s) pending, %d repeatable delta(s) to apply\n",
+		len(pending), len(deltas), pendingRepeatables(repeatables)); err != nil {
+		return statusWriteErr(err)
+	}
+
+	if release == nil {
+		return nil
+	}
+	text := "none"
+	if release.Label != "" {
+		text = fmt.Sprintf("%s (%s)", release.Label, utils.ToPrefix(release.Tag))
+	}
+	if _, err := fmt.Fprintf(out, "Release label: %s\n", text); err != nil {
+		return statusWriteErr(err)
+	}
+	return nil
+}
+
+// statusWriteErr wraps a failure to write the status output.
+func statusWriteErr(err error) error {
+	return &errschemer.SchemerErr{
+		Code:    "0164",
+		Message: "failed to write status",
+		Err:     err,
+	}
+}
@@ -35,6 +35,10 @@ type CommandArgs struct {
 	toTag                string   // boundary tag (upper for up, lower for down)
 	fromTag              string   // boundary tag (lower for up, upper for down)
 	cherryPickedVersions []string // specific delta tags to apply instead of a range
+	allowOutOfOrder      bool     // if true, applies pending up deltas lower than the highest applied tag
+	exitNothingToDo      bool     // if true, up fails with ExitNothingToDo when nothing is pending
+	snapshotPath         string   // if set, a schema snapshot is written here after a successful run
+	snapshotSchemas      []string // schemas included in the snapshot
 }
 
 // DeltaRequest defines the range or specific set of deltas to apply.
@@ -49,10 +53,10 @@ type CommandArgs struct {
 //
 // If Cherries is set, it overrides From/To and applies only the specified tags.
 type DeltaRequest struct {
-	To       *int          // tag boundary (upper for up, lower for down)
-	From     *int          // tag boundary (lower for up, upper for down)
-	Cherries *map[int]bool // specific delta tags to apply; overrides From/To if set
-	LastTag  *int          // indicates that only the last delta should be applied
+	To       *int64          // tag boundary (upper for up, lower for down)
+	From     *int64          // tag boundary (lower for up, upper for down)
+	Cherries *map[int64]bool // specific delta tags to apply; overrides From/To if set
+	LastTag  *int64          // indicates that only the last delta should be applied
 }
 
 // Represents user input for post command.
@@ -60,6 +64,66 @@ type PostForce struct {
 	Force bool // allow applying post deltas even if not registered in schemer
 }
 
+// Represents user input for repair command.
+type RepairOptions struct {
+	MarkApplied    bool // record the dirty delta as if it had completed successfully
+	MarkRolledBack bool // record the dirty delta as if it had never been executed
+}
+
+// Represents user input for renumber command.
+type RenumberOptions struct {
+	Versioning string // tagging scheme of the new tags, sequential or timestamp
+}
+
+// Represents user input for dump command.
+type DumpOptions struct {
+	File    string   // file to write the snapshot to, stdout if empty
+	Schemas []string // schemas to include in the snapshot
+}
+
+// Represents user input for diff command.
+type DiffOptions struct {
+	Source     string   // connection string, environment key or snapshot file
+	Target     string   // connection string, environment key or snapshot file
+	Schemas    []string // schemas to introspect on live databases
+	Format     string   // text or json
+	FailOnDiff bool     // exit with a non-zero status if any difference is found
+}
+
+// Represents user input for test roundtrip command.
+type RoundtripOptions struct {
+	Schemas []string // schemas compared before and after each down delta
+	JUnit   string   // file to write a JUnit XML report to, skipped if empty
+}
+
+// Represents user input for scratch commands.
+type ScratchOptions struct {
+	Template  string // database to clone when creating a scratch database
+	NoMigrate bool   // if true, deltas are not applied to the new scratch database
+	File      string // file to write the connection string to, stdout if empty
+	All       bool   // if true, every scratch database on the server is dropped
+}
+
+// Represents user input for seed command.
+type SeedOptions struct {
+	Env             string // environment whose seed folder is applied, the active config profile
+	AllowProduction bool   // if true, production environments may be seeded
+}
+
+// Seed is a seed file and its checksum.
+type Seed struct {
+	File     string // path of the seed file relative to the seeds directory
+	Data     []byte // raw SQL content of the seed file
+	Checksum string // sha256 of the seed file
+}
+
+// RepeatableDelta represents a repeatable delta and its checksum.
+type RepeatableDelta struct {
+	File     string // path of the delta file relative to the deltas directory
+	Data     []byte // raw SQL content of the repeatable delta
+	Checksum string // sha256 of the repeatable delta
+}
+
 // PostStatusEnum represents the state of a post delta.
 //
 // Possible values:
@@ -76,14 +140,26 @@ const (
 
 // PostDelta represents a post-migration delta and its metadata.
 type PostDelta struct {
-	Tag        int            // unique identifier of the delta
-	Data       []byte         // raw SQL content of the post delta
-	PostStatus PostStatusEnum // current post status (e.g., Pending, Applied)
+	Tag          int64          // unique identifier of the delta
+	File         string         // path of the delta file relative to the deltas directory
+	Data         []byte         // raw SQL content of the post delta
+	PostStatus   PostStatusEnum // current post status (e.g., Pending, Applied)
+	Environments []string       // environments the delta group is limited to, empty for all
 }
 
 // UpDelta represents a forward (up) delta and its metadata.
 type UpDelta struct {
-	Tag        int            // unique identifier of the delta
-	Data       []byte         // raw SQL content of the up delta
-	PostStatus PostStatusEnum // post delta status (e.g., NoExist, Pending)
+	Tag          int64          // unique identifier of the delta
+	File         string         // path of the delta file relative to the deltas directory
+	Data         []byte         // raw SQL content of the up delta
+	PostStatus   PostStatusEnum // post delta status (e.g., NoExist, Pending)
+	Environments []string       // environments the delta group is limited to, empty for all
+}
+
+// DownDelta represents a rollback (down) delta and its metadata.
+type DownDelta struct {
+	Tag          int64    // unique identifier of the delta
+	File         string   // path of the delta file relative to the deltas directory
+	Data         []byte   // raw SQL content of the down delta
+	Environments []string // environments the delta group is limited to, empty for all
 }
@@ -27,10 +27,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
-	"regexp"
-	"sort"
-	"strconv"
-	"strings"
+	"slices"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/spf13/cobra"
@@ -38,6 +35,7 @@ import (
 	"github.com/inskribe/schemer/cmd"
 	"github.com/inskribe/schemer/internal/errschemer"
 	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/output"
 	"github.com/inskribe/schemer/internal/utils"
 )
 
@@ -57,27 +55,26 @@ Examples:
   schemer up --from 003 --to 006
   schemer up --cherry-pick 004,007
   schemer up --prune-no-op
-`,
-		Run: func(command *cobra.Command, args []string) {
-			if cmd.RootCmd.PersistentPreRun != nil {
-				cmd.RootCmd.PersistentPreRun(command, args)
-			}
+  schemer up --allow-out-of-order
+
+Pending deltas with a tag lower than the highest applied delta are rejected by default,
+this usually means a branch was merged after a later delta was applied.
 
-			_, err := utils.LoadDotEnv()
-			if err != nil {
-				glog.Error("%s", errschemer.FormatChain(err))
-				return
+Repeatable deltas, files named R_<name>.sql, are applied after the versioned deltas whenever
+they are new or their contents changed, in filename order. Use them for views, functions and
+grants that are redefined over time. They are skipped when --to or --cherry-pick is used.
+Use --dry-run to print the rendered SQL of the deltas and repeatable deltas that would be applied.
+`,
+		RunE: func(command *cobra.Command, args []string) error {
+			if _, err := utils.LoadDotEnv(); err != nil {
+				return err
 			}
 
 			if err := parseApplyCommand(&upRequest); err != nil {
-				glog.Error("%s", errschemer.FormatChain(err))
-				return
+				return err
 			}
 
-			if err := utils.WithConn(upRequest.connString, executeUpCommand); err != nil {
-				glog.Error("%s", errschemer.FormatChain(err))
-				return
-			}
+			return utils.WithConn(upRequest.connString, withSnapshot(&upRequest, executeUpCommand))
 		},
 	}
 )
@@ -90,18 +87,26 @@ func init() {
 	upCmd.PersistentFlags().BoolVar(&upRequest.PruneNoOp, "prune", false, `Enable no-operation file prunning. Scan delta files and skip applying files
 that only contains comments and empty lines. This can be useful for large replays to avoid unnessecarry database calls.`)
 	upCmd.PersistentFlags().StringVarP(&upRequest.toTag, "to", "t", "", `Specify the version to end at. Accepted formats are: 
-  4   - No Padding
-  004 - Padded zeros`)
+  4           - No Padding
+  004         - Padded zeros
+  release-2.3 - Label defined in the deltas directory labels.txt`)
 
 	upCmd.PersistentFlags().StringVarP(&upRequest.fromTag, "from", "f", "", `Specify the version to begin at. Accepted formats are:
-  4   - No Padding
-  004 - Padded zeros`)
+  4           - No Padding
+  004         - Padded zeros
+  release-2.3 - Label defined in the deltas directory labels.txt`)
+	upCmd.PersistentFlags().BoolVar(&upRequest.allowOutOfOrder, "allow-out-of-order", false, `Apply pending deltas even if their tag is lower than the highest applied delta.
+Each delta applied this way is recorded as out of order in the schemer_history table.`)
+	upCmd.PersistentFlags().BoolVar(&upRequest.exitNothingToDo, "exit-code-nothing-to-do", false, `Exit with code 6 instead of 0 when every requested delta was already applied
+and no repeatable delta changed.`)
 	upCmd.PersistentFlags().StringArrayVarP(&upRequest.cherryPickedVersions, "cherry-pick", "c", nil, `Specify deltas to execute againg the database.
 It is possible to cherry pick non-consecutive deltas. This is not reccomended and do so at your own risk.
 Accepted formats are:
-  4   - No Padding
-  004 - Padded zeros
+  4           - No Padding
+  004         - Padded zeros
+  release-2.3 - Label defined in the deltas directory labels.txt
 		`)
+	addSnapshotFlags(upCmd, &upRequest)
 }
 
 // loadUpDeltas loads all eligible up deltas from the delta directory.
@@ -111,20 +116,19 @@ Accepted formats are:
 //   - request: pointer to DeltaRequest specifying tag filters (from, to, or cherries)
 //
 // Returns:
-//   - map[int]UpDelta: a map of tag numbers to corresponding UpDelta structs
+//   - map[int64]UpDelta: a map of tag numbers to corresponding UpDelta structs
 //   - error: non-nil if delta path can't be resolved, files can't be read, or tag parsing fails
-func loadUpDeltas(request *DeltaRequest) (map[int]UpDelta, error) {
+func loadUpDeltas(request *DeltaRequest) (map[int64]UpDelta, error) {
 	deltaPath, err := utils.GetDeltaPath()
 	if err != nil {
 		return nil, err
 	}
-	expression := regexp.MustCompile(`^(\d+)_.*\.up\.sql$`)
-	result := make(map[int]UpDelta)
+	result := make(map[int64]UpDelta)
 
 	err = filepath.WalkDir(deltaPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return &errschemer.SchemerErr{
-				Code:    "load-up-001",
+				Code:    "0210",
 				Message: "failed to access path: " + path,
 				Err:     err,
 			}
@@ -133,19 +137,18 @@ func loadUpDeltas(request *DeltaRequest) (map[int]UpDelta, error) {
 			return nil
 		}
 
-		matches := expression.FindStringSubmatch(d.Name())
-		if matches == nil || len(matches) < 2 {
-			return nil
-		}
-
-		tag, err := strconv.Atoi(matches[1])
+		parsed, err := utils.ParseDeltaFilename(d.Name())
 		if err != nil {
 			return &errschemer.SchemerErr{
-				Code:    "load-up-002",
+				Code:    "0211",
 				Message: "malformed delta tag in file: " + d.Name(),
 				Err:     err,
 			}
 		}
+		if parsed == nil || parsed.Direction != utils.DirectionUp {
+			return nil
+		}
+		tag := parsed.Tag
 
 		if request.Cherries != nil {
 			if !(*request.Cherries)[tag] {
@@ -163,33 +166,28 @@ func loadUpDeltas(request *DeltaRequest) (map[int]UpDelta, error) {
 		contents, err := os.ReadFile(path)
 		if err != nil {
 			return &errschemer.SchemerErr{
-				Code:    "load-up-003",
+				Code:    "0212",
 				Message: "failed to read file at: " + path,
 				Err:     err,
 			}
 		}
 
 		var status PostStatusEnum = NoExist
-		var TagWithName string
-		before, after, found := strings.Cut(d.Name(), ".")
-		if found && after == "up.sql" {
-			TagWithName = before
-			dirtory := filepath.Dir(path)
-			if _,
-				err := os.Stat(filepath.Join(dirtory, strings.Join([]string{TagWithName, "post", "sql"}, "."))); err == nil {
-				status = Pending
-			}
+		if _, err := os.Stat(filepath.Join(filepath.Dir(path), parsed.Base+".post.sql")); err == nil {
+			status = Pending
 		}
 		delta := UpDelta{
-			Tag:        tag,
-			Data:       contents,
-			PostStatus: status,
+			Tag:          tag,
+			File:         relativeDeltaPath(deltaPath, path),
+			Data:         contents,
+			PostStatus:   status,
+			Environments: utils.ParseEnvironments(contents),
 		}
 
 		if _, exists := result[tag]; exists {
 			return &errschemer.SchemerErr{
-				Code:    "load-up-004",
-				Message: "duplicate delta tag found: " + strconv.Itoa(tag) + " in file: " + d.Name(),
+				Code:    "0213",
+				Message: "duplicate delta tag found: " + utils.ToPrefix(tag) + " in file: " + d.Name(),
 			}
 		}
 
@@ -213,6 +211,10 @@ func loadUpDeltas(request *DeltaRequest) (map[int]UpDelta, error) {
 // Returns:
 //   - error: non-nil if any step in the up migration process fails
 func executeUpCommand(connection *pgx.Conn, ctx context.Context) error {
+	if err := utils.CheckNotDirty(connection, ctx); err != nil {
+		return err
+	}
+
 	applied, err := GetAppliedDeltas(connection, ctx)
 	if err != nil {
 		return err
@@ -230,12 +232,89 @@ func executeUpCommand(connection *pgx.Conn, ctx context.Context) error {
 		return err
 	}
 
-	return applyUpDeltas(applied, statements, connection, ctx)
+	// Repeatable deltas may depend on anything the versioned deltas create, so they only
+	// run once every pending delta has been applied.
+	var repeatables []repeatableState
+	partial := deltas.To != nil || deltas.Cherries != nil
+	if !partial {
+		if repeatables, err = loadRepeatableStates(connection, ctx); err != nil {
+			return err
+		}
+	}
+
+	if upRequest.dryRun {
+		return reportUpDryRun(applied, statements, repeatables)
+	}
+
+	pending := 0
+	for tag := range statements {
+		if !applied[tag] {
+			pending++
+		}
+	}
+	if pending == 0 && pendingRepeatables(repeatables) == 0 {
+		if upRequest.exitNothingToDo {
+			return &errschemer.SchemerErr{
+				Code:    "0052",
+				Kind:    errschemer.KindNothingToDo,
+				Message: "all requested deltas have been already applied.",
+			}
+		}
+		glog.Info("Nothing to apply, the database is up to date")
+		return nil
+	}
+	if pending > 0 {
+		if err := applyUpDeltas(applied, statements, connection, ctx); err != nil {
+			return err
+		}
+	}
+
+	if partial {
+		glog.Info("Repeatable deltas are only applied when --to and --cherry-pick are not used")
+		return nil
+	}
+	return applyRepeatables(repeatables, connection, ctx)
+}
+
+// reportUpDryRun logs the rendered up deltas and repeatable deltas that would be applied.
+//
+// Params:
+//   - applied: map of already applied delta tags
+//   - deltas: requested up deltas
+//   - repeatables: repeatable deltas with their status
+//
+// Returns:
+//   - error: non-nil if a delta cannot be rendered
+func reportUpDryRun(applied map[int64]bool, deltas map[int64]UpDelta, repeatables []repeatableState) error {
+	var tags []int64
+	for tag := range deltas {
+		if !applied[tag] {
+			tags = append(tags, tag)
+		}
+	}
+	slices.Sort(tags)
+
+	for _, tag := range tags {
+		if err := logDryRun(utils.DirectionUp, &tag, deltas[tag].File, deltas[tag].Data, ""); err != nil {
+			return err
+		}
+	}
+	for _, state := range repeatables {
+		if state.Status != RepeatableUpToDate {
+			if err := logDryRun(utils.DirectionRepeatable, nil, state.Delta.File, state.Delta.Data, string(state.Status)); err != nil {
+				return err
+			}
+		}
+	}
+	if len(tags) == 0 && pendingRepeatables(repeatables) == 0 {
+		glog.Info("Nothing to apply, the database is up to date")
+	}
+	return nil
 }
 
 // applyUpDeltas applies unapplied up deltas to the database.
 // Executes each delta in order, skipping any already recorded in the schemer table.
-// After successful execution, records each applied delta and its post status.
+// Each delta is recorded with its post status as soon as it succeeds.
 //
 // Params:
 //   - appliedDeltas: map of already applied delta tags
@@ -245,28 +324,42 @@ func executeUpCommand(connection *pgx.Conn, ctx context.Context) error {
 //
 // Returns:
 //   - error: non-nil if any delta fails to apply or if the schemer table update fails
-func applyUpDeltas(appliedDeltas map[int]bool, deltas map[int]UpDelta, connection *pgx.Conn, ctx context.Context) error {
+func applyUpDeltas(appliedDeltas map[int64]bool, deltas map[int64]UpDelta, connection *pgx.Conn, ctx context.Context) error {
 	if upRequest.PruneNoOp {
 		PruneNoOpUp(&deltas)
 	}
 
-	var tagsToApply []int
+	var tagsToApply []int64
 	for tag := range deltas {
 		_, ok := appliedDeltas[tag]
 		if ok {
 			glog.Warn("Skipping delta %s: has already been applied", utils.ToPrefix(tag))
+			reportDelta(utils.DirectionUp, &tag, deltas[tag].File, output.StatusSkipped, "already applied")
 			continue
 		}
 		tagsToApply = append(tagsToApply, tag)
 	}
 
 	if len(tagsToApply) == 0 {
+		glog.Info("All requested deltas have already been applied")
+		return nil
+	}
+
+	slices.Sort(tagsToApply)
+
+	highestApplied, outOfOrder := findOutOfOrder(appliedDeltas, tagsToApply)
+	if len(outOfOrder) > 0 && !upRequest.allowOutOfOrder {
 		return &errschemer.SchemerErr{
-			Code:    "0052",
-			Message: "all requested deltas have been already applied.",
-			Err:     nil,
+			Code: "0089",
+			Message: fmt.Sprintf(`pending deltas are lower than the highest applied delta %s: %s
+This usually happens when branches are merged after a later delta was applied.
+Use --allow-out-of-order to apply them anyway.`, utils.ToPrefix(highestApplied), joinTags(outOfOrder)),
 		}
 	}
+	if len(outOfOrder) > 0 {
+		glog.Warn("Applying deltas out of order, highest applied delta is %s: %s",
+			utils.ToPrefix(highestApplied), joinTags(outOfOrder))
+	}
 
 	/*
 	* It is possible that the table was not created during initialization.
@@ -277,48 +370,57 @@ func applyUpDeltas(appliedDeltas map[int]bool, deltas map[int]UpDelta, connectio
 		return err
 	}
 
-	sort.Ints(tagsToApply)
-
-	var args []any
-	var placeholders []string
-	var execErr error = nil
-
-	for i, tag := range tagsToApply {
-		statement := string(deltas[tag].Data)
+	for _, tag := range tagsToApply {
+		var note string
+		if slices.Contains(outOfOrder, tag) {
+			note = "applied out of order, highest applied delta was " + utils.ToPrefix(highestApplied)
+		}
 
-		_, err := connection.Exec(ctx, statement)
+		err := executeDelta(connection, ctx, deltaExecution{
+			Direction:    utils.DirectionUp,
+			Tag:          tag,
+			File:         deltas[tag].File,
+			Data:         deltas[tag].Data,
+			Environments: deltas[tag].Environments,
+			Note:         note,
+			Record:       recordUpDelta(tag, deltas[tag].PostStatus),
+		})
 		if err != nil {
-			execErr = &errschemer.SchemerErr{
+			return &errschemer.SchemerErr{
 				Code:    "0053",
+				Kind:    errschemer.KindExecution,
 				Message: "failed to apply delta: " + utils.ToPrefix(tag),
 				Err:     err,
 			}
-			break
 		}
 
-		glog.Info("Applied delta %s successfully", utils.ToPrefix(tag))
-		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d)", i*2+1, i*2+2))
-		args = append(args, tag, deltas[tag].PostStatus)
-	}
-
-	if len(placeholders) == 0 {
-		return execErr
+		if runsInEnvironment(deltas[tag].Environments) {
+			glog.Info("Applied delta %s successfully", utils.ToPrefix(tag))
+		}
 	}
 
-	schemerStatement := fmt.Sprintf("INSERT INTO schemer (tag, post_status) VALUES %s", strings.Join(placeholders, ", "))
+	return nil
+}
 
-	_, err := connection.Exec(ctx, schemerStatement, args...)
-	if err != nil {
-		tableErr := &errschemer.SchemerErr{
-			Code:    "0054",
-			Message: "failed to update schemer table.",
-			Err:     err,
-		}
-		if execErr != nil {
-			return fmt.Errorf("table error:  %v\nexecuteion error: %w", err, execErr)
+// recordUpDelta returns the deltaExecution.Record of an up delta, which adds its tag to the
+// schemer table.
+//
+// Params:
+//   - tag: tag of the delta
+//   - postStatus: post status recorded with the tag
+//
+// Returns:
+//   - func(*pgx.Conn, context.Context) error: inserts the tracking row
+func recordUpDelta(tag int64, postStatus PostStatusEnum) func(*pgx.Conn, context.Context) error {
+	return func(connection *pgx.Conn, ctx context.Context) error {
+		statement := fmt.Sprintf("INSERT INTO %s (tag, post_status) VALUES ($1, $2)", utils.TrackingTable)
+		if _, err := connection.Exec(ctx, statement, tag, postStatus); err != nil {
+			return &errschemer.SchemerErr{
+				Code:    "0054",
+				Message: "failed to update schemer table for delta: " + utils.ToPrefix(tag),
+				Err:     err,
+			}
 		}
-		return tableErr
+		return nil
 	}
-
-	return execErr
 }
@@ -2,10 +2,15 @@ package apply
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/jackc/pgx/v5"
+
+	"github.com/inskribe/schemer/cmd"
+	er "github.com/inskribe/schemer/internal/errschemer"
 	"github.com/inskribe/schemer/internal/templates"
 	"github.com/inskribe/schemer/internal/utils"
 	tu "github.com/inskribe/schemer/internal/utils/testutils"
@@ -34,7 +39,7 @@ func TestLoadUpDeltas(t *testing.T) {
 				if delta, ok := deltas[1]; !ok || delta.PostStatus != Pending {
 					t.Fatalf("expected 001 to be Pending, got: %+v", delta)
 				}
-				for _, tag := range []int{2, 3} {
+				for _, tag := range []int64{2, 3} {
 					if _, ok := deltas[tag]; !ok {
 						t.Fatalf("expected delta tag %03d", tag)
 					}
@@ -44,7 +49,7 @@ func TestLoadUpDeltas(t *testing.T) {
 		{
 			name: "Cherry_Pick",
 			request: &DeltaRequest{
-				Cherries: &map[int]bool{1: true, 3: true},
+				Cherries: &map[int64]bool{1: true, 3: true},
 			},
 			verify: func(args *DeltaRequest) {
 				deltas, err := loadUpDeltas(args)
@@ -64,7 +69,7 @@ func TestLoadUpDeltas(t *testing.T) {
 		},
 		{
 			name:    "From_002",
-			request: &DeltaRequest{From: tu.Ptr(2)},
+			request: &DeltaRequest{From: tu.Ptr[int64](2)},
 			verify: func(args *DeltaRequest) {
 				deltas, err := loadUpDeltas(args)
 				if err != nil {
@@ -83,7 +88,7 @@ func TestLoadUpDeltas(t *testing.T) {
 		},
 		{
 			name:    "To_001",
-			request: &DeltaRequest{To: tu.Ptr(1)},
+			request: &DeltaRequest{To: tu.Ptr[int64](1)},
 			verify: func(args *DeltaRequest) {
 				deltas, err := loadUpDeltas(args)
 				if err != nil {
@@ -118,11 +123,11 @@ func TestApplyUpDeltas(t *testing.T) {
 	}
 	upRequest = CommandArgs{PruneNoOp: false}
 
-	deltas := map[int]UpDelta{
+	deltas := map[int64]UpDelta{
 		0: {Tag: 0, Data: []byte(""), PostStatus: Pending},
 		1: {Tag: 1, Data: []byte(""), PostStatus: NoExist},
 	}
-	applied := map[int]bool{}
+	applied := map[int64]bool{}
 
 	schemerArgs := templates.SchemerTemplateArgs{
 		TableName: "schemer",
@@ -143,7 +148,7 @@ func TestApplyUpDeltas(t *testing.T) {
 	}
 
 	for rows.Next() {
-		var tag int
+		var tag int64
 		var status PostStatusEnum
 
 		if err := rows.Scan(&tag, &status); err != nil {
@@ -200,13 +205,13 @@ func TestExecuteUpCommand(t *testing.T) {
 		t.Fatalf("failed to verify deltas applied: %v", err)
 	}
 
-	deltas := map[int]PostStatusEnum{
+	deltas := map[int64]PostStatusEnum{
 		1: Pending,
 		2: NoExist,
 	}
 
 	for rows.Next() {
-		var tag int
+		var tag int64
 		var status PostStatusEnum
 
 		rows.Scan(&tag, &status)
@@ -301,7 +306,7 @@ func TestLoadUpDeltas_Recursive_FromTo(t *testing.T) {
 		return tempDir, nil
 	}
 
-	deltas, err := loadUpDeltas(&DeltaRequest{From: tu.Ptr(2), To: tu.Ptr(2)})
+	deltas, err := loadUpDeltas(&DeltaRequest{From: tu.Ptr[int64](2), To: tu.Ptr[int64](2)})
 	if err != nil {
 		t.Fatalf("loadUpDeltas failed: %v", err)
 	}
@@ -320,3 +325,249 @@ func TestLoadUpDeltas_Recursive_FromTo(t *testing.T) {
 		t.Fatalf("did not expect delta 003 to be loaded")
 	}
 }
+
+func TestApplyUpDeltas_OutOfOrder(t *testing.T) {
+	tempDir := t.TempDir()
+	utils.GetDeltaPath = func() (string, error) {
+		return tempDir, nil
+	}
+
+	schemerArgs := templates.SchemerTemplateArgs{
+		TableName: "schemer",
+	}
+	if err := schemerArgs.WriteTemplate(tempDir); err != nil {
+		t.Fatalf("failed to write table template: %v", err)
+	}
+
+	testCases := []struct {
+		name            string
+		allowOutOfOrder bool
+		expected        any
+	}{
+		{
+			name:            "Rejected_By_Default",
+			allowOutOfOrder: false,
+			expected:        "0089",
+		},
+		{
+			name:            "Allowed_With_Flag",
+			allowOutOfOrder: true,
+			expected:        nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tu.SetupTestTable(t)
+			upRequest = CommandArgs{allowOutOfOrder: tc.allowOutOfOrder}
+			defer func() { upRequest = CommandArgs{} }()
+
+			if _, err := tu.SharedConnection.Exec(context.Background(), `INSERT INTO schemer (tag) VALUES (13),(15)`); err != nil {
+				t.Fatalf("failed to insert applied deltas: %v", err)
+			}
+
+			applied := map[int64]bool{13: true, 15: true}
+			deltas := map[int64]UpDelta{
+				14: {Tag: 14, Data: []byte("SELECT 1"), PostStatus: NoExist},
+			}
+
+			err := applyUpDeltas(applied, deltas, tu.SharedConnection, context.Background())
+			if tc.expected == nil {
+				if err != nil {
+					t.Fatalf("failed to apply deltas: %v", err)
+				}
+				return
+			}
+
+			var actual *er.SchemerErr
+			if !errors.As(err, &actual) || actual.Code != tc.expected {
+				t.Fatalf("expected %v recieved %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestApplyUpDeltas_RecordsEachDelta(t *testing.T) {
+	tempDir := t.TempDir()
+	utils.GetDeltaPath = func() (string, error) {
+		return tempDir, nil
+	}
+
+	schemerArgs := templates.SchemerTemplateArgs{
+		TableName: "schemer",
+	}
+	if err := schemerArgs.WriteTemplate(tempDir); err != nil {
+		t.Fatalf("failed to write table template: %v", err)
+	}
+	tu.SetupTestTable(t)
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, _ = tu.SharedConnection.Exec(ctx, `DELETE FROM schemer_dirty WHERE tag IN (31, 32)`)
+	})
+
+	deltas := map[int64]UpDelta{
+		31: {Tag: 31, File: "031_ok.up.sql", Data: []byte("SELECT 1"), PostStatus: NoExist},
+		32: {Tag: 32, File: "032_broken.up.sql", Data: []byte("SELECT * FROM missing_table_032"), PostStatus: NoExist},
+	}
+	err := applyUpDeltas(map[int64]bool{}, deltas, tu.SharedConnection, ctx)
+	var actual *er.SchemerErr
+	if !errors.As(err, &actual) || actual.Code != "0053" {
+		t.Fatalf("expected 0053 recieved %v", err)
+	}
+
+	// The delta that succeeded is recorded and clean, only the failed one is dirty.
+	applied, err := GetAppliedDeltas(tu.SharedConnection, ctx)
+	if err != nil {
+		t.Fatalf("failed to read applied deltas: %v", err)
+	}
+	if _, ok := applied[31]; !ok || len(applied) != 1 {
+		t.Fatalf("expected only 031 to be recorded, recieved %v", applied)
+	}
+	dirty, err := utils.GetDirtyDeltas(tu.SharedConnection, ctx)
+	if err != nil {
+		t.Fatalf("failed to read dirty deltas: %v", err)
+	}
+	if len(dirty) != 1 || dirty[0].Tag != 32 {
+		t.Fatalf("expected only 032 to be dirty, recieved %+v", dirty)
+	}
+}
+
+func TestApplyUpDeltas_FailOnWarningRollsBack(t *testing.T) {
+	tempDir := t.TempDir()
+	utils.GetDeltaPath = func() (string, error) {
+		return tempDir, nil
+	}
+
+	schemerArgs := templates.SchemerTemplateArgs{
+		TableName: "schemer",
+	}
+	if err := schemerArgs.WriteTemplate(tempDir); err != nil {
+		t.Fatalf("failed to write table template: %v", err)
+	}
+	tu.SetupTestTable(t)
+	ctx := context.Background()
+	utils.FailOnWarning = true
+	t.Cleanup(func() {
+		utils.FailOnWarning = false
+		_, _ = tu.SharedConnection.Exec(ctx, `DROP TABLE IF EXISTS warned_033; DELETE FROM schemer_dirty WHERE tag = 33`)
+	})
+
+	deltas := map[int64]UpDelta{
+		33: {Tag: 33, File: "033_warned.up.sql", PostStatus: NoExist, Data: []byte(
+			"CREATE TABLE warned_033 (id int);\nDO $$ BEGIN RAISE WARNING 'careful'; END $$;")},
+	}
+	err := applyUpDeltas(map[int64]bool{}, deltas, tu.SharedConnection, ctx)
+	var actual *er.SchemerErr
+	if !errors.As(err, &actual) || actual.Code != "0053" {
+		t.Fatalf("expected 0053 recieved %v", err)
+	}
+
+	// The warning rolled the delta back, so it is neither applied nor dirty.
+	var exists bool
+	if err := tu.SharedConnection.QueryRow(ctx, `SELECT to_regclass('warned_033') IS NOT NULL`).Scan(&exists); err != nil {
+		t.Fatalf("failed to look up table: %v", err)
+	}
+	if exists {
+		t.Fatalf("expected warned_033 to be rolled back")
+	}
+	applied, err := GetAppliedDeltas(tu.SharedConnection, ctx)
+	if err != nil {
+		t.Fatalf("failed to read applied deltas: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Fatalf("expected nothing to be recorded, recieved %v", applied)
+	}
+	dirty, err := utils.GetDirtyDeltas(tu.SharedConnection, ctx)
+	if err != nil {
+		t.Fatalf("failed to read dirty deltas: %v", err)
+	}
+	if len(dirty) != 0 {
+		t.Fatalf("expected no dirty deltas, recieved %+v", dirty)
+	}
+}
+
+func TestExecuteUpCommand_NothingToDo(t *testing.T) {
+	tu.SetupTestTable(t)
+
+	tempDir := tu.CreateTestDeltaFiles(t)
+	utils.GetDeltaPath = func() (string, error) {
+		return tempDir, nil
+	}
+	schemerArgs := templates.SchemerTemplateArgs{
+		TableName: "schemer",
+	}
+	if err := schemerArgs.WriteTemplate(tempDir); err != nil {
+		t.Fatalf("failed to write table template: %v", err)
+	}
+
+	upRequest = CommandArgs{cherryPickedVersions: []string{"002"}}
+	if err := executeUpCommand(tu.SharedConnection, context.Background()); err != nil {
+		t.Fatalf("failed to apply deltas: %v", err)
+	}
+
+	// An up to date database is a success unless the caller asked to tell it apart.
+	if err := executeUpCommand(tu.SharedConnection, context.Background()); err != nil {
+		t.Fatalf("expected no error on an up to date database, recieved %v", err)
+	}
+
+	upRequest.exitNothingToDo = true
+	err := executeUpCommand(tu.SharedConnection, context.Background())
+	var actual *er.SchemerErr
+	if !errors.As(err, &actual) || actual.Code != "0052" || actual.Kind != er.KindNothingToDo {
+		t.Fatalf("expected 0052 recieved %v", err)
+	}
+}
+
+func TestApplyUpDeltas_LockTimeoutLeavesNoMarker(t *testing.T) {
+	tempDir := t.TempDir()
+	utils.GetDeltaPath = func() (string, error) {
+		return tempDir, nil
+	}
+
+	schemerArgs := templates.SchemerTemplateArgs{
+		TableName: "schemer",
+	}
+	if err := schemerArgs.WriteTemplate(tempDir); err != nil {
+		t.Fatalf("failed to write table template: %v", err)
+	}
+	tu.SetupTestTable(t)
+	ctx := context.Background()
+	if _, err := tu.SharedConnection.Exec(ctx, `CREATE TABLE IF NOT EXISTS locked_034 (id int)`); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = tu.SharedConnection.Exec(ctx, `DROP TABLE IF EXISTS locked_034; DELETE FROM schemer_dirty WHERE tag = 34`)
+	})
+
+	holder, err := pgx.ConnectConfig(ctx, tu.SharedConnection.Config())
+	if err != nil {
+		t.Fatalf("failed to open second connection: %v", err)
+	}
+	defer holder.Close(ctx)
+	tx, err := holder.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin: %v", err)
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `LOCK TABLE locked_034 IN ACCESS EXCLUSIVE MODE`); err != nil {
+		t.Fatalf("failed to lock table: %v", err)
+	}
+
+	deltas := map[int64]UpDelta{
+		34: {Tag: 34, File: "034_locked.up.sql", PostStatus: NoExist, Data: []byte(
+			"SET lock_timeout = '100ms';\nALTER TABLE locked_034 ADD COLUMN email text;")},
+	}
+	err = applyUpDeltas(map[int64]bool{}, deltas, tu.SharedConnection, ctx)
+	if !cmd.IsLockError(err) {
+		t.Fatalf("expected a lock timeout recieved %v", err)
+	}
+
+	// The server rolled the delta back, so a retry must not be refused as dirty.
+	dirty, err := utils.GetDirtyDeltas(tu.SharedConnection, ctx)
+	if err != nil {
+		t.Fatalf("failed to read dirty deltas: %v", err)
+	}
+	if len(dirty) != 0 {
+		t.Fatalf("expected no dirty deltas, recieved %+v", dirty)
+	}
+}
@@ -25,22 +25,42 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
-	"regexp"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/inskribe/schemer/cmd"
+	"github.com/inskribe/schemer/cmd/apply"
 	"github.com/inskribe/schemer/internal/errschemer"
 	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/output"
+	"github.com/inskribe/schemer/internal/schema"
 	"github.com/inskribe/schemer/internal/utils"
 )
 
 // Represents user input for create command.
 type CreateCmdRequest struct {
-	Post      bool   // Identifies if the user requested a post file to be created.
-	Directory string // Optional directory to create the delta files in. If empty, defaults to the deltas directory in the current working directory.
+	Post       bool     // Identifies if the user requested a post file to be created.
+	Directory  string   // Optional directory to create the delta files in. If empty, defaults to the deltas directory in the current working directory.
+	FromDiff   string   // Optional database or snapshot to generate the delta SQL from.
+	Scratch    string   // Optional server to build the scratch database on. Defaults to the FromDiff database.
+	Schemas    []string // Schemas compared when generating from a diff.
+	Versioning string   // Tagging scheme for the new delta group, sequential or timestamp.
+}
+
+// deltaContents holds the initial contents of a new delta file group.
+type deltaContents struct {
+	Up   string
+	Down string
+	Post string
+}
+
+// defaultContents are written when the delta SQL is left to the user.
+var defaultContents = deltaContents{
+	Up:   "-- TODO: Add delta SQL here\n",
+	Down: "-- TODO: Add rollback SQL here\n",
+	Post: "-- TODO: Handle delta cleanup here\n",
 }
 
 var (
@@ -65,15 +85,35 @@ Examples:
 
 Delta files follow the format: {version}_{name}.{up,down,post}.sql
 
+With --versioning timestamp, usually set once as "versioning: timestamp" in the project
+config, the version is the UTC creation time instead of the next number. Deltas created
+on parallel branches then no longer collide, and they sort after existing numbered deltas.
+
+  schemer create add_users --versioning timestamp
+    → deltas/20261017143000_add_users.up.sql
+      deltas/20261017143000_add_users.down.sql
+
 This command does not apply the delta or connect to the database.
 It simply prepares the file structure for future use.
+
+With --from-diff the up and down SQL is generated instead. A scratch database is built
+from the deltas directory and compared with the given database or snapshot, and the
+differences in tables, columns, constraints, indexes, sequences and enum types are written as SQL.
+Changes that cannot be generated are left as comments starting with "-- TODO(schemer):".
+The scratch database is created on the --from-diff server unless --scratch is given and
+is dropped afterwards.
+
+  schemer create add_email --from-diff DEV_DATABASE_URL
+  schemer create add_email --from-diff dev.sql --scratch postgres://localhost/postgres
 `,
 		Args: cobra.ExactArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			if err := executeCreateCommand(args); err != nil {
-				glog.Error("%v", err)
-				return
+		RunE: func(command *cobra.Command, args []string) error {
+			if CreateRequest.FromDiff != "" {
+				if _, err := utils.LoadDotEnv(); err != nil {
+					return err
+				}
 			}
+			return executeCreateCommand(args)
 		},
 	}
 )
@@ -82,22 +122,30 @@ func init() {
 	cmd.RootCmd.AddCommand(createCmd)
 	createCmd.Flags().BoolVarP(&CreateRequest.Post, "post", "p", false, `Create a post delta along with the up and down deltas.`)
 	createCmd.Flags().StringVarP(&CreateRequest.Directory, "directory", "d", "", `Optional directory to create the delta files in. Defaults to the deltas directory in the current working directory.`)
+	createCmd.Flags().StringVar(&CreateRequest.FromDiff, "from-diff", "", `Generate the up and down SQL from the differences between the deltas and this database.
+Accepts a connection string, an environment key holding one, or a snapshot file.`)
+	createCmd.Flags().StringVar(&CreateRequest.Scratch, "scratch", "", `Connection string or environment key of the server to build the scratch database on.
+Defaults to the --from-diff database. Required when --from-diff is a snapshot file.`)
+	createCmd.Flags().StringArrayVar(&CreateRequest.Schemas, "schema", schema.DefaultSchemas, "Schema to compare when using --from-diff, may be repeated.")
+	createCmd.Flags().StringVar(&CreateRequest.Versioning, "versioning", string(utils.VersioningSequential), `How the new delta group is tagged:
+  sequential - the highest existing tag plus one, e.g. 015
+  timestamp  - the UTC creation time, e.g. 20261017143000`)
 }
 
 // determineNextTag returns the next available delta tag for a new delta file group.
-// Scans the given deltaPath for existing *.up.sql files and determines the next tag in sequence.
+// Scans the given deltaPath for existing *.up.sql files and tags the new group after the highest one.
 //
 // Params:
 //   - deltaPath: the directory containing delta files
+//   - versioning: sequential or timestamp tags
 //
 // Returns:
-//   - int: the next tag number (0 if directory is empty)
-//   - error: non-nil if the directory can't be read or a tag can't be parsed
-func determineNextTag(deltaPath string) (int, error) {
-	next := 0
-	seen := make(map[int]string)
+//   - int64: the next tag number (1 if directory is empty with sequential versioning)
+//   - error: non-nil if the directory can't be read, a tag can't be parsed or the versioning is unknown
+func determineNextTag(deltaPath string, versioning utils.Versioning) (int64, error) {
+	var highest int64
+	seen := make(map[int64]string)
 
-	expression := regexp.MustCompile(`^(\d+)_.*\.up\.sql$`)
 	err := filepath.WalkDir(deltaPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
@@ -109,31 +157,30 @@ func determineNextTag(deltaPath string) (int, error) {
 
 		if strings.HasSuffix(d.Name(), ".up.sql") {
 			name := d.Name()
-			matches := expression.FindStringSubmatch(name)
-			if matches == nil || len(matches) < 2 {
-				return nil
-			}
-
-			tag, err := strconv.Atoi(matches[1])
+			parsed, err := utils.ParseDeltaFilename(name)
 			if err != nil {
 				return &errschemer.SchemerErr{
-					Code:    "determine-next-tag-002",
-					Message: "malformed delta tag found in filename: " + matches[0],
+					Code:    "0196",
+					Message: "malformed delta tag found in filename: " + name,
 					Err:     err,
 				}
 			}
+			if parsed == nil {
+				return nil
+			}
+			tag := parsed.Tag
 
 			if existing, exists := seen[tag]; exists {
 				return &errschemer.SchemerErr{
-					Code:    "determine-next-tag-004",
-					Message: "duplicate delta tag found: " + strconv.Itoa(tag) + " in files: " + existing + " and " + name,
+					Code:    "0198",
+					Message: "duplicate delta tag found: " + utils.ToPrefix(tag) + " in files: " + existing + " and " + name,
 				}
 			}
 
 			seen[tag] = d.Name()
 
-			if tag > next {
-				next = tag
+			if tag > highest {
+				highest = tag
 			}
 
 			return nil
@@ -143,13 +190,20 @@ func determineNextTag(deltaPath string) (int, error) {
 	})
 	if err != nil {
 		return -1, &errschemer.SchemerErr{
-			Code:    "determine-next-tag-003",
+			Code:    "0197",
 			Message: "failed to determine next tag: " + deltaPath,
 			Err:     err,
 		}
 	}
 
-	return next + 1, nil
+	return utils.NextTag(highest, versioning, time.Now())
+}
+
+// createEvent is the document emitted by the create command with --output json.
+type createEvent struct {
+	output.Header
+	Tag   *int64   `json:"tag,omitempty"` // tag of the created group, unset if nothing was created
+	Files []string `json:"files"`         // paths of the created files
 }
 
 // createDeltaFiles creates a delta file group in the deltas directory.
@@ -165,7 +219,7 @@ func determineNextTag(deltaPath string) (int, error) {
 //	001_remove_user.post.sql
 //
 // If an error is returned, it will be of type PathError.
-func createDeltaFiles(filename string, nextTag int, deltaPath string) error {
+func createDeltaFiles(filename string, nextTag int64, deltaPath string, contents deltaContents) error {
 	ErrAlreadExist := "delta file alread exist for: "
 	name := strings.Join([]string{utils.ToPrefix(nextTag), strings.Trim(filename, "_")}, "_")
 
@@ -211,12 +265,13 @@ func createDeltaFiles(filename string, nextTag int, deltaPath string) error {
 
 	defer downFile.Close()
 
-	_, _ = upFile.WriteString("-- TODO: Add delta SQL here\n")
-	_, _ = downFile.WriteString("-- TODO: Add rollback SQL here\n")
+	_, _ = upFile.WriteString(contents.Up)
+	_, _ = downFile.WriteString(contents.Down)
 
 	// Exit early if user did not request a .post file
 	if !CreateRequest.Post {
 		glog.Info("Created deltas:\n  %s\n  %s\n", upPath, downPath)
+		output.Emit("create", &createEvent{Tag: &nextTag, Files: []string{upPath, downPath}})
 		return nil
 	}
 
@@ -242,9 +297,10 @@ func createDeltaFiles(filename string, nextTag int, deltaPath string) error {
 
 	defer postFile.Close()
 
-	_, _ = postFile.WriteString("-- TODO: Handle delta cleanup here\n")
+	_, _ = postFile.WriteString(contents.Post)
 
 	glog.Info("Created deltas:\n  %s\n  %s\n  %s\n", upPath, downPath, postPath)
+	output.Emit("create", &createEvent{Tag: &nextTag, Files: []string{upPath, downPath, postPath}})
 
 	return nil
 }
@@ -262,21 +318,99 @@ func executeCreateCommand(args []string) error {
 
 	targetPath = filepath.Clean(targetPath)
 
-	nextTag, err := determineNextTag(deltaPath)
+	nextTag, err := determineNextTag(deltaPath, utils.Versioning(CreateRequest.Versioning))
 	if err != nil {
 		return err
 	}
 
 	if err := os.MkdirAll(targetPath, os.ModePerm); err != nil {
 		return &errschemer.SchemerErr{
-			Code:    "execute-create-001",
+			Code:    "0199",
 			Message: "failed to create target directory: " + targetPath,
 			Err:     err,
 		}
 	}
 
-	if err := createDeltaFiles(args[0], nextTag, targetPath); err != nil {
+	contents := defaultContents
+	if CreateRequest.FromDiff != "" {
+		generated, err := generateFromDiff()
+		if err != nil {
+			return err
+		}
+		if generated == nil {
+			glog.Info("No differences found between the deltas and %s, no delta created.", CreateRequest.FromDiff)
+			output.Emit("create", &createEvent{Files: []string{}})
+			return nil
+		}
+		contents = *generated
+	}
+
+	if err := createDeltaFiles(args[0], nextTag, targetPath, contents); err != nil {
 		return err
 	}
 	return nil
 }
+
+// generateFromDiff builds a scratch database from the deltas directory and generates the
+// SQL that turns it into the --from-diff schema.
+//
+// Returns:
+//   - *deltaContents: the generated SQL, nil if there are no differences
+//   - error: non-nil if either schema cannot be read or the scratch database cannot be built
+func generateFromDiff() (*deltaContents, error) {
+	desired, err := apply.ResolveSchemaSource(CreateRequest.FromDiff)
+	if err != nil {
+		return nil, err
+	}
+
+	server := desired.ConnString
+	if CreateRequest.Scratch != "" {
+		scratchSource, err := apply.ResolveSchemaSource(CreateRequest.Scratch)
+		if err != nil {
+			return nil, err
+		}
+		server = scratchSource.ConnString
+	}
+	if server == "" {
+		return nil, &errschemer.SchemerErr{
+			Code:    "0132",
+			Kind:    errschemer.KindUsage,
+			Message: "--scratch must be a connection string or environment key when --from-diff is a snapshot file",
+		}
+	}
+
+	target, err := desired.Load(CreateRequest.Schemas)
+	if err != nil {
+		return nil, err
+	}
+
+	database, err := apply.BuildScratch(server, "")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := database.Drop(); err != nil {
+			glog.Warn("%s", errschemer.FormatChain(err))
+		}
+	}()
+
+	current, err := apply.SchemaSource{ConnString: database.ConnString}.Load(CreateRequest.Schemas)
+	if err != nil {
+		return nil, err
+	}
+
+	up, down := schema.GenerateMigration(current, target)
+	if up == "" && down == "" {
+		return nil, nil
+	}
+
+	header := "-- Generated by schemer create --from-diff. Review before applying.\n\n"
+	if strings.Contains(up+down, schema.UnsupportedMarker) {
+		glog.Warn("Some changes could not be generated, search the new deltas for %q.", strings.TrimSpace(schema.UnsupportedMarker))
+	}
+	return &deltaContents{
+		Up:   header + up,
+		Down: header + down,
+		Post: defaultContents.Post,
+	}, nil
+}
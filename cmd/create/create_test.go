@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/inskribe/schemer/internal/glog"
 	"github.com/inskribe/schemer/internal/utils"
@@ -36,7 +37,7 @@ func TestMain(m *testing.M) {
 
 func TestDetermineNextTag(t *testing.T) {
 	tempDir := tu.CreateTestDeltaFiles(t)
-	nextTag, err := determineNextTag(tempDir)
+	nextTag, err := determineNextTag(tempDir, utils.VersioningSequential)
 	if err != nil {
 		t.Fatalf("failed to get nextTag: %v", err)
 	}
@@ -66,7 +67,7 @@ func TestDetermineNextTag_Recursive(t *testing.T) {
 		}
 	}
 
-	nextTag, err := determineNextTag(tempDir)
+	nextTag, err := determineNextTag(tempDir, utils.VersioningSequential)
 	if err != nil {
 		t.Fatalf("failed to get nextTag: %v", err)
 	}
@@ -76,14 +77,31 @@ func TestDetermineNextTag_Recursive(t *testing.T) {
 	}
 }
 
+func TestDetermineNextTag_Timestamp(t *testing.T) {
+	tempDir := tu.CreateTestDeltaFiles(t)
+	before := time.Now().UTC().Add(-time.Second).Format(utils.TimestampTagLayout)
+
+	nextTag, err := determineNextTag(tempDir, utils.VersioningTimestamp)
+	if err != nil {
+		t.Fatalf("failed to get nextTag: %v", err)
+	}
+	if !utils.IsTimestampTag(nextTag) || utils.ToPrefix(nextTag) < before {
+		t.Fatalf("expected a timestamp tag after %s, recived %s", before, utils.ToPrefix(nextTag))
+	}
+
+	if _, err := determineNextTag(tempDir, "date"); err == nil {
+		t.Fatalf("expected unknown versioning to fail")
+	}
+}
+
 func TestCreateDeltaFile(t *testing.T) {
 	tempDir := t.TempDir()
 	expectedFilename := "test_filename"
-	nextTag := 100
+	nextTag := int64(100)
 
 	CreateRequest.Post = true
 
-	if err := createDeltaFiles(expectedFilename, nextTag, tempDir); err != nil {
+	if err := createDeltaFiles(expectedFilename, nextTag, tempDir, defaultContents); err != nil {
 		t.Fatalf("failed to create delta file: %v", err)
 	}
 
@@ -109,7 +127,7 @@ func TestCreateDeltaFile_SubDir(t *testing.T) {
 	tempDir := t.TempDir()
 	targetDir := filepath.Join(tempDir, "users")
 	expectedFilename := "test_filename"
-	nextTag := 100
+	nextTag := int64(100)
 
 	CreateRequest.Post = true
 
@@ -117,7 +135,7 @@ func TestCreateDeltaFile_SubDir(t *testing.T) {
 		t.Fatalf("failed to create target dir: %v", err)
 	}
 
-	if err := createDeltaFiles(expectedFilename, nextTag, targetDir); err != nil {
+	if err := createDeltaFiles(expectedFilename, nextTag, targetDir, defaultContents); err != nil {
 		t.Fatalf("failed to create delta file: %v", err)
 	}
 
@@ -0,0 +1,94 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/inskribe/schemer/internal/errschemer"
+)
+
+// Exit codes of the schemer process.
+const (
+	ExitOK          = 0 // the command succeeded
+	ExitError       = 1 // any failure not covered below
+	ExitUsage       = 2 // invalid flags, arguments or config values
+	ExitConnection  = 3 // the database could not be reached
+	ExitExecution   = 4 // a delta, repeatable delta or seed failed to apply
+	ExitLock        = 5 // a lock could not be acquired in time, see IsLockError
+	ExitNothingToDo = 6 // every requested delta was already applied, only with up --exit-code-nothing-to-do
+)
+
+// lockStates are the SQLSTATE codes of lock contention:
+// lock_not_available, raised by lock_timeout and NOWAIT, and deadlock_detected.
+var lockStates = map[string]bool{"55P03": true, "40P01": true}
+
+// IsLockError reports whether err is lock contention reported by PostgreSQL. The failed
+// statement was rolled back by the server, so a delta that failed this way is not left dirty
+// unless it controls transactions itself or builds an index concurrently.
+//
+// Params:
+//   - err: the error to inspect
+//
+// Returns:
+//   - bool: true if the chain of err holds a lock_not_available or deadlock_detected error
+func IsLockError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && lockStates[pgErr.Code]
+}
+
+// ExitCode maps the error a command failed with to the process exit code.
+// Lock contention reported by PostgreSQL wins over the Kind of the error, since a delta that
+// timed out waiting for a lock is worth retrying while other execution failures are not.
+//
+// Params:
+//   - err: the error returned by the command, nil on success
+//
+// Returns:
+//   - int: one of the Exit constants
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	if IsLockError(err) {
+		return ExitLock
+	}
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return ExitConnection
+	}
+
+	switch errschemer.KindOf(err) {
+	case errschemer.KindUsage:
+		return ExitUsage
+	case errschemer.KindConnection:
+		return ExitConnection
+	case errschemer.KindExecution:
+		return ExitExecution
+	case errschemer.KindNothingToDo:
+		return ExitNothingToDo
+	}
+	return ExitError
+}
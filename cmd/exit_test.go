@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+)
+
+func TestExitCode(t *testing.T) {
+	execution := func(err error) error {
+		return &er.SchemerErr{Code: "0053", Kind: er.KindExecution, Message: "failed to apply delta: 001", Err: err}
+	}
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "success", expected: ExitOK},
+		{name: "general", err: errors.New("boom"), expected: ExitError},
+		{name: "usage", err: &er.SchemerErr{Code: "0027", Kind: er.KindUsage}, expected: ExitUsage},
+		{name: "connection", err: &er.SchemerErr{Code: "0008", Kind: er.KindConnection, Err: errors.New("refused")}, expected: ExitConnection},
+		{name: "execution", err: execution(&pgconn.PgError{Code: "42P07"}), expected: ExitExecution},
+		{name: "lock timeout", err: execution(fmt.Errorf("exec: %w", &pgconn.PgError{Code: "55P03"})), expected: ExitLock},
+		{name: "deadlock", err: &pgconn.PgError{Code: "40P01"}, expected: ExitLock},
+		{name: "innermost kind", err: execution(&er.SchemerErr{Code: "0175", Kind: er.KindUsage}), expected: ExitUsage},
+		{name: "nothing to do", err: &er.SchemerErr{Code: "0052", Kind: er.KindNothingToDo}, expected: ExitNothingToDo},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ExitCode(tc.err); got != tc.expected {
+				t.Fatalf("expected exit code %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package explain
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inskribe/schemer/cmd"
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/output"
+)
+
+// explainEvent is the document emitted by the explain command with --output json.
+type explainEvent struct {
+	output.Header
+	Entries []errschemer.Entry `json:"entries"`
+}
+
+var explainCmd = &cobra.Command{
+	Use:   "explain [code]",
+	Short: "Describe an error code",
+	Long: `The explain command prints the category, likely cause and remedy of an error code.
+Without a code it lists every code with its summary.
+
+The same catalog is published in SCHEMER_ERRORS.md.
+
+Examples:
+  schemer explain 0021
+  schemer explain 21
+  schemer explain
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(command *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			output.Emit("explain", &explainEvent{Entries: errschemer.Catalog})
+			if !output.JSON() {
+				return listEntries(os.Stdout, errschemer.Catalog)
+			}
+			return nil
+		}
+
+		entry, ok := errschemer.Lookup(args[0])
+		if !ok {
+			return &errschemer.SchemerErr{
+				Code:    "0214",
+				Message: fmt.Sprintf("unknown error code %q, run schemer explain to list every code", args[0]),
+				Kind:    errschemer.KindUsage,
+			}
+		}
+		output.Emit("explain", &explainEvent{Entries: []errschemer.Entry{entry}})
+		if !output.JSON() {
+			return printEntry(os.Stdout, entry)
+		}
+		return nil
+	},
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(explainCmd)
+}
+
+// printEntry writes a single catalog entry.
+//
+// Params:
+//   - w: destination of the text.
+//   - entry: the entry to print.
+//
+// Returns:
+//   - error: if writing to w failed.
+func printEntry(w io.Writer, entry errschemer.Entry) error {
+	_, err := fmt.Fprintf(w, "Error %s: %s\n\nCategory: %s\nCause:    %s\nRemedy:   %s\n",
+		entry.Code, entry.Summary, entry.Category, entry.Cause, entry.Remedy)
+	return err
+}
+
+// listEntries writes one line per catalog entry.
+//
+// Params:
+//   - w: destination of the table.
+//   - entries: the entries to list.
+//
+// Returns:
+//   - error: if writing to w failed.
+func listEntries(w io.Writer, entries []errschemer.Entry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, entry := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", entry.Code, entry.Category, entry.Summary)
+	}
+	return tw.Flush()
+}
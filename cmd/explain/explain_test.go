@@ -0,0 +1,40 @@
+package explain
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/inskribe/schemer/internal/errschemer"
+)
+
+func TestPrintEntry(t *testing.T) {
+	entry, ok := errschemer.Lookup("0021")
+	if !ok {
+		t.Fatal("0021 is not in the catalog")
+	}
+
+	var buf bytes.Buffer
+	if err := printEntry(&buf, entry); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Error 0021: ", "Category: tracking", "Cause:", "Remedy:"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestListEntries(t *testing.T) {
+	var buf bytes.Buffer
+	if err := listEntries(&buf, errschemer.Catalog); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(errschemer.Catalog) {
+		t.Errorf("got %d lines, want %d", len(lines), len(errschemer.Catalog))
+	}
+	if !strings.HasPrefix(lines[0], "0001  usage") {
+		t.Errorf("first line = %q", lines[0])
+	}
+}
@@ -43,7 +43,11 @@ var (
 	initCmd = &cobra.Command{
 		Use:   "init [options]",
 		Short: "Initialize a schemer project in the current directory",
-		Long: `The init command sets up a new schemer project scaffold in the current working directory.
+		// A new project nested in an existing one must not be written into the outer project.
+		Annotations: map[string]string{cmd.NoRootDiscovery: ""},
+		Long: `The init command sets up a new schemer project scaffold in the current working directory,
+or in --project-dir or SCHEMER_PROJECT_DIR when set. Existing projects above the current
+directory are ignored.
 
 It creates a deltas directory (if it doesn't already exist) and generates a .env file
 for storing environment-specific variables like the database connection string.
@@ -53,17 +57,15 @@ Use the --url-key flag to customize the generated environment variable key.
 Note: This command is intended for local development or initial setup and should not be
 run in production environments, as it may overwrite existing configuration files.
 `,
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(command *cobra.Command, args []string) error {
 			glog.Info("Initializing Schemer project\n\n")
 
-			_, err := utils.LoadDotEnv()
-			if err != nil {
-				glog.Error(err.Error())
+			// A broken .env file is not fatal, init is what recreates it.
+			if _, err := utils.LoadDotEnv(); err != nil {
+				glog.Error("%s", errschemer.FormatChain(err))
 			}
 
-			if err := executeInitCommand(); err != nil {
-				glog.Error(err.Error())
-			}
+			return executeInitCommand()
 		},
 	}
 )
@@ -74,20 +76,17 @@ func init() {
 	initCmd.Flags().StringVarP(&DatabaseArgs.UrlKey, "key", "k", "", "An environment variable key to retrieve connection string.")
 }
 
-// executeInitCommand initializes a new schemer project in the current directory.
+// executeInitCommand initializes a new schemer project in the project root, which is the
+// current directory unless --project-dir or SCHEMER_PROJECT_DIR is set.
 // Creates the deltas directory, generates a .env file, writes the schemer.sql template,
 // and optionally creates the schemer tracking table using the resolved database connection.
 //
 // Returns:
 //   - error: non-nil if any step in the initialization process fails
 func executeInitCommand() error {
-	cwd, err := os.Getwd()
+	root, err := utils.GetProjectDir()
 	if err != nil {
-		return &errschemer.SchemerErr{
-			Code:    "0067",
-			Message: "failed to get working directory.",
-			Err:     err,
-		}
+		return err
 	}
 
 	if DatabaseArgs.UrlKey == "" {
@@ -107,17 +106,20 @@ Please update after initialization.`)
 		}
 	}
 
-	deltaPath := filepath.Join(cwd, "deltas")
+	deltaPath, err := utils.GetDeltaPath()
+	if err != nil {
+		return err
+	}
 	if err = createDeltasDirectory(deltaPath); err != nil {
 		return err
 	}
 
-	if err := createEnvFile(cwd); err != nil {
+	if err := createEnvFile(root); err != nil {
 		return err
 	}
 
 	schemerArgs := templates.SchemerTemplateArgs{
-		TableName: "schemer",
+		TableName: utils.TrackingTable,
 	}
 
 	if err := schemerArgs.WriteTemplate(deltaPath); err != nil {
@@ -0,0 +1,328 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package lint
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inskribe/schemer/cmd"
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/sqltoken"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+// suppressDirective disables rules for the whole file when found in a comment.
+const suppressDirective = "schemer:lint-disable"
+
+// Finding is a single rule violation in a delta file.
+type Finding struct {
+	Rule     string   `json:"rule"`     // id of the violated rule
+	Severity Severity `json:"severity"` // warning or error
+	Path     string   `json:"path"`     // file relative to the working directory
+	Line     int      `json:"line"`     // 1-based line of the offending statement
+	Message  string   `json:"message"`  // human readable description of the problem
+}
+
+// Represents user input for lint command.
+type LintOptions struct {
+	Format string   // text, json or sarif
+	Rules  []string // rule severity overrides in the form <rule>=<off|warning|error>
+	Strict bool     // treat warnings as failures
+}
+
+var (
+	LintRequest LintOptions
+	lintOutput  io.Writer = os.Stdout
+
+	lintCmd = &cobra.Command{
+		Use:   "lint [files...] [options]",
+		Short: "Check up and post deltas for SQL that is unsafe to run against a live database",
+		Long: `The lint command reads up and post deltas and flags statements that take long locks,
+rewrite tables or break code that is still running. No database connection is needed.
+
+Without arguments every up and post delta in the deltas directory is checked. Down deltas
+are skipped since they are expected to undo changes.
+
+Rules:
+  create-index-without-concurrently    error    CREATE INDEX on an existing table without CONCURRENTLY
+  alter-column-type                    error    ALTER COLUMN ... TYPE on an existing table
+  add-column-not-null-without-default  error    ADD COLUMN ... NOT NULL with no DEFAULT on an existing table
+  drop-in-up                           warning  DROP COLUMN or DROP TABLE in an up delta instead of a post delta
+  rename                               warning  renaming a table, column or other object
+  lock-table                           warning  explicit LOCK TABLE
+
+Severities can be changed with --rule. To silence rules for a single file, add a comment:
+  -- schemer:lint-disable rename, drop-in-up
+A directive without rule ids silences every rule for that file.
+
+Lint exits with a non-zero status if any error is found, or any warning with --strict.
+
+Examples:
+  schemer lint
+  schemer lint deltas/004_add_users.up.sql
+  schemer lint --rule rename=off --rule drop-in-up=error
+  schemer lint --format sarif > schemer-lint.sarif
+`,
+		RunE: func(command *cobra.Command, args []string) error {
+			rules, err := configureRules(Rules(), LintRequest.Rules)
+			if err != nil {
+				return err
+			}
+
+			files := args
+			if len(files) == 0 {
+				deltaPath, err := utils.GetDeltaPath()
+				if err != nil {
+					return err
+				}
+				if files, err = collectDeltaFiles(deltaPath); err != nil {
+					return err
+				}
+			}
+
+			findings, err := lintFiles(files, rules)
+			if err != nil {
+				return err
+			}
+
+			passed, err := writeReport(lintOutput, LintRequest.Format, rules, findings, LintRequest.Strict)
+			if err != nil {
+				return err
+			}
+			if !passed {
+				return &errschemer.SchemerErr{
+					Code:    "0194",
+					Message: "lint found errors, or warnings with --strict",
+				}
+			}
+			return nil
+		},
+	}
+)
+
+func init() {
+	cmd.RootCmd.AddCommand(lintCmd)
+	lintCmd.Flags().StringVarP(&LintRequest.Format, "format", "f", "text", "Output format: text, json or sarif.")
+	lintCmd.Flags().StringArrayVar(&LintRequest.Rules, "rule", nil, `Override the severity of a rule, may be repeated.
+Accepted formats are:
+  <rule>=off
+  <rule>=warning
+  <rule>=error`)
+	lintCmd.Flags().BoolVar(&LintRequest.Strict, "strict", false, "Exit with a non-zero status on warnings as well as errors.")
+}
+
+// configureRules applies severity overrides to rules.
+//
+// Params:
+//   - rules: rules with their default severities
+//   - overrides: values of --rule in the form <rule>=<off|warning|error>
+//
+// Returns:
+//   - []Rule: the rules with overrides applied
+//   - error: non-nil if an override is malformed or names an unknown rule
+func configureRules(rules []Rule, overrides []string) ([]Rule, error) {
+	configured := append([]Rule{}, rules...)
+	for _, override := range overrides {
+		id, level, found := strings.Cut(override, "=")
+		severity := Severity(strings.ToLower(strings.TrimSpace(level)))
+		if !found || (severity != SeverityOff && severity != SeverityWarning && severity != SeverityError) {
+			return nil, &errschemer.SchemerErr{
+				Code:    "0094",
+				Message: "invalid --rule " + override + ", expected <rule>=<off|warning|error>",
+			}
+		}
+
+		matched := false
+		for i := range configured {
+			if configured[i].ID == strings.TrimSpace(id) {
+				configured[i].Severity = severity
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, &errschemer.SchemerErr{
+				Code:    "0095",
+				Message: "unknown lint rule in --rule: " + id,
+			}
+		}
+	}
+	return configured, nil
+}
+
+// collectDeltaFiles returns every up and post delta under deltaPath in a stable order.
+//
+// Params:
+//   - deltaPath: the deltas directory to walk
+//
+// Returns:
+//   - []string: paths of the up and post delta files
+//   - error: non-nil if the directory cannot be walked
+func collectDeltaFiles(deltaPath string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(deltaPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return &errschemer.SchemerErr{
+				Code:    "0092",
+				Message: "failed to access path: " + path,
+				Err:     err,
+			}
+		}
+		if d.IsDir() {
+			return nil
+		}
+		parsed, err := utils.ParseDeltaFilename(d.Name())
+		if err != nil || parsed == nil || parsed.Direction == utils.DirectionDown {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// lintFiles runs every enabled rule against each file.
+//
+// Params:
+//   - files: paths of the delta files to lint
+//   - rules: rules with their configured severities
+//
+// Returns:
+//   - []Finding: violations found, sorted by path and line
+//   - error: non-nil if a file cannot be read
+func lintFiles(files []string, rules []Rule) ([]Finding, error) {
+	var findings []Finding
+	for _, path := range files {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return nil, &errschemer.SchemerErr{
+				Code:    "0093",
+				Message: "failed to read file at: " + path,
+				Err:     err,
+			}
+		}
+
+		direction := utils.DirectionUp
+		if parsed, err := utils.ParseDeltaFilename(filepath.Base(path)); err == nil && parsed != nil {
+			direction = parsed.Direction
+		}
+		findings = append(findings, lintSql(displayPath(path), direction, string(contents), rules)...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Path != findings[j].Path {
+			return findings[i].Path < findings[j].Path
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings, nil
+}
+
+// lintSql runs every enabled rule against the statements in sql.
+//
+// Params:
+//   - path: file name used in findings
+//   - direction: up or post, some rules only apply to up deltas
+//   - sql: contents of the delta file
+//   - rules: rules with their configured severities
+//
+// Returns:
+//   - []Finding: violations found in statement order
+func lintSql(path string, direction utils.Direction, sql string, rules []Rule) []Finding {
+	tokens := sqltoken.Tokenize(sql)
+	suppressed, suppressAll := parseSuppressions(tokens)
+	if suppressAll {
+		return nil
+	}
+
+	file := &lintFile{direction: direction, created: make(map[string]bool)}
+	var findings []Finding
+	for _, tokens := range sqltoken.Statements(tokens) {
+		stmt := newStatement(tokens)
+		for _, rule := range rules {
+			if rule.Severity == SeverityOff || suppressed[rule.ID] {
+				continue
+			}
+			for _, match := range rule.check(file, stmt) {
+				findings = append(findings, Finding{
+					Rule:     rule.ID,
+					Severity: rule.Severity,
+					Path:     path,
+					Line:     match.line,
+					Message:  match.message,
+				})
+			}
+		}
+		if table := createdTable(tokens); table != "" {
+			file.created[table] = true
+		}
+	}
+	return findings
+}
+
+// parseSuppressions collects the rules disabled by schemer:lint-disable comments.
+//
+// Returns:
+//   - map[string]bool: ids of suppressed rules
+//   - bool: true if a directive without rule ids disables every rule
+func parseSuppressions(tokens []sqltoken.Token) (map[string]bool, bool) {
+	suppressed := make(map[string]bool)
+	for _, comment := range sqltoken.Comments(tokens) {
+		rest, found := strings.CutPrefix(comment.Text, suppressDirective)
+		if !found {
+			continue
+		}
+		ids := strings.FieldsFunc(rest, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(ids) == 0 {
+			return nil, true
+		}
+		for _, id := range ids {
+			suppressed[id] = true
+		}
+	}
+	return suppressed, false
+}
+
+// displayPath returns path relative to the working directory when possible, using
+// forward slashes so reports are stable across platforms.
+func displayPath(path string) string {
+	if workingDir, err := os.Getwd(); err == nil {
+		if absolute, err := filepath.Abs(path); err == nil {
+			if relative, err := filepath.Rel(workingDir, absolute); err == nil && !strings.HasPrefix(relative, "..") {
+				path = relative
+			}
+		}
+	}
+	return filepath.ToSlash(path)
+}
@@ -0,0 +1,214 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+func TestMain(m *testing.M) {
+	glog.InitializeLogger(true)
+	os.Exit(m.Run())
+}
+
+func ruleIds(findings []Finding) []string {
+	var ids []string
+	for _, finding := range findings {
+		ids = append(ids, finding.Rule)
+	}
+	return ids
+}
+
+func TestLintSql(t *testing.T) {
+	mockData := []struct {
+		name      string
+		direction utils.Direction
+		input     string
+		expected  []string
+	}{
+		{
+			name:      "index without concurrently",
+			direction: utils.DirectionUp,
+			input:     "CREATE UNIQUE INDEX idx_users_email ON users (email);",
+			expected:  []string{"create-index-without-concurrently"},
+		},
+		{
+			name:      "index with concurrently",
+			direction: utils.DirectionUp,
+			input:     "CREATE INDEX CONCURRENTLY idx_users_email ON users (email);",
+		},
+		{
+			name:      "index on table created in the same file",
+			direction: utils.DirectionUp,
+			input: `CREATE TABLE IF NOT EXISTS "Users" (id INT NOT NULL);
+CREATE INDEX idx_users_id ON "Users" (id);
+ALTER TABLE "Users" ADD COLUMN name TEXT NOT NULL, ALTER COLUMN id TYPE BIGINT;`,
+		},
+		{
+			name:      "alter column type",
+			direction: utils.DirectionUp,
+			input:     "ALTER TABLE public.users ALTER COLUMN id SET DATA TYPE BIGINT, ALTER name DROP NOT NULL;",
+			expected:  []string{"alter-column-type"},
+		},
+		{
+			name:      "add not null column",
+			direction: utils.DirectionUp,
+			input: `ALTER TABLE users
+    ADD COLUMN a TEXT NOT NULL,
+    ADD COLUMN b TEXT NOT NULL DEFAULT 'x',
+    ADD COLUMN c NUMERIC(10, 2),
+    ADD CONSTRAINT c_not_null CHECK (c IS NOT NULL);`,
+			expected: []string{"add-column-not-null-without-default"},
+		},
+		{
+			name:      "drops in up delta",
+			direction: utils.DirectionUp,
+			input:     "ALTER TABLE users DROP COLUMN a, DROP CONSTRAINT b; DROP TABLE IF EXISTS old_users;",
+			expected:  []string{"drop-in-up", "drop-in-up"},
+		},
+		{
+			name:      "drops in post delta",
+			direction: utils.DirectionPost,
+			input:     "ALTER TABLE users DROP COLUMN a; DROP TABLE old_users;",
+		},
+		{
+			name:      "rename and lock",
+			direction: utils.DirectionUp,
+			input:     "ALTER TABLE users RENAME COLUMN a TO b; ALTER INDEX i RENAME TO j; LOCK TABLE users IN ACCESS EXCLUSIVE MODE;",
+			expected:  []string{"rename", "rename", "lock-table"},
+		},
+		{
+			name:      "keywords inside strings, comments and function bodies",
+			direction: utils.DirectionUp,
+			input: `-- CREATE INDEX i ON users (a);
+INSERT INTO notes VALUES ('DROP TABLE users; LOCK TABLE users');
+CREATE FUNCTION f() RETURNS void AS $$ BEGIN EXECUTE 'ALTER TABLE a RENAME TO b'; END $$ LANGUAGE plpgsql;`,
+		},
+		{
+			name:      "suppressed rules",
+			direction: utils.DirectionUp,
+			input: `-- schemer:lint-disable rename, drop-in-up
+ALTER TABLE users RENAME TO members;
+DROP TABLE old_users;
+LOCK TABLE members;`,
+			expected: []string{"lock-table"},
+		},
+		{
+			name:      "suppress every rule",
+			direction: utils.DirectionUp,
+			input: `/* schemer:lint-disable */
+CREATE INDEX i ON users (a);
+LOCK TABLE users;`,
+		},
+	}
+
+	for _, mock := range mockData {
+		t.Run(mock.name, func(t *testing.T) {
+			findings := lintSql("delta.sql", mock.direction, mock.input, Rules())
+			result := ruleIds(findings)
+			if len(result) != len(mock.expected) {
+				t.Fatalf("lintSql() = %v, expected %v", findings, mock.expected)
+			}
+			for i := range result {
+				if result[i] != mock.expected[i] {
+					t.Fatalf("lintSql() = %v, expected %v", result, mock.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestLintSql_Lines(t *testing.T) {
+	findings := lintSql("delta.sql", utils.DirectionUp, "SELECT 1;\n\nALTER TABLE users\n  ADD COLUMN a INT,\n  DROP COLUMN b;", Rules())
+	if len(findings) != 1 || findings[0].Line != 5 {
+		t.Fatalf("expected drop-in-up on line 5, got %+v", findings)
+	}
+}
+
+func TestConfigureRules(t *testing.T) {
+	rules, err := configureRules(Rules(), []string{"rename=off", "lock-table=ERROR"})
+	if err != nil {
+		t.Fatalf("configureRules failed: %v", err)
+	}
+	findings := lintSql("delta.sql", utils.DirectionUp, "ALTER TABLE a RENAME TO b; LOCK TABLE b;", rules)
+	if len(findings) != 1 || findings[0].Rule != "lock-table" || findings[0].Severity != SeverityError {
+		t.Fatalf("unexpected findings after override: %+v", findings)
+	}
+
+	for _, invalid := range []string{"rename", "rename=loud", "no-such-rule=off"} {
+		if _, err := configureRules(Rules(), []string{invalid}); err == nil {
+			t.Errorf("expected error for --rule %s", invalid)
+		}
+	}
+}
+
+func TestCollectDeltaFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"000_init.up.sql", "000_init.down.sql", "users/001_add.up.sql", "users/001_add.post.sql", "notes.sql"} {
+		full := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(full), os.ModePerm); err != nil {
+			t.Fatalf("failed to create dir for %s: %v", name, err)
+		}
+		if err := os.WriteFile(full, []byte("SELECT 1;"), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	files, err := collectDeltaFiles(tempDir)
+	if err != nil {
+		t.Fatalf("collectDeltaFiles failed: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("expected 3 up and post files, got %v", files)
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	findings := []Finding{
+		{Rule: "rename", Severity: SeverityWarning, Path: "deltas/001_a.up.sql", Line: 2, Message: "rename"},
+	}
+
+	var text bytes.Buffer
+	passed, err := writeReport(&text, "text", Rules(), findings, false)
+	if err != nil || !passed {
+		t.Fatalf("expected warnings to pass without --strict, passed=%v err=%v", passed, err)
+	}
+	if text.String() != "deltas/001_a.up.sql:2: warning [rename] rename\n" {
+		t.Fatalf("unexpected text report: %q", text.String())
+	}
+
+	if passed, _ := writeReport(&bytes.Buffer{}, "text", Rules(), findings, true); passed {
+		t.Fatalf("expected warnings to fail with --strict")
+	}
+
+	var sarif bytes.Buffer
+	if _, err := writeReport(&sarif, "sarif", Rules(), findings, false); err != nil {
+		t.Fatalf("sarif report failed: %v", err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(sarif.Bytes(), &log); err != nil {
+		t.Fatalf("sarif report is not valid json: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 1 ||
+		log.Runs[0].Results[0].Locations[0].PhysicalLocation.Region.StartLine != 2 {
+		t.Fatalf("unexpected sarif report: %s", sarif.String())
+	}
+
+	var report bytes.Buffer
+	if _, err := writeReport(&report, "json", Rules(), nil, false); err != nil {
+		t.Fatalf("json report failed: %v", err)
+	}
+	var decoded jsonReport
+	if err := json.Unmarshal(report.Bytes(), &decoded); err != nil || decoded.Findings == nil {
+		t.Fatalf("unexpected json report: %s", report.String())
+	}
+
+	if _, err := writeReport(&bytes.Buffer{}, "xml", Rules(), findings, false); err == nil {
+		t.Fatalf("expected error for unknown format")
+	}
+}
@@ -0,0 +1,232 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/inskribe/schemer/internal/build"
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/output"
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// jsonReport is the document written by --format json.
+type jsonReport struct {
+	Findings []Finding `json:"findings"`
+	Errors   int       `json:"errors"`
+	Warnings int       `json:"warnings"`
+}
+
+// lintEvent is the document emitted by the lint command with --output json, replacing --format.
+type lintEvent struct {
+	output.Header
+	jsonReport
+	Passed bool `json:"passed"` // false if the command exits non-zero
+}
+
+// The sarif types cover the subset of SARIF 2.1.0 needed by code scanning tools.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	DefaultConfig    sarifConfig  `json:"defaultConfiguration"`
+}
+
+type sarifConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           sarifRegion   `json:"region"`
+}
+
+type sarifArtifact struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// writeReport writes findings to out in the requested format.
+//
+// Params:
+//   - out: destination of the report
+//   - format: text, json or sarif
+//   - rules: configured rules, listed in the sarif report
+//   - findings: violations found by lintFiles
+//   - strict: treat warnings as failures
+//
+// Returns:
+//   - bool: true if the deltas passed linting
+//   - error: non-nil if the format is unknown or the report cannot be written
+func writeReport(out io.Writer, format string, rules []Rule, findings []Finding, strict bool) (bool, error) {
+	var errorCount, warningCount int
+	for _, finding := range findings {
+		if finding.Severity == SeverityError {
+			errorCount++
+		} else {
+			warningCount++
+		}
+	}
+	passed := errorCount == 0 && (!strict || warningCount == 0)
+
+	if output.JSON() {
+		event := &lintEvent{jsonReport: jsonReport{Findings: findings, Errors: errorCount, Warnings: warningCount}, Passed: passed}
+		if event.Findings == nil {
+			event.Findings = []Finding{}
+		}
+		output.Emit("lint", event)
+		return passed, nil
+	}
+
+	var err error
+	switch format {
+	case "", "text":
+		for _, finding := range findings {
+			if _, err = fmt.Fprintf(out, "%s:%d: %s [%s] %s\n",
+				finding.Path, finding.Line, finding.Severity, finding.Rule, finding.Message); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			glog.Info("Lint finished with %d error(s) and %d warning(s).", errorCount, warningCount)
+		}
+	case "json":
+		report := jsonReport{Findings: findings, Errors: errorCount, Warnings: warningCount}
+		if report.Findings == nil {
+			report.Findings = []Finding{}
+		}
+		err = encodeJson(out, report)
+	case "sarif":
+		err = encodeJson(out, newSarifLog(rules, findings))
+	default:
+		return false, &errschemer.SchemerErr{
+			Code:    "0096",
+			Kind:    errschemer.KindUsage,
+			Message: "unknown --format " + format + ", expected text, json or sarif",
+		}
+	}
+
+	if err != nil {
+		return false, &errschemer.SchemerErr{
+			Code:    "0097",
+			Message: "failed to write lint report",
+			Err:     err,
+		}
+	}
+	return passed, nil
+}
+
+func encodeJson(out io.Writer, value any) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// newSarifLog converts findings into a SARIF 2.1.0 log.
+func newSarifLog(rules []Rule, findings []Finding) sarifLog {
+	driver := sarifDriver{
+		Name:           "schemer",
+		Version:        build.Version,
+		InformationURI: "https://github.com/inskribe/schemer",
+	}
+	for _, rule := range rules {
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:               rule.ID,
+			ShortDescription: sarifMessage{rule.Description},
+			DefaultConfig:    sarifConfig{sarifLevel(rule.Severity)},
+		})
+	}
+
+	results := make([]sarifResult, 0, len(findings))
+	for _, finding := range findings {
+		results = append(results, sarifResult{
+			RuleID:  finding.Rule,
+			Level:   sarifLevel(finding.Severity),
+			Message: sarifMessage{finding.Message},
+			Locations: []sarifLocation{{sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifact{finding.Path},
+				Region:           sarifRegion{finding.Line},
+			}}},
+		})
+	}
+
+	return sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{driver}, Results: results}},
+	}
+}
+
+// sarifLevel maps a severity to a SARIF result level.
+func sarifLevel(severity Severity) string {
+	switch severity {
+	case SeverityError:
+		return "error"
+	case SeverityOff:
+		return "none"
+	}
+	return "warning"
+}
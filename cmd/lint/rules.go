@@ -0,0 +1,365 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package lint
+
+import (
+	"strings"
+
+	"github.com/inskribe/schemer/internal/sqltoken"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+// Severity ranks how serious a finding is.
+type Severity string
+
+const (
+	SeverityOff     Severity = "off"     // rule is disabled
+	SeverityWarning Severity = "warning" // risky, reported but does not fail the run
+	SeverityError   Severity = "error"   // dangerous, fails the run
+)
+
+// Rule is a single lint check applied to every statement of a delta file.
+type Rule struct {
+	ID          string   // stable identifier used in flags and suppression comments
+	Description string   // one line explanation shown in reports
+	Severity    Severity // default severity, may be overridden with --rule
+	check       func(file *lintFile, stmt *statement) []violation
+}
+
+// violation is a match of a rule against a statement.
+type violation struct {
+	line    int
+	message string
+}
+
+// lintFile carries per-file state shared by the rules.
+type lintFile struct {
+	direction utils.Direction
+	// tables created earlier in the same file, locking them is harmless since
+	// no other session can see them yet
+	created map[string]bool
+}
+
+// statement is a single SQL statement with the parts the rules look at pre-parsed.
+type statement struct {
+	tokens  []sqltoken.Token   // significant tokens, without trivia
+	table   string             // normalized target table of CREATE INDEX and ALTER TABLE
+	actions [][]sqltoken.Token // ALTER TABLE actions split at top level commas
+}
+
+// Rules returns every lint rule with its default severity, in report order.
+func Rules() []Rule {
+	return []Rule{
+		{
+			ID:          "create-index-without-concurrently",
+			Description: "CREATE INDEX without CONCURRENTLY blocks writes to the table while the index is built.",
+			Severity:    SeverityError,
+			check:       checkCreateIndex,
+		},
+		{
+			ID:          "alter-column-type",
+			Description: "ALTER COLUMN ... TYPE usually rewrites the table under an ACCESS EXCLUSIVE lock.",
+			Severity:    SeverityError,
+			check:       checkAlterColumnType,
+		},
+		{
+			ID:          "add-column-not-null-without-default",
+			Description: "ADD COLUMN ... NOT NULL without a DEFAULT fails on any table that already has rows.",
+			Severity:    SeverityError,
+			check:       checkAddColumnNotNull,
+		},
+		{
+			ID:          "drop-in-up",
+			Description: "DROP COLUMN and DROP TABLE in an up delta break code that is still running; move them to a post delta.",
+			Severity:    SeverityWarning,
+			check:       checkDropInUp,
+		},
+		{
+			ID:          "rename",
+			Description: "Renaming a table or column breaks code that still uses the old name.",
+			Severity:    SeverityWarning,
+			check:       checkRename,
+		},
+		{
+			ID:          "lock-table",
+			Description: "LOCK TABLE holds an explicit lock until the delta finishes.",
+			Severity:    SeverityWarning,
+			check:       checkLockTable,
+		},
+	}
+}
+
+// newStatement pre-parses the target table and ALTER TABLE actions of tokens.
+func newStatement(tokens []sqltoken.Token) *statement {
+	stmt := &statement{tokens: tokens}
+
+	switch {
+	case keywordsAt(tokens, 0, "CREATE", "INDEX"), keywordsAt(tokens, 0, "CREATE", "UNIQUE", "INDEX"):
+		for i, token := range tokens {
+			if token.IsKeyword("ON") {
+				index := i + 1
+				if keywordsAt(tokens, index, "ONLY") {
+					index++
+				}
+				stmt.table, _ = qualifiedName(tokens, index)
+				break
+			}
+		}
+
+	case keywordsAt(tokens, 0, "ALTER", "TABLE"):
+		index := 2
+		if keywordsAt(tokens, index, "IF", "EXISTS") {
+			index += 2
+		}
+		if keywordsAt(tokens, index, "ONLY") {
+			index++
+		}
+		var next int
+		stmt.table, next = qualifiedName(tokens, index)
+		stmt.actions = splitTopLevel(tokens[next:])
+	}
+	return stmt
+}
+
+// createdTable returns the normalized name of the table created by tokens, if any.
+func createdTable(tokens []sqltoken.Token) string {
+	index := 1
+	for index < len(tokens) && (tokens[index].IsKeyword("TEMP") || tokens[index].IsKeyword("TEMPORARY") ||
+		tokens[index].IsKeyword("UNLOGGED") || tokens[index].IsKeyword("GLOBAL") || tokens[index].IsKeyword("LOCAL")) {
+		index++
+	}
+	if !tokens[0].IsKeyword("CREATE") || !keywordsAt(tokens, index, "TABLE") {
+		return ""
+	}
+	index++
+	if keywordsAt(tokens, index, "IF", "NOT", "EXISTS") {
+		index += 3
+	}
+	name, _ := qualifiedName(tokens, index)
+	return name
+}
+
+func checkCreateIndex(file *lintFile, stmt *statement) []violation {
+	tokens := stmt.tokens
+	index := 1
+	if keywordsAt(tokens, index, "UNIQUE") {
+		index++
+	}
+	if !tokens[0].IsKeyword("CREATE") || !keywordsAt(tokens, index, "INDEX") {
+		return nil
+	}
+	if keywordsAt(tokens, index+1, "CONCURRENTLY") || file.created[stmt.table] {
+		return nil
+	}
+	return []violation{{tokens[0].Line,
+		"CREATE INDEX on existing table " + stmt.table + " without CONCURRENTLY blocks writes; " +
+			"use CREATE INDEX CONCURRENTLY in a delta file of its own"}}
+}
+
+func checkAlterColumnType(file *lintFile, stmt *statement) []violation {
+	if file.created[stmt.table] {
+		return nil
+	}
+	var result []violation
+	for _, action := range stmt.actions {
+		if !keywordsAt(action, 0, "ALTER") {
+			continue
+		}
+		index := 1
+		if keywordsAt(action, index, "COLUMN") {
+			index++
+		}
+		column := action[min(index, len(action)-1)].Text
+		index++
+		if keywordsAt(action, index, "SET", "DATA") {
+			index += 2
+		}
+		if keywordsAt(action, index, "TYPE") {
+			result = append(result, violation{action[0].Line,
+				"changing the type of column " + column + " on " + stmt.table + " may rewrite the table; " +
+					"add a new column and backfill it instead"})
+		}
+	}
+	return result
+}
+
+func checkAddColumnNotNull(file *lintFile, stmt *statement) []violation {
+	if file.created[stmt.table] {
+		return nil
+	}
+	var result []violation
+	for _, action := range stmt.actions {
+		if !keywordsAt(action, 0, "ADD") || len(action) < 2 {
+			continue
+		}
+		switch strings.ToUpper(action[1].Text) {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "FOREIGN", "CHECK", "EXCLUDE":
+			continue
+		}
+
+		var notNull, hasDefault bool
+		for i, token := range action {
+			if token.IsKeyword("DEFAULT") {
+				hasDefault = true
+			}
+			if keywordsAt(action, i, "NOT", "NULL") {
+				notNull = true
+			}
+		}
+		if notNull && !hasDefault {
+			index := 1
+			if keywordsAt(action, index, "COLUMN") {
+				index++
+			}
+			if keywordsAt(action, index, "IF", "NOT", "EXISTS") {
+				index += 3
+			}
+			column := action[min(index, len(action)-1)].Text
+			result = append(result, violation{action[0].Line,
+				"column " + column + " is added to " + stmt.table + " as NOT NULL without a DEFAULT; " +
+					"add a DEFAULT or add the column as nullable and backfill it"})
+		}
+	}
+	return result
+}
+
+func checkDropInUp(file *lintFile, stmt *statement) []violation {
+	if file.direction != utils.DirectionUp {
+		return nil
+	}
+	tokens := stmt.tokens
+	if keywordsAt(tokens, 0, "DROP", "TABLE") {
+		return []violation{{tokens[0].Line, "DROP TABLE in an up delta; move it to a post delta once no code uses the table"}}
+	}
+
+	var result []violation
+	for _, action := range stmt.actions {
+		if !keywordsAt(action, 0, "DROP") || len(action) < 2 {
+			continue
+		}
+		switch strings.ToUpper(action[1].Text) {
+		case "CONSTRAINT", "DEFAULT", "NOT", "IDENTITY", "EXPRESSION":
+			continue
+		}
+		result = append(result, violation{action[0].Line,
+			"DROP COLUMN on " + stmt.table + " in an up delta; move it to a post delta once no code uses the column"})
+	}
+	return result
+}
+
+func checkRename(file *lintFile, stmt *statement) []violation {
+	tokens := stmt.tokens
+	if !tokens[0].IsKeyword("ALTER") {
+		return nil
+	}
+	depth := 0
+	for _, token := range tokens {
+		switch {
+		case token.Kind == sqltoken.Punct && token.Text == "(":
+			depth++
+		case token.Kind == sqltoken.Punct && token.Text == ")":
+			depth--
+		case depth == 0 && token.IsKeyword("RENAME"):
+			return []violation{{token.Line,
+				"rename breaks code that still uses the old name; add the new name alongside the old one and drop the old one in a post delta"}}
+		}
+	}
+	return nil
+}
+
+func checkLockTable(file *lintFile, stmt *statement) []violation {
+	if !stmt.tokens[0].IsKeyword("LOCK") {
+		return nil
+	}
+	return []violation{{stmt.tokens[0].Line, "explicit LOCK TABLE blocks other sessions until the delta finishes"}}
+}
+
+// keywordsAt reports whether tokens starting at index are the given keywords.
+func keywordsAt(tokens []sqltoken.Token, index int, keywords ...string) bool {
+	if index < 0 || index+len(keywords) > len(tokens) {
+		return false
+	}
+	for i, keyword := range keywords {
+		if !tokens[index+i].IsKeyword(keyword) {
+			return false
+		}
+	}
+	return true
+}
+
+// qualifiedName reads a possibly schema qualified name starting at index.
+// Unquoted parts are lower cased to match how PostgreSQL folds identifiers.
+//
+// Returns:
+//   - string: the normalized name, empty if there is no name at index
+//   - int: index of the first token after the name
+func qualifiedName(tokens []sqltoken.Token, index int) (string, int) {
+	var parts []string
+	for index < len(tokens) {
+		token := tokens[index]
+		switch token.Kind {
+		case sqltoken.Word:
+			parts = append(parts, strings.ToLower(token.Text))
+		case sqltoken.QuotedIdent:
+			parts = append(parts, strings.ReplaceAll(strings.Trim(token.Text, `"`), `""`, `"`))
+		default:
+			return strings.Join(parts, "."), index
+		}
+		index++
+		if index < len(tokens) && tokens[index].Kind == sqltoken.Punct && tokens[index].Text == "." {
+			index++
+			continue
+		}
+		break
+	}
+	return strings.Join(parts, "."), index
+}
+
+// splitTopLevel splits tokens at commas that are not inside parentheses.
+func splitTopLevel(tokens []sqltoken.Token) [][]sqltoken.Token {
+	var result [][]sqltoken.Token
+	var current []sqltoken.Token
+	depth := 0
+	for _, token := range tokens {
+		if token.Kind == sqltoken.Punct {
+			switch token.Text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			case ",":
+				if depth == 0 {
+					if len(current) > 0 {
+						result = append(result, current)
+					}
+					current = nil
+					continue
+				}
+			}
+		}
+		current = append(current, token)
+	}
+	if len(current) > 0 {
+		result = append(result, current)
+	}
+	return result
+}
@@ -0,0 +1,138 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inskribe/schemer/internal/config"
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/glog"
+)
+
+// loggingOptions holds the logging flags of the root command.
+type loggingOptions struct {
+	Level   string
+	Format  string
+	File    string
+	Quiet   bool // only errors
+	Verbose bool // debug records as well
+}
+
+var logging loggingOptions
+
+// configureLogging sets up glog from the logging flags, falling back to $SCHEMER_LOG_LEVEL,
+// $SCHEMER_LOG_FORMAT and $SCHEMER_LOG_FILE. It runs before the config file is loaded so that
+// the lines logged while loading it already honour the flags.
+//
+// Params:
+//   - command: the command being run
+//
+// Returns:
+//   - error: non-nil if a flag is invalid or the log file cannot be opened
+func configureLogging(command *cobra.Command) error {
+	flags := command.Flags()
+	level := flagOrEnv(command, "log-level", logging.Level)
+	format := flagOrEnv(command, "log-format", logging.Format)
+	file := flagOrEnv(command, "log-file", logging.File)
+
+	if logging.Quiet && logging.Verbose || flags.Changed("log-level") && (logging.Quiet || logging.Verbose) {
+		return &errschemer.SchemerErr{
+			Code:    "0215",
+			Message: "only one of --log-level, --quiet and --verbose can be used",
+			Kind:    errschemer.KindUsage,
+		}
+	}
+
+	// --quiet and --verbose take precedence over $SCHEMER_LOG_LEVEL.
+	options := glog.DefaultOptions()
+	if level != "" {
+		parsed, err := glog.ParseLevel(level)
+		if err != nil {
+			return &errschemer.SchemerErr{
+				Code:    "0216",
+				Message: "invalid log level",
+				Err:     err,
+				Kind:    errschemer.KindUsage,
+			}
+		}
+		options.Level = parsed
+	}
+	switch {
+	case logging.Quiet:
+		options.Level = slog.LevelError
+	case logging.Verbose:
+		options.Level = slog.LevelDebug
+	}
+
+	switch glog.Format(format) {
+	case "", glog.FormatText:
+		options.Format = glog.FormatText
+	case glog.FormatJSON:
+		options.Format = glog.FormatJSON
+	default:
+		return &errschemer.SchemerErr{
+			Code:    "0217",
+			Message: "unknown --log-format " + format + ", expected text or json",
+			Kind:    errschemer.KindUsage,
+		}
+	}
+
+	if file != "" {
+		handle, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return &errschemer.SchemerErr{
+				Code:    "0218",
+				Message: "failed to open log file " + file,
+				Err:     err,
+			}
+		}
+		// The file stays open until the process exits.
+		options.Writer = handle
+	}
+	options.Color = options.Format == glog.FormatText && glog.UseColor(options.Writer)
+
+	glog.Configure(options)
+	return nil
+}
+
+// flagOrEnv returns the value of a flag, or its SCHEMER_* environment variable if the flag
+// was not passed.
+//
+// Params:
+//   - command: the command being run
+//   - name: name of the flag
+//   - value: current value of the flag
+//
+// Returns:
+//   - string: the value to use
+func flagOrEnv(command *cobra.Command, name string, value string) string {
+	if !command.Flags().Changed(name) {
+		if env := os.Getenv(config.EnvKey(name)); env != "" {
+			return env
+		}
+	}
+	return value
+}
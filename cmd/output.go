@@ -0,0 +1,79 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inskribe/schemer/internal/config"
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/output"
+)
+
+var outputFormat string
+
+// configureOutput selects the output format from --output or $SCHEMER_OUTPUT.
+//
+// Params:
+//   - command: the command being run
+//
+// Returns:
+//   - error: non-nil if the format is unknown
+func configureOutput(command *cobra.Command) error {
+	format := outputFormat
+	if !command.Flags().Changed("output") {
+		if value := os.Getenv(config.EnvKey("output")); value != "" {
+			format = value
+		}
+	}
+
+	name := ""
+	if command.HasParent() {
+		name = strings.TrimPrefix(command.CommandPath(), command.Root().Name()+" ")
+	}
+	return output.Configure(format, name)
+}
+
+// reportResult logs the error a command failed with and emits the result event of the run.
+// Finding nothing to do is logged as information, not as an error.
+//
+// Params:
+//   - err: the error returned by the command, nil on success
+//
+// Returns:
+//   - int: the process exit code
+func reportResult(err error) int {
+	code := ExitCode(err)
+	switch {
+	case err == nil:
+	case code == ExitNothingToDo:
+		glog.Info("%v", err)
+	default:
+		glog.Error("%s", strings.TrimSuffix(errschemer.FormatChain(err), "\n"))
+	}
+	output.EmitResult(err, code)
+	return code
+}
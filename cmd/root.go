@@ -24,14 +24,37 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 
+	"github.com/inskribe/schemer/internal/config"
+	"github.com/inskribe/schemer/internal/errschemer"
 	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/output"
+	"github.com/inskribe/schemer/internal/utils"
 )
 
-var cfgFile string
+var (
+	started        bool // set once flags and arguments are parsed and the command starts
+	cfgFile        string
+	profileName    string
+	confirmProfile string
+	templateVars   []string
+
+	// ActiveConfig is the loaded config file and profile, set before any command runs.
+	ActiveConfig *config.Config
+)
+
+// unboundFlags are never read from the environment or the config file.
+// --env is selected with SCHEMER_PROFILE since SCHEMER_ENV is taken, --project-dir is
+// resolved before the config file is found, and --confirm must always be typed out.
+// Template variables come from the vars section and SCHEMER_VAR_* instead of --var.
+// --output and the logging flags are read before the config file, which is loaded with
+// logging already set up.
+var unboundFlags = []string{"help", "config", "env", "project-dir", "confirm", "var", "output",
+	"log-level", "log-format", "log-file", "quiet", "verbose"}
 
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
@@ -42,67 +65,241 @@ var RootCmd = &cobra.Command{
 It operates on a delta-based system using ordered .sql files and tracks applied changes via a schemer table.
 Supports up, down, and post-migration steps with optional cherry-picking, tagging, and status tracking.
 
+The project root is the first directory above the current one holding schemer.yaml or
+deltas/schemer.sql, unless --project-dir or SCHEMER_PROJECT_DIR is set. init always uses the
+current directory unless one of them is set.
+Settings are read from schemer.yaml in the project root or a parent directory, with named
+profiles selected by --env or SCHEMER_PROFILE. Every flag can also be set with an environment
+variable named after it, e.g. SCHEMER_CONN_KEY for --conn-key.
+
 Typical usage:
   schemer up
   schemer down --to 002
   schemer post --force
+  schemer up --env staging
 `,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-
-		if os.Getenv("SCHEMER_ENV") == "local" {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Flags and arguments are valid once the command starts, any later error is reported
+		// by Execute instead of cobra's usage message.
+		started = true
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
 
-		} else {
-			glog.InitializeLogger(false)
+		if err := configureLogging(cmd); err != nil {
+			return err
+		}
+		if err := configureOutput(cmd); err != nil {
+			return err
 		}
+		return initConfig(cmd)
 	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// Errors returned by the commands are logged here and mapped to the exit code, see ExitCode.
 func Execute() {
-	err := RootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+	command, err := RootCmd.ExecuteC()
+	if err != nil && !started {
+		// cobra already printed the error and the usage of the command.
+		if configureOutput(command) == nil {
+			output.EmitResult(err, ExitUsage)
+		}
+		os.Exit(ExitUsage)
+	}
+
+	utils.LogTraceSummary()
+	if code := reportResult(err); code != ExitOK {
+		os.Exit(code)
 	}
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
-
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.schemer.yaml)")
+	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is schemer.yaml or .schemer.yaml in the project root or a parent directory, then $HOME/.schemer.yaml)")
+	RootCmd.PersistentFlags().StringVar(&utils.ProjectDir, "project-dir", "", "Project root. Defaults to $SCHEMER_PROJECT_DIR, then the first directory above the current one holding schemer.yaml or deltas/schemer.sql.")
+	RootCmd.PersistentFlags().StringVarP(&profileName, "env", "e", "", "Config profile to use, e.g. dev, staging or prod. Defaults to $SCHEMER_PROFILE, then default-profile in the config file.")
+	RootCmd.PersistentFlags().StringVar(&confirmProfile, "confirm", "", "Name of the active profile, required to run down, repair and seed against a protected profile.")
+	RootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", string(output.FormatText), "Output format: text, or json to write newline delimited JSON events to stdout. Defaults to $SCHEMER_OUTPUT.")
+	RootCmd.PersistentFlags().StringVar(&logging.Level, "log-level", "", "Lowest level logged: debug, info, warn or error. Defaults to $SCHEMER_LOG_LEVEL, then info.")
+	RootCmd.PersistentFlags().StringVar(&logging.Format, "log-format", string(glog.FormatText), "Log format: text, or json for one JSON object per line. Defaults to $SCHEMER_LOG_FORMAT.")
+	RootCmd.PersistentFlags().StringVar(&logging.File, "log-file", "", "Append logs to this file instead of stderr. Defaults to $SCHEMER_LOG_FILE.")
+	RootCmd.PersistentFlags().BoolVarP(&logging.Quiet, "quiet", "q", false, "Only log errors.")
+	RootCmd.PersistentFlags().BoolVarP(&logging.Verbose, "verbose", "v", false, "Log debug messages as well.")
+	RootCmd.PersistentFlags().StringArrayVar(&templateVars, "var", nil, "Template variable for delta files as name=value, available as {{.name}} in files starting with -- schemer:template. Can be repeated.")
+	RootCmd.PersistentFlags().StringVar(&utils.DeltaDir, "deltas-dir", utils.DeltaDir, "Directory holding the delta files, relative to the project root.")
+	RootCmd.PersistentFlags().StringVar(&utils.TrackingTable, "table", utils.DefaultTrackingTable, "Name of the tracking table. The companion tables are named after it, e.g. <table>_history.")
+	RootCmd.PersistentFlags().DurationVar(&utils.Connection.ConnectTimeout, "connect-timeout", 0, "How long to wait for a database connection, e.g. 10s. 0 uses the driver default.")
+	RootCmd.PersistentFlags().DurationVar(&utils.Connection.StatementTimeout, "statement-timeout", 0, "Abort any statement that takes longer, e.g. 5m. 0 uses the server default.")
+	RootCmd.PersistentFlags().DurationVar(&utils.Connection.LockTimeout, "lock-timeout", 0, "Abort any statement that waits longer for a lock, e.g. 10s. 0 uses the server default.")
+	RootCmd.PersistentFlags().BoolVar(&utils.FailOnWarning, "fail-on-warning", false, "Fail and roll back a delta, repeatable delta or seed when the server raises a WARNING while it runs.")
+	RootCmd.PersistentFlags().BoolVar(&utils.Trace.Enabled, "trace-sql", false, "Log every statement sent to the database with its delta, duration and rows, and the slowest statements at the end.")
+	RootCmd.PersistentFlags().IntVar(&utils.Trace.MaxLength, "trace-sql-length", utils.Trace.MaxLength, "Truncate statements logged by --trace-sql to this many characters. 0 logs them in full.")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	RootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-// initConfig reads in config file and ENV variables if set.
-func initConfig() {
+// NoRootDiscovery is the command annotation that makes the project root default to the current
+// directory instead of the first directory above it holding a project marker.
+const NoRootDiscovery = "schemer:no-root-discovery"
+
+// initConfig loads the config file once and applies it to the flags of the running command.
+// Flags passed on the command line take precedence over SCHEMER_* environment variables,
+// which take precedence over the config file.
+//
+// Params:
+//   - command: the command being run
+//
+// Returns:
+//   - error: non-nil if the config cannot be loaded or holds an invalid value
+func initConfig(command *cobra.Command) error {
+	if ActiveConfig == nil {
+		_, skipDiscovery := command.Annotations[NoRootDiscovery]
+		root, err := resolveProjectDir(!skipDiscovery)
+		if err != nil {
+			return err
+		}
+		utils.ProjectDir = root
+		glog.Info("Using project root %s", root)
+
+		file := findConfigFile(root)
+		loaded, err := config.Load(file, profileName)
+		if err != nil {
+			return err
+		}
+		ActiveConfig = loaded
+		if file != "" {
+			glog.Info("Using config file %s", file)
+		}
+		if loaded.Profile != "" {
+			glog.Info("Using profile %s", loaded.Profile)
+		}
+
+		if utils.TemplateVars, err = loaded.Vars(templateVars); err != nil {
+			return err
+		}
+	}
+
+	section := ""
+	if command.HasParent() {
+		section = strings.ReplaceAll(strings.TrimPrefix(command.CommandPath(), command.Root().Name()+" "), " ", ".")
+	}
+	if err := ActiveConfig.ApplyFlags(section, command.Flags(), unboundFlags); err != nil {
+		return err
+	}
+	return utils.CheckTrackingTable()
+}
+
+// resolveProjectDir returns --project-dir or $SCHEMER_PROJECT_DIR if set, otherwise the first
+// directory above the current one that holds a project marker, otherwise the current directory.
+//
+// Params:
+//   - discover: search the current directory and its parents for a project marker
+//
+// Returns:
+//   - string: absolute path of the project root
+//   - error: non-nil if the given project directory does not exist or cwd cannot be resolved
+func resolveProjectDir(discover bool) (string, error) {
+	dir := utils.ProjectDir
+	if dir == "" {
+		dir = os.Getenv(config.EnvKey("project-dir"))
+	}
+	if dir != "" {
+		absolute, err := filepath.Abs(dir)
+		if err == nil {
+			var info os.FileInfo
+			if info, err = os.Stat(absolute); err == nil && !info.IsDir() {
+				err = fmt.Errorf("%s is not a directory", absolute)
+			}
+		}
+		if err != nil {
+			return "", &errschemer.SchemerErr{
+				Code:    "0171",
+				Kind:    errschemer.KindUsage,
+				Message: "invalid project directory " + dir,
+				Err:     err,
+			}
+		}
+		return absolute, nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", &errschemer.SchemerErr{
+			Code:    "0165",
+			Message: "failed to get current working directory.",
+			Err:     err,
+		}
+	}
+	if !discover {
+		return cwd, nil
+	}
+	if root := config.FindRoot(cwd); root != "" {
+		return root, nil
+	}
+	glog.Info("No schemer.yaml or deltas/schemer.sql found above %s, using it as the project root.", cwd)
+	return cwd, nil
+}
+
+// findConfigFile resolves the config file from --config, $SCHEMER_CONFIG, the project root
+// and its parents, and finally $HOME/.schemer.yaml.
+//
+// Params:
+//   - root: the project root
+//
+// Returns:
+//   - string: path of the config file, empty if there is none
+func findConfigFile(root string) string {
 	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Find home directory.
-		home, err := os.UserHomeDir()
-		cobra.CheckErr(err)
+		return cfgFile
+	}
+	if file := os.Getenv("SCHEMER_CONFIG"); file != "" {
+		return file
+	}
+	if file := config.Find(root); file != "" {
+		return file
+	}
 
-		// Search config in home directory with name ".schemer" (without extension).
-		viper.AddConfigPath(home)
-		viper.SetConfigType("yaml")
-		viper.SetConfigName(".schemer")
+	if home, err := os.UserHomeDir(); err == nil {
+		file := filepath.Join(home, ".schemer.yaml")
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
 	}
+	return ""
+}
 
-	viper.AutomaticEnv() // read in environment variables that match
+// Profile returns the name of the active config profile, empty if none is selected.
+func Profile() string {
+	if ActiveConfig != nil {
+		return ActiveConfig.Profile
+	}
+	return profileName
+}
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+// CheckProtected refuses to run a destructive command against a protected profile
+// unless the profile name is repeated with --confirm.
+//
+// Params:
+//   - action: name of the command, used in the error message
+//
+// Returns:
+//   - error: non-nil if the active profile is protected and was not confirmed
+func CheckProtected(action string) error {
+	if ActiveConfig == nil || !ActiveConfig.Protected() || confirmProfile == ActiveConfig.Profile {
+		return nil
+	}
+	return &errschemer.SchemerErr{
+		Code:    "0170",
+		Kind:    errschemer.KindUsage,
+		Message: fmt.Sprintf("profile %s is protected, pass --confirm %s to run %s", ActiveConfig.Profile, ActiveConfig.Profile, action),
 	}
 }
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+func TestResolveProjectDir(t *testing.T) {
+	outer, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to resolve temp dir: %v", err)
+	}
+	nested := filepath.Join(outer, "services", "billing")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatalf("failed to create nested dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(outer, "schemer.yaml"), nil, 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	t.Chdir(nested)
+	t.Setenv("SCHEMER_PROJECT_DIR", "")
+	utils.ProjectDir = ""
+
+	testCases := []struct {
+		name     string
+		discover bool
+		expected string
+	}{
+		{name: "discovers the outer project", discover: true, expected: outer},
+		{name: "init uses the current directory", discover: false, expected: nested},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root, err := resolveProjectDir(tc.discover)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if root != tc.expected {
+				t.Fatalf("expected root %s, got %s", tc.expected, root)
+			}
+		})
+	}
+
+	t.Run("explicit directory wins", func(t *testing.T) {
+		t.Setenv("SCHEMER_PROJECT_DIR", outer)
+		root, err := resolveProjectDir(false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if root != outer {
+			t.Fatalf("expected root %s, got %s", outer, root)
+		}
+	})
+}
@@ -0,0 +1,380 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package validate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inskribe/schemer/cmd"
+	"github.com/inskribe/schemer/cmd/apply"
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/output"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+// Severity ranks how serious a validation issue is.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // schemer will misbehave or refuse to run
+	SeverityWarning Severity = "warning" // likely a mistake, but schemer can still run
+)
+
+// Issue is a single problem found in the deltas tree.
+type Issue struct {
+	Severity Severity `json:"severity"`       // error or warning
+	Path     string   `json:"path,omitempty"` // file relative to the deltas directory, empty for tree-wide issues
+	Message  string   `json:"message"`        // human readable description of the problem
+}
+
+// validateEvent is the document emitted by the validate command with --output json.
+type validateEvent struct {
+	output.Header
+	Valid    bool    `json:"valid"` // false if the command exits non-zero
+	Errors   int     `json:"errors"`
+	Warnings int     `json:"warnings"`
+	Issues   []Issue `json:"issues"`
+}
+
+// Represents user input for validate command.
+type ValidateOptions struct {
+	Strict bool // treat warnings as failures
+}
+
+var (
+	ValidateRequest ValidateOptions
+	validateCmd     = &cobra.Command{
+		Use:   "validate [options]",
+		Short: "Check the deltas directory for mistakes without connecting to a database",
+		Long: `The validate command walks the deltas directory and reports problems that would
+otherwise only show up when deltas are applied.
+
+It uses the same naming rules as up, down, post and create and reports:
+  error    files ending in .up.sql, .down.sql or .post.sql that don't follow <tag>_<name>.<type>.sql
+  error    duplicate tags, including across subdirectories
+  error    repeatable files starting with R_ that don't follow R_<name>.sql
+  error    repeatable files with the same name in different subdirectories
+  error    down or post files with no matching up file
+  error    empty files
+  warning  up files with no down file
+  warning  files that contain only comments
+  warning  gaps between tags
+
+Validate exits with a non-zero status if any error is found, or any warning with --strict.
+This makes it suitable as a pre-commit hook.
+
+Examples:
+  schemer validate
+  schemer validate --strict
+`,
+		Args: cobra.NoArgs,
+		RunE: func(command *cobra.Command, args []string) error {
+			deltaPath, err := utils.GetDeltaPath()
+			if err != nil {
+				return err
+			}
+
+			issues, err := validateDeltas(deltaPath)
+			if err != nil {
+				return err
+			}
+
+			if !reportIssues(issues, ValidateRequest.Strict) {
+				return &errschemer.SchemerErr{
+					Code:    "0195",
+					Message: "validation found errors, or warnings with --strict",
+				}
+			}
+			return nil
+		},
+	}
+)
+
+func init() {
+	cmd.RootCmd.AddCommand(validateCmd)
+	validateCmd.Flags().BoolVar(&ValidateRequest.Strict, "strict", false, "Exit with a non-zero status on warnings as well as errors.")
+}
+
+// deltaGroup collects every file found for a single tag.
+type deltaGroup struct {
+	up   []string
+	down []string
+	post []string
+	// up file bases, keyed by directory, used to pair post files with their up file
+	upBases map[string]string
+}
+
+// validateDeltas walks deltaPath and returns every problem found.
+//
+// Params:
+//   - deltaPath: the deltas directory to validate
+//
+// Returns:
+//   - []Issue: problems found, sorted by path
+//   - error: non-nil if the directory cannot be walked
+func validateDeltas(deltaPath string) ([]Issue, error) {
+	var issues []Issue
+	groups := make(map[int64]*deltaGroup)
+	repeatables := make(map[string][]string)
+
+	err := filepath.WalkDir(deltaPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return &errschemer.SchemerErr{
+				Code:    "0090",
+				Message: "failed to access path: " + path,
+				Err:     err,
+			}
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		relative, err := filepath.Rel(deltaPath, path)
+		if err != nil {
+			relative = path
+		}
+		relative = filepath.ToSlash(relative)
+
+		if strings.HasPrefix(d.Name(), "R_") && strings.HasSuffix(d.Name(), ".sql") && !utils.HasDeltaSuffix(d.Name()) {
+			if !utils.IsRepeatableFilename(d.Name()) {
+				issues = append(issues, Issue{SeverityError, relative,
+					"repeatable filename does not follow R_<name>.sql and will be ignored"})
+				return nil
+			}
+			repeatables[d.Name()] = append(repeatables[d.Name()], relative)
+			issue, err := checkContents(path, relative, false)
+			if err != nil {
+				return err
+			}
+			if issue != nil {
+				issues = append(issues, *issue)
+			}
+			return nil
+		}
+
+		if !utils.HasDeltaSuffix(d.Name()) {
+			return nil
+		}
+
+		parsed, err := utils.ParseDeltaFilename(d.Name())
+		if err != nil {
+			issues = append(issues, Issue{SeverityError, relative, "malformed delta tag: " + err.Error()})
+			return nil
+		}
+		if parsed == nil {
+			issues = append(issues, Issue{SeverityError, relative,
+				"filename does not follow <tag>_<name>.<up|down|post>.sql and will be ignored"})
+			return nil
+		}
+
+		issue, err := checkContents(path, relative, parsed.Direction == utils.DirectionUp)
+		if err != nil {
+			return err
+		}
+		if issue != nil {
+			issues = append(issues, *issue)
+		}
+
+		group, ok := groups[parsed.Tag]
+		if !ok {
+			group = &deltaGroup{upBases: make(map[string]string)}
+			groups[parsed.Tag] = group
+		}
+
+		switch parsed.Direction {
+		case utils.DirectionUp:
+			group.up = append(group.up, relative)
+			group.upBases[filepath.Dir(relative)] = parsed.Base
+		case utils.DirectionDown:
+			group.down = append(group.down, relative)
+		case utils.DirectionPost:
+			group.post = append(group.post, relative)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]int64, 0, len(groups))
+	for tag := range groups {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+
+	var upTags []int64
+	for _, tag := range tags {
+		group := groups[tag]
+		prefix := utils.ToPrefix(tag)
+
+		for _, kind := range []struct {
+			name  string
+			files []string
+		}{{"up", group.up}, {"down", group.down}, {"post", group.post}} {
+			if len(kind.files) > 1 {
+				issues = append(issues, Issue{SeverityError, kind.files[0],
+					fmt.Sprintf("duplicate %s delta tag %s in files: %s", kind.name, prefix, strings.Join(kind.files, ", "))})
+			}
+		}
+
+		if len(group.up) == 0 {
+			for _, file := range append(append([]string{}, group.down...), group.post...) {
+				issues = append(issues, Issue{SeverityError, file, "no up delta found for tag " + prefix})
+			}
+			continue
+		}
+		upTags = append(upTags, tag)
+
+		if len(group.down) == 0 {
+			issues = append(issues, Issue{SeverityWarning, group.up[0], "no down delta found, tag " + prefix + " cannot be rolled back"})
+		}
+
+		for _, post := range group.post {
+			base, _, _ := strings.Cut(filepath.Base(post), ".")
+			if group.upBases[filepath.Dir(post)] != base {
+				issues = append(issues, Issue{SeverityError, post,
+					"post delta must sit next to an up delta with the same name, otherwise it is never registered"})
+			}
+		}
+	}
+
+	for i := 1; i < len(upTags); i++ {
+		// timestamp tags are never contiguous, only gaps between sequential tags are reported
+		if utils.IsTimestampTag(upTags[i]) {
+			break
+		}
+		if upTags[i] != upTags[i-1]+1 {
+			issues = append(issues, Issue{SeverityWarning, "",
+				fmt.Sprintf("gap in tags between %s and %s", utils.ToPrefix(upTags[i-1]), utils.ToPrefix(upTags[i]))})
+		}
+	}
+
+	labels, err := utils.ReadLabels(deltaPath)
+	if err != nil {
+		var schemerErr *errschemer.SchemerErr
+		message := err.Error()
+		if errors.As(err, &schemerErr) {
+			message = schemerErr.Message
+		}
+		issues = append(issues, Issue{SeverityError, utils.LabelsFile, message})
+	}
+	names := slices.Sorted(maps.Keys(labels))
+	for _, name := range names {
+		if tag := labels[name]; !slices.Contains(upTags, tag) {
+			issues = append(issues, Issue{SeverityError, utils.LabelsFile,
+				fmt.Sprintf("label %s points to tag %s, which has no up delta", name, utils.ToPrefix(tag))})
+		}
+	}
+
+	for _, files := range repeatables {
+		if len(files) > 1 {
+			sort.Strings(files)
+			issues = append(issues, Issue{SeverityError, files[0],
+				"duplicate repeatable delta in files: " + strings.Join(files, ", ")})
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Path < issues[j].Path
+	})
+	return issues, nil
+}
+
+// checkContents reports a file that is empty, contains only comments, or holds an
+// environment directive where it is ignored.
+//
+// Params:
+//   - path: path of the file to read
+//   - relative: path reported in the issue
+//   - directiveAllowed: true for up deltas, the only files the environment directive is read from
+//
+// Returns:
+//   - *Issue: the problem found, nil if the file contains SQL
+//   - error: non-nil if the file cannot be read
+func checkContents(path string, relative string, directiveAllowed bool) (*Issue, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &errschemer.SchemerErr{
+			Code:    "0091",
+			Message: "failed to read file at: " + path,
+			Err:     err,
+		}
+	}
+	if len(bytes.TrimSpace(contents)) == 0 {
+		return &Issue{SeverityError, relative, "file is empty"}, nil
+	}
+	if apply.IsNoOpSql(string(contents)) {
+		return &Issue{SeverityWarning, relative, "file contains only comments"}, nil
+	}
+	if !directiveAllowed && utils.ParseEnvironments(contents) != nil {
+		return &Issue{SeverityWarning, relative,
+			utils.EnvironmentDirective + " is only read from the up delta of a group and is ignored here"}, nil
+	}
+	return nil, nil
+}
+
+// reportIssues logs every issue and a summary line.
+//
+// Params:
+//   - issues: problems found by validateDeltas
+//   - strict: treat warnings as failures
+//
+// Returns:
+//   - bool: true if the deltas directory passed validation
+func reportIssues(issues []Issue, strict bool) bool {
+	var errorCount, warningCount int
+	for _, issue := range issues {
+		location := issue.Path
+		if location == "" {
+			location = "deltas"
+		}
+		if issue.Severity == SeverityError {
+			errorCount++
+			glog.Error("%s: %s", location, issue.Message)
+		} else {
+			warningCount++
+			glog.Warn("%s: %s", location, issue.Message)
+		}
+	}
+
+	glog.Info("Validation finished with %d error(s) and %d warning(s).", errorCount, warningCount)
+	valid := errorCount == 0 && (!strict || warningCount == 0)
+	output.Emit("validate", &validateEvent{
+		Valid:    valid,
+		Errors:   errorCount,
+		Warnings: warningCount,
+		Issues:   append([]Issue{}, issues...),
+	})
+	return valid
+}
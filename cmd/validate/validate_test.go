@@ -0,0 +1,134 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inskribe/schemer/internal/glog"
+)
+
+func TestMain(m *testing.M) {
+	glog.InitializeLogger(true)
+	os.Exit(m.Run())
+}
+
+func writeDeltaTree(t *testing.T, files map[string]string) string {
+	tempDir := t.TempDir()
+	for rel, contents := range files {
+		full := filepath.Join(tempDir, rel)
+		if err := os.MkdirAll(filepath.Dir(full), os.ModePerm); err != nil {
+			t.Fatalf("failed to create dir for %s: %v", rel, err)
+		}
+		if err := os.WriteFile(full, []byte(contents), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", rel, err)
+		}
+	}
+	return tempDir
+}
+
+func TestValidateDeltas_Clean(t *testing.T) {
+	tempDir := writeDeltaTree(t, map[string]string{
+		"schemer.sql":                      "CREATE TABLE schemer ();",
+		"000_init.up.sql":                  "CREATE TABLE a ();",
+		"000_init.down.sql":                "DROP TABLE a;",
+		"users/001_add_user.up.sql":        "CREATE TABLE b ();",
+		"users/001_add_user.down.sql":      "DROP TABLE b;",
+		"users/001_add_user.post.sql":      "ALTER TABLE b ADD COLUMN c INT;",
+		"billing/002_add_invoice.up.sql":   "CREATE TABLE c ();",
+		"billing/002_add_invoice.down.sql": "DROP TABLE c;",
+		"20261017143000_add_d.up.sql":      "CREATE TABLE d ();",
+		"20261017143000_add_d.down.sql":    "DROP TABLE d;",
+		"20261018090000_add_e.up.sql":      "CREATE TABLE e ();",
+		"20261018090000_add_e.down.sql":    "DROP TABLE e;",
+		"R_views.sql":                      "CREATE OR REPLACE VIEW v AS SELECT 1;",
+	})
+
+	issues, err := validateDeltas(tempDir)
+	if err != nil {
+		t.Fatalf("validateDeltas failed: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("expected no issues, got %+v", issues)
+	}
+	if !reportIssues(issues, true) {
+		t.Fatalf("expected clean tree to pass strict validation")
+	}
+}
+
+func TestValidateDeltas_Problems(t *testing.T) {
+	tempDir := writeDeltaTree(t, map[string]string{
+		"000_init.up.sql":              "CREATE TABLE a ();",
+		"000_init.down.sql":            "DROP TABLE a;",
+		"users/000_dupe.up.sql":        "CREATE TABLE b ();",
+		"001_only_comments.up.sql":     "-- TODO: Add delta SQL here",
+		"001_only_comments.down.sql":   "",
+		"002_orphan.down.sql":          "DROP TABLE x;",
+		"004_no_down.up.sql":           "CREATE TABLE d ();",
+		"004_wrong_name.post.sql":      "SELECT 1;",
+		"add_users.up.sql":             "CREATE TABLE e ();",
+		"notes/readme.sql":             "-- not a delta",
+		"notes/005_ignored_prefix.txt": "not a delta",
+		"R_views.sql":                  "CREATE OR REPLACE VIEW v AS SELECT 1;",
+		"users/R_views.sql":            "CREATE OR REPLACE VIEW w AS SELECT 1;",
+		"R_grants.sql":                 "",
+		"R_bad.name.sql":               "SELECT 1;",
+		"labels.txt":                   "release-1 = 001\nrelease-2 = 003\n",
+	})
+
+	issues, err := validateDeltas(tempDir)
+	if err != nil {
+		t.Fatalf("validateDeltas failed: %v", err)
+	}
+
+	expected := []struct {
+		severity Severity
+		path     string
+		contains string
+	}{
+		{SeverityError, "000_init.up.sql", "duplicate up delta tag 000"},
+		{SeverityWarning, "001_only_comments.up.sql", "only comments"},
+		{SeverityError, "001_only_comments.down.sql", "empty"},
+		{SeverityError, "002_orphan.down.sql", "no up delta"},
+		{SeverityWarning, "004_no_down.up.sql", "no down delta"},
+		{SeverityError, "004_wrong_name.post.sql", "same name"},
+		{SeverityError, "add_users.up.sql", "does not follow"},
+		{SeverityWarning, "", "gap in tags between 001 and 004"},
+		{SeverityError, "R_views.sql", "duplicate repeatable delta"},
+		{SeverityError, "R_grants.sql", "empty"},
+		{SeverityError, "R_bad.name.sql", "R_<name>.sql"},
+		{SeverityError, "labels.txt", "label release-2 points to tag 003"},
+	}
+
+	for _, want := range expected {
+		found := false
+		for _, issue := range issues {
+			if issue.Severity == want.severity && issue.Path == want.path && strings.Contains(issue.Message, want.contains) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected %s issue for %q containing %q, got %+v", want.severity, want.path, want.contains, issues)
+		}
+	}
+
+	if len(issues) != len(expected) {
+		t.Errorf("expected %d issues, got %d: %+v", len(expected), len(issues), issues)
+	}
+
+	if reportIssues(issues, false) {
+		t.Fatalf("expected validation to fail")
+	}
+}
+
+func TestReportIssues_Strict(t *testing.T) {
+	issues := []Issue{{SeverityWarning, "001_a.up.sql", "file contains only comments"}}
+	if !reportIssues(issues, false) {
+		t.Fatalf("expected warnings to pass without --strict")
+	}
+	if reportIssues(issues, true) {
+		t.Fatalf("expected warnings to fail with --strict")
+	}
+}
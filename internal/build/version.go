@@ -0,0 +1,5 @@
+package build
+
+// Version is the schemer release version. Overridden at build time with
+// -ldflags "-X github.com/inskribe/schemer/internal/build.Version=...".
+var Version = "0.5.0" // x-release-please-version
@@ -0,0 +1,328 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Package config loads the project config file and applies its values to command flags.
+//
+// A config file holds top-level flag values, per-command sections and named profiles:
+//
+//	default-profile: dev
+//	conn-key: DATABASE_URL
+//	profiles:
+//	  dev:
+//	    conn-key: DEV_DATABASE_URL
+//	  prod:
+//	    conn-key: PROD_DATABASE_URL
+//	    protected: true
+//	    up:
+//	      allow-out-of-order: false
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/inskribe/schemer/internal/errschemer"
+)
+
+const (
+	EnvPrefix         = "SCHEMER_"        // prefix of the environment variable bound to every flag
+	ProfileEnvKey     = "SCHEMER_PROFILE" // environment variable selecting the profile
+	DefaultProfileKey = "default-profile" // config key naming the profile used when none is selected
+	ProfilesKey       = "profiles"        // config key holding the named profiles
+	ProtectedKey      = "protected"       // profile key marking the profile as protected
+	VarsKey           = "vars"            // config key holding template variables for delta files
+	VarEnvPrefix      = "SCHEMER_VAR_"    // prefix of environment variables holding template variables
+)
+
+// FileNames are the names of a project config file, in the order they are looked for in each directory.
+var FileNames = []string{"schemer.yaml", ".schemer.yaml"}
+
+// Config is a loaded config file together with the selected profile.
+type Config struct {
+	File    string // path of the loaded config file, empty if none was found
+	Profile string // name of the selected profile, empty if none was selected
+	values  *viper.Viper
+}
+
+// deltasMarker marks a project root that has no config file.
+var deltasMarker = filepath.Join("deltas", "schemer.sql")
+
+// FindRoot walks up from dir to the first directory holding a config file or a deltas
+// directory containing schemer.sql.
+//
+// Params:
+//   - dir: directory to start the search in
+//
+// Returns:
+//   - string: the project root, empty if no marker was found up to the filesystem root
+func FindRoot(dir string) string {
+	for {
+		for _, marker := range append(slices.Clone(FileNames), deltasMarker) {
+			if info, err := os.Stat(filepath.Join(dir, marker)); err == nil && !info.IsDir() {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Find walks up from dir and returns the first config file found.
+//
+// Params:
+//   - dir: directory to start the search in
+//
+// Returns:
+//   - string: path of the config file, empty if none was found up to the filesystem root
+func Find(dir string) string {
+	for {
+		for _, name := range FileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Load reads the config file and selects a profile.
+// The profile is the given name, else $SCHEMER_PROFILE, else the default-profile key.
+// When the file defines profiles the selected one must be among them.
+//
+// Params:
+//   - file: path of the config file, empty to load an empty config
+//   - profile: name of the profile selected on the command line, may be empty
+//
+// Returns:
+//   - *Config: the loaded config
+//   - error: non-nil if the file cannot be read or the profile is unknown
+func Load(file string, profile string) (*Config, error) {
+	config := &Config{File: file, values: viper.New()}
+	if file != "" {
+		config.values.SetConfigFile(file)
+		config.values.SetConfigType("yaml")
+		if err := config.values.ReadInConfig(); err != nil {
+			return nil, &errschemer.SchemerErr{
+				Code:    "0166",
+				Message: "failed to read config file " + file,
+				Err:     err,
+			}
+		}
+	}
+
+	if profile == "" {
+		profile = os.Getenv(ProfileEnvKey)
+	}
+	if profile == "" {
+		profile = config.values.GetString(DefaultProfileKey)
+	}
+
+	profiles := config.Profiles()
+	if profile != "" && len(profiles) > 0 && !slices.Contains(profiles, profile) {
+		return nil, &errschemer.SchemerErr{
+			Code:    "0167",
+			Kind:    errschemer.KindUsage,
+			Message: fmt.Sprintf("unknown profile %q, %s defines %s", profile, file, strings.Join(profiles, ", ")),
+		}
+	}
+	config.Profile = profile
+	return config, nil
+}
+
+// Profiles returns the names of the profiles defined in the config file, sorted.
+func (config *Config) Profiles() []string {
+	var names []string
+	for name := range config.values.GetStringMap(ProfilesKey) {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Protected reports whether the selected profile is marked as protected.
+func (config *Config) Protected() bool {
+	if config.Profile == "" {
+		return false
+	}
+	return config.values.GetBool(ProfilesKey + "." + config.Profile + "." + ProtectedKey)
+}
+
+// Lookup returns the config value for a flag. The most specific key wins:
+// the command section of the profile, the profile, the command section, then the top level.
+//
+// Params:
+//   - section: command path below the root joined with dots, e.g. "up" or "scratch.create"
+//   - name: flag name
+//
+// Returns:
+//   - any: the configured value
+//   - bool: false if the flag is not configured
+func (config *Config) Lookup(section string, name string) (any, bool) {
+	var keys []string
+	if config.Profile != "" {
+		prefix := ProfilesKey + "." + config.Profile + "."
+		if section != "" {
+			keys = append(keys, prefix+section+"."+name)
+		}
+		keys = append(keys, prefix+name)
+	}
+	if section != "" {
+		keys = append(keys, section+"."+name)
+	}
+	keys = append(keys, name)
+
+	for _, key := range keys {
+		if !config.values.IsSet(key) {
+			continue
+		}
+		value := config.values.Get(key)
+		if _, nested := value.(map[string]any); nested {
+			continue
+		}
+		return value, true
+	}
+	return nil, false
+}
+
+// Vars returns the template variables for delta files: the vars section, overlaid by the vars
+// of the selected profile, then SCHEMER_VAR_* environment variables, then the given assignments.
+// Names are lower case since config keys are case-insensitive.
+//
+// Params:
+//   - assignments: name=value pairs given with --var
+//
+// Returns:
+//   - map[string]string: variables by name
+//   - error: non-nil if an assignment is not of the form name=value
+func (config *Config) Vars(assignments []string) (map[string]string, error) {
+	vars := config.values.GetStringMapString(VarsKey)
+	if config.Profile != "" {
+		for name, value := range config.values.GetStringMapString(ProfilesKey + "." + config.Profile + "." + VarsKey) {
+			vars[name] = value
+		}
+	}
+
+	for _, variable := range os.Environ() {
+		if name, value, ok := strings.Cut(variable, "="); ok && strings.HasPrefix(name, VarEnvPrefix) {
+			vars[strings.ToLower(strings.TrimPrefix(name, VarEnvPrefix))] = value
+		}
+	}
+
+	for _, assignment := range assignments {
+		name, value, ok := strings.Cut(assignment, "=")
+		if !ok || name == "" {
+			return nil, &errschemer.SchemerErr{
+				Code:    "0174",
+				Kind:    errschemer.KindUsage,
+				Message: fmt.Sprintf("invalid --var %q, expected name=value", assignment),
+			}
+		}
+		vars[strings.ToLower(name)] = value
+	}
+	return vars, nil
+}
+
+// EnvKey returns the environment variable bound to a flag, e.g. SCHEMER_CONN_KEY for conn-key.
+func EnvKey(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// ApplyFlags sets every flag that was not passed on the command line from its environment
+// variable, or else from the config file. Flags named in skip are left alone.
+//
+// Params:
+//   - section: command path below the root joined with dots
+//   - flags: the flags of the command being run
+//   - skip: names of flags that are never read from the environment or config
+//
+// Returns:
+//   - error: non-nil if a value cannot be parsed by its flag
+func (config *Config) ApplyFlags(section string, flags *pflag.FlagSet, skip []string) error {
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || slices.Contains(skip, flag.Name) {
+			return
+		}
+
+		var values []string
+		source := EnvKey(flag.Name)
+		if value, ok := os.LookupEnv(source); ok {
+			values = strings.Split(value, ",")
+			if _, isSlice := flag.Value.(pflag.SliceValue); !isSlice {
+				values = []string{value}
+			}
+		} else if value, ok := config.Lookup(section, flag.Name); ok {
+			source = config.File
+			values = configValues(value)
+		} else {
+			return
+		}
+
+		if setErr := setFlag(flag, values); setErr != nil {
+			err = &errschemer.SchemerErr{
+				Code:    "0168",
+				Kind:    errschemer.KindUsage,
+				Message: fmt.Sprintf("invalid value for --%s from %s", flag.Name, source),
+				Err:     setErr,
+			}
+		}
+	})
+	return err
+}
+
+// configValues converts a config value into the strings a flag parses.
+func configValues(value any) []string {
+	list, ok := value.([]any)
+	if !ok {
+		return []string{fmt.Sprint(value)}
+	}
+	values := make([]string, 0, len(list))
+	for _, item := range list {
+		values = append(values, fmt.Sprint(item))
+	}
+	return values
+}
+
+// setFlag sets a flag without marking it as changed, so applying the config twice is harmless.
+func setFlag(flag *pflag.Flag, values []string) error {
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		return slice.Replace(values)
+	}
+	if len(values) != 1 {
+		return fmt.Errorf("expected a single value, got %d", len(values))
+	}
+	return flag.Value.Set(values[0])
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+)
+
+const testConfig = `
+default-profile: dev
+conn-key: DATABASE_URL
+dry-run: true
+up:
+  to: "003"
+vars:
+  app_role: app
+  tablespace: pg_default
+profiles:
+  dev:
+    conn-key: DEV_DATABASE_URL
+    vars:
+      app_role: app_dev
+  prod:
+    conn-key: PROD_DATABASE_URL
+    protected: true
+    up:
+      cherry-pick: ["001", "002"]
+`
+
+func writeConfig(t *testing.T, dir string, name string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(testConfig), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("failed to create dirs: %v", err)
+	}
+
+	hidden := writeConfig(t, root, ".schemer.yaml")
+	if found := Find(nested); found != hidden {
+		t.Fatalf("expected %s, got %s", hidden, found)
+	}
+
+	plain := writeConfig(t, root, "schemer.yaml")
+	if found := Find(nested); found != plain {
+		t.Fatalf("expected schemer.yaml to be preferred, got %s", found)
+	}
+
+	closer := writeConfig(t, filepath.Join(root, "a"), ".schemer.yaml")
+	if found := Find(nested); found != closer {
+		t.Fatalf("expected the closest config %s, got %s", closer, found)
+	}
+}
+
+func TestFindRoot(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "services", "api", "deltas")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("failed to create dirs: %v", err)
+	}
+
+	if found := FindRoot(nested); found != "" && !strings.HasPrefix(root, found) {
+		t.Fatalf("expected no root inside the temp dir, got %s", found)
+	}
+
+	writeConfig(t, root, "schemer.yaml")
+	if found := FindRoot(nested); found != root {
+		t.Fatalf("expected the config file to mark %s, got %s", root, found)
+	}
+
+	service := filepath.Join(root, "services", "api")
+	if err := os.WriteFile(filepath.Join(nested, "schemer.sql"), []byte("-- table"), 0644); err != nil {
+		t.Fatalf("failed to write schemer.sql: %v", err)
+	}
+	if found := FindRoot(nested); found != service {
+		t.Fatalf("expected deltas/schemer.sql to mark %s, got %s", service, found)
+	}
+}
+
+func TestLoad_Profile(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), "schemer.yaml")
+
+	testCases := []struct {
+		name      string
+		flag      string
+		env       string
+		expected  string
+		protected bool
+	}{
+		{name: "default profile", expected: "dev"},
+		{name: "env var", env: "prod", expected: "prod", protected: true},
+		{name: "flag wins over env var", flag: "dev", env: "prod", expected: "dev"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(ProfileEnvKey, tc.env)
+			config, err := Load(path, tc.flag)
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if config.Profile != tc.expected {
+				t.Errorf("expected profile %s, got %s", tc.expected, config.Profile)
+			}
+			if config.Protected() != tc.protected {
+				t.Errorf("expected protected=%v", tc.protected)
+			}
+		})
+	}
+
+	t.Setenv(ProfileEnvKey, "")
+	_, err := Load(path, "staging")
+	if schemerErr, ok := err.(*er.SchemerErr); !ok || schemerErr.Code != "0167" {
+		t.Fatalf("expected unknown profile to fail with 0167, got %v", err)
+	}
+
+	config, err := Load("", "staging")
+	if err != nil || config.Profile != "staging" {
+		t.Fatalf("expected any profile name without a config file, got %+v, %v", config, err)
+	}
+}
+
+func TestApplyFlags(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), "schemer.yaml")
+	t.Setenv(ProfileEnvKey, "")
+
+	newFlags := func() (*pflag.FlagSet, *string, *string, *bool, *[]string) {
+		flags := pflag.NewFlagSet("up", pflag.ContinueOnError)
+		connKey := flags.String("conn-key", "", "")
+		to := flags.String("to", "", "")
+		dryRun := flags.Bool("dry-run", false, "")
+		cherries := flags.StringSlice("cherry-pick", nil, "")
+		return flags, connKey, to, dryRun, cherries
+	}
+
+	config, err := Load(path, "prod")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	flags, connKey, to, dryRun, cherries := newFlags()
+	for run := 0; run < 2; run++ {
+		if err := config.ApplyFlags("up", flags, nil); err != nil {
+			t.Fatalf("ApplyFlags failed: %v", err)
+		}
+	}
+	if *connKey != "PROD_DATABASE_URL" || *to != "003" || !*dryRun || len(*cherries) != 2 {
+		t.Fatalf("expected profile, section and top-level values, got %s %s %v %v", *connKey, *to, *dryRun, *cherries)
+	}
+
+	t.Setenv(EnvKey("conn-key"), "ENV_DATABASE_URL")
+	flags, connKey, to, _, _ = newFlags()
+	if err := flags.Parse([]string{"--to", "005"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	if err := config.ApplyFlags("up", flags, []string{"dry-run"}); err != nil {
+		t.Fatalf("ApplyFlags failed: %v", err)
+	}
+	if *connKey != "ENV_DATABASE_URL" || *to != "005" {
+		t.Fatalf("expected the env var and command line to win, got %s %s", *connKey, *to)
+	}
+	if dryRun, _ := flags.GetBool("dry-run"); dryRun {
+		t.Fatalf("expected skipped flags to be left alone")
+	}
+
+	t.Setenv(EnvKey("dry-run"), "sometimes")
+	flags, _, _, _, _ = newFlags()
+	err = config.ApplyFlags("up", flags, nil)
+	if schemerErr, ok := err.(*er.SchemerErr); !ok || schemerErr.Code != "0168" {
+		t.Fatalf("expected invalid value to fail with 0168, got %v", err)
+	}
+}
+
+func TestVars(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), "schemer.yaml")
+	t.Setenv(ProfileEnvKey, "")
+	t.Setenv(VarEnvPrefix+"PUBLICATION", "dev_pub")
+	t.Setenv(VarEnvPrefix+"TABLESPACE", "fast")
+
+	config, err := Load(path, "dev")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	vars, err := config.Vars([]string{"Tenant=acme", "publication=cli_pub"})
+	if err != nil {
+		t.Fatalf("Vars failed: %v", err)
+	}
+	expected := map[string]string{"app_role": "app_dev", "tablespace": "fast", "publication": "cli_pub", "tenant": "acme"}
+	for name, value := range expected {
+		if vars[name] != value {
+			t.Errorf("expected %s=%s, got %q", name, value, vars[name])
+		}
+	}
+
+	_, err = config.Vars([]string{"tenant"})
+	if schemerErr, ok := err.(*er.SchemerErr); !ok || schemerErr.Code != "0174" {
+		t.Fatalf("expected an assignment without = to fail with 0174, got %v", err)
+	}
+
+	empty, err := Load("", "")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if vars, err := empty.Vars(nil); err != nil || vars == nil {
+		t.Fatalf("expected an empty variable map, got %v, %v", vars, err)
+	}
+}
@@ -0,0 +1,1462 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package errschemer
+
+import (
+	"fmt"
+	"strings"
+)
+
+//go:generate go run ./gen
+
+// Category groups error codes by the part of schemer they come from.
+type Category string
+
+const (
+	CategoryUsage      Category = "usage"      // flags, arguments and config values
+	CategoryConfig     Category = "config"     // project directory, .env and schemer.yaml
+	CategoryConnection Category = "connection" // reaching the server, scratch databases
+	CategoryTracking   Category = "tracking"   // the tracking table and its side tables
+	CategoryDeltas     Category = "deltas"     // reading delta, seed and label files
+	CategoryExecution  Category = "execution"  // running deltas against the database
+	CategorySchema     Category = "schema"     // snapshots read by dump and diff
+	CategoryOutput     Category = "output"     // writing files and reports
+	CategoryCheck      Category = "check"      // failed validate, lint, diff and roundtrip runs
+)
+
+// Entry documents one SchemerErr code.
+type Entry struct {
+	Code     string   `json:"code"`
+	Category Category `json:"category"`
+	Summary  string   `json:"summary"` // what went wrong, one sentence
+	Cause    string   `json:"cause"`   // the likely reason
+	Remedy   string   `json:"remedy"`  // what to do about it
+}
+
+// Catalog holds every SchemerErr code in use, ordered by code. A code is never reused
+// once it is removed from the tree; TestCatalog keeps this list and the tree in sync.
+var Catalog = []Entry{
+	{
+		Code:     "0001",
+		Category: CategoryUsage,
+		Summary:  "No connection given.",
+		Cause:    "Neither --conn-key nor --conn-string was passed and the config file sets neither.",
+		Remedy:   "Pass --conn-string, or --conn-key with the name of an environment variable holding the connection string, or set conn-key in schemer.yaml.",
+	},
+	{
+		Code:     "0002",
+		Category: CategoryConfig,
+		Summary:  "The connection environment variable is not set.",
+		Cause:    "The variable named by --conn-key is missing from the environment and the .env file.",
+		Remedy:   "Export the variable or add it to .env in the project root, or pass a different --conn-key.",
+	},
+	{
+		Code:     "0003",
+		Category: CategoryUsage,
+		Summary:  "--from/--to combined with --cherry-pick.",
+		Cause:    "A range and a list of tags were requested at the same time.",
+		Remedy:   "Use either --from/--to or --cherry-pick.",
+	},
+	{
+		Code:     "0004",
+		Category: CategoryTracking,
+		Summary:  "No database handle to create the tracking table with.",
+		Cause:    "Internal error: the tracking table was created without an open connection.",
+		Remedy:   "Report the command that failed as a bug.",
+	},
+	{
+		Code:     "0005",
+		Category: CategoryDeltas,
+		Summary:  "schemer.sql could not be read.",
+		Cause:    "The deltas directory has no readable schemer.sql, the template of the tracking table.",
+		Remedy:   "Run schemer init or restore deltas/schemer.sql, and check the file permissions.",
+	},
+	{
+		Code:     "0006",
+		Category: CategoryTracking,
+		Summary:  "Checking for the tracking table failed.",
+		Cause:    "The query looking up the tracking table in the database failed.",
+		Remedy:   "Check the wrapped database error, usually missing privileges on the schema.",
+	},
+	{
+		Code:     "0007",
+		Category: CategoryTracking,
+		Summary:  "The tracking table could not be created.",
+		Cause:    "Executing schemer.sql failed.",
+		Remedy:   "Check the wrapped database error and that the role may create tables in the schema.",
+	},
+	{
+		Code:     "0008",
+		Category: CategoryConnection,
+		Summary:  "The database could not be reached.",
+		Cause:    "The server refused or did not answer the connection.",
+		Remedy:   "Check the connection string, that the server is running and reachable, and the credentials. --connect-timeout limits how long to wait.",
+	},
+	{
+		Code:     "0009",
+		Category: CategoryConnection,
+		Summary:  "The database could not be opened.",
+		Cause:    "The connection string could not be used to open a database handle.",
+		Remedy:   "Check the connection string.",
+	},
+	{
+		Code:     "0010",
+		Category: CategoryConnection,
+		Summary:  "The database did not answer a ping.",
+		Cause:    "The server could not be reached with the given connection string.",
+		Remedy:   "Check that the server is running and reachable and the credentials are correct.",
+	},
+	{
+		Code:     "0011",
+		Category: CategoryConfig,
+		Summary:  "The current working directory is unavailable.",
+		Cause:    "The directory schemer was started in was removed or is not accessible.",
+		Remedy:   "Run schemer from an existing directory, or pass --project-dir.",
+	},
+	{
+		Code:     "0012",
+		Category: CategoryConfig,
+		Summary:  "The .env file could not be loaded.",
+		Cause:    "The .env file in the project root exists but is unreadable or malformed.",
+		Remedy:   "Fix the syntax of .env, one KEY=value per line, or its permissions.",
+	},
+	{
+		Code:     "0013",
+		Category: CategoryOutput,
+		Summary:  "The .env template could not be parsed.",
+		Cause:    "Internal error in the template used by schemer init.",
+		Remedy:   "Report it as a bug.",
+	},
+	{
+		Code:     "0014",
+		Category: CategoryOutput,
+		Summary:  "The .env file could not be created.",
+		Cause:    "schemer init could not create .env in the project root.",
+		Remedy:   "Check the permissions of the project root.",
+	},
+	{
+		Code:     "0015",
+		Category: CategoryOutput,
+		Summary:  "The .env file could not be written.",
+		Cause:    "Writing the rendered template to .env failed.",
+		Remedy:   "Check the free disk space and the permissions of .env.",
+	},
+	{
+		Code:     "0016",
+		Category: CategoryOutput,
+		Summary:  "The schemer.sql template arguments are invalid.",
+		Cause:    "The tracking table name passed to the template is not usable.",
+		Remedy:   "Use a table name made of letters, digits and underscores.",
+	},
+	{
+		Code:     "0017",
+		Category: CategoryOutput,
+		Summary:  "schemer.sql could not be created.",
+		Cause:    "schemer init could not create schemer.sql in the deltas directory.",
+		Remedy:   "Check the permissions of the deltas directory.",
+	},
+	{
+		Code:     "0018",
+		Category: CategoryOutput,
+		Summary:  "The schemer.sql template could not be parsed.",
+		Cause:    "Internal error in the template used by schemer init.",
+		Remedy:   "Report it as a bug.",
+	},
+	{
+		Code:     "0019",
+		Category: CategoryUsage,
+		Summary:  "The tracking table name contains an illegal character.",
+		Cause:    "The table name passed to schemer init is not a plain identifier.",
+		Remedy:   "Use letters, digits and underscores only.",
+	},
+	{
+		Code:     "0020",
+		Category: CategoryTracking,
+		Summary:  "No connection to read the applied deltas with.",
+		Cause:    "Internal error: the applied deltas were read without an open connection.",
+		Remedy:   "Report the command that failed as a bug.",
+	},
+	{
+		Code:     "0021",
+		Category: CategoryTracking,
+		Summary:  "The tracking table does not exist.",
+		Cause:    "The database was never initialized by schemer, --table names another table, or the connection points to the wrong database or schema.",
+		Remedy:   "Run schemer up once to create the tracking tables, or check --table, --conn-key and the search_path of the role.",
+	},
+	{
+		Code:     "0022",
+		Category: CategoryTracking,
+		Summary:  "Reading the applied deltas failed.",
+		Cause:    "The query on the tracking table failed.",
+		Remedy:   "Check the wrapped database error and the privileges of the role on the tracking table.",
+	},
+	{
+		Code:     "0023",
+		Category: CategoryTracking,
+		Summary:  "An applied delta could not be read.",
+		Cause:    "A row of the tracking table does not match the expected columns.",
+		Remedy:   "Check that the tracking table was created by schemer and not changed by hand.",
+	},
+	{
+		Code:     "0024",
+		Category: CategoryTracking,
+		Summary:  "Reading the applied deltas was interrupted.",
+		Cause:    "The connection failed while the rows of the tracking table were read.",
+		Remedy:   "Retry, and check the wrapped error.",
+	},
+	{
+		Code:     "0025",
+		Category: CategoryUsage,
+		Summary:  "A --cherry-pick value is not a tag.",
+		Cause:    "A value is neither a number nor a label defined in labels.txt.",
+		Remedy:   "Pass tags such as 4 or 004, or labels from labels.txt.",
+	},
+	{
+		Code:     "0026",
+		Category: CategoryUsage,
+		Summary:  "The --to value is not a tag.",
+		Cause:    "The value is neither a number nor a label defined in labels.txt.",
+		Remedy:   "Pass a tag such as 4 or 004, or a label from labels.txt.",
+	},
+	{
+		Code:     "0027",
+		Category: CategoryUsage,
+		Summary:  "The --from value is not a tag.",
+		Cause:    "The value is neither a number nor a label defined in labels.txt.",
+		Remedy:   "Pass a tag such as 4 or 004, or a label from labels.txt.",
+	},
+	{
+		Code:     "0028",
+		Category: CategoryExecution,
+		Summary:  "A down delta failed.",
+		Cause:    "PostgreSQL rejected the statements of the down delta. The deltas reverted before it are recorded.",
+		Remedy:   "Read the wrapped database error, fix the down delta and resolve the dirty marker with schemer repair.",
+	},
+	{
+		Code:     "0029",
+		Category: CategoryTracking,
+		Summary:  "A reverted delta could not be removed from the tracking table.",
+		Cause:    "The down delta ran but removing its tag failed, so the change was not recorded.",
+		Remedy:   "Check the wrapped database error. The delta keeps its dirty marker; inspect the database and resolve it with schemer repair.",
+	},
+	{
+		Code:     "0035",
+		Category: CategoryExecution,
+		Summary:  "No delta has been applied yet.",
+		Cause:    "down without options reverts the last applied delta, and the tracking table is empty.",
+		Remedy:   "Nothing to revert. Pass --from, --to or --cherry-pick to revert specific tags.",
+	},
+	{
+		Code:     "0036",
+		Category: CategoryDeltas,
+		Summary:  "The last applied delta has no down delta.",
+		Cause:    "The down file of the highest applied tag is missing.",
+		Remedy:   "Add the down delta for that tag, or revert a specific tag with --cherry-pick.",
+	},
+	{
+		Code:     "0037",
+		Category: CategoryExecution,
+		Summary:  "The down delta of the last applied delta failed.",
+		Cause:    "PostgreSQL rejected the statements of the down delta.",
+		Remedy:   "Read the wrapped database error, fix the down delta and resolve the dirty marker with schemer repair.",
+	},
+	{
+		Code:     "0039",
+		Category: CategoryTracking,
+		Summary:  "Reading the post statuses failed.",
+		Cause:    "The query on the tracking table failed.",
+		Remedy:   "Check the wrapped database error and the privileges of the role.",
+	},
+	{
+		Code:     "0041",
+		Category: CategoryTracking,
+		Summary:  "A post status could not be read.",
+		Cause:    "A row of the tracking table does not match the expected columns.",
+		Remedy:   "Check that the tracking table was created by schemer and not changed by hand.",
+	},
+	{
+		Code:     "0042",
+		Category: CategoryTracking,
+		Summary:  "Reading the post statuses was interrupted.",
+		Cause:    "The connection failed while the rows of the tracking table were read.",
+		Remedy:   "Retry, and check the wrapped error.",
+	},
+	{
+		Code:     "0047",
+		Category: CategoryExecution,
+		Summary:  "A post delta failed.",
+		Cause:    "PostgreSQL rejected the statements of the post delta.",
+		Remedy:   "Read the wrapped database error, fix the post delta and resolve the dirty marker with schemer repair.",
+	},
+	{
+		Code:     "0048",
+		Category: CategoryTracking,
+		Summary:  "An applied post delta could not be recorded.",
+		Cause:    "The post delta ran but its post status could not be updated.",
+		Remedy:   "Check the wrapped database error. The delta keeps its dirty marker; inspect the database and resolve it with schemer repair.",
+	},
+	{
+		Code:     "0052",
+		Category: CategoryExecution,
+		Summary:  "Nothing to apply.",
+		Cause:    "up --exit-code-nothing-to-do was given and every requested delta is already recorded as applied.",
+		Remedy:   "Nothing to do. Create a new delta with schemer create, or check the tags passed to --from, --to and --cherry-pick.",
+	},
+	{
+		Code:     "0053",
+		Category: CategoryExecution,
+		Summary:  "An up delta failed.",
+		Cause:    "PostgreSQL rejected the statements of the up delta. The deltas applied before it are recorded.",
+		Remedy:   "Read the wrapped database error, fix the delta and resolve the dirty marker with schemer repair before running up again.",
+	},
+	{
+		Code:     "0054",
+		Category: CategoryTracking,
+		Summary:  "An applied delta could not be recorded.",
+		Cause:    "The up delta ran but its tag could not be added to the tracking table.",
+		Remedy:   "Check the wrapped database error. The delta keeps its dirty marker; inspect the database and resolve it with schemer repair.",
+	},
+	{
+		Code:     "0057",
+		Category: CategoryDeltas,
+		Summary:  "The up delta to create already exists.",
+		Cause:    "A file with the generated name is already in the deltas directory.",
+		Remedy:   "Pick another name, or remove the existing file.",
+	},
+	{
+		Code:     "0058",
+		Category: CategoryDeltas,
+		Summary:  "The down delta to create already exists.",
+		Cause:    "A file with the generated name is already in the deltas directory.",
+		Remedy:   "Pick another name, or remove the existing file.",
+	},
+	{
+		Code:     "0059",
+		Category: CategoryOutput,
+		Summary:  "The up delta could not be created.",
+		Cause:    "Creating the file in the deltas directory failed.",
+		Remedy:   "Check the permissions of the deltas directory.",
+	},
+	{
+		Code:     "0060",
+		Category: CategoryOutput,
+		Summary:  "The down delta could not be created.",
+		Cause:    "Creating the file in the deltas directory failed.",
+		Remedy:   "Check the permissions of the deltas directory.",
+	},
+	{
+		Code:     "0061",
+		Category: CategoryDeltas,
+		Summary:  "The post delta to create already exists.",
+		Cause:    "A file with the generated name is already in the deltas directory.",
+		Remedy:   "Pick another name, or remove the existing file.",
+	},
+	{
+		Code:     "0062",
+		Category: CategoryOutput,
+		Summary:  "The post delta could not be created.",
+		Cause:    "Creating the file in the deltas directory failed.",
+		Remedy:   "Check the permissions of the deltas directory.",
+	},
+	{
+		Code:     "0063",
+		Category: CategoryOutput,
+		Summary:  "The deltas directory could not be created.",
+		Cause:    "schemer init could not create the directory.",
+		Remedy:   "Check the permissions of the project root.",
+	},
+	{
+		Code:     "0064",
+		Category: CategoryConfig,
+		Summary:  "No working directory to initialize.",
+		Cause:    "Internal error: schemer init got an empty project root.",
+		Remedy:   "Report it as a bug.",
+	},
+	{
+		Code:     "0065",
+		Category: CategoryConfig,
+		Summary:  ".env is a directory.",
+		Cause:    "schemer init writes the connection settings to a .env file, but a directory has that name.",
+		Remedy:   "Rename or remove the .env directory.",
+	},
+	{
+		Code:     "0066",
+		Category: CategoryConfig,
+		Summary:  "No deltas directory to create.",
+		Cause:    "Internal error: schemer init got an empty deltas directory path.",
+		Remedy:   "Report it as a bug.",
+	},
+	{
+		Code:     "0068",
+		Category: CategoryTracking,
+		Summary:  "No connection to create the dirty table with.",
+		Cause:    "Internal error: the dirty table was created without an open connection.",
+		Remedy:   "Report the command that failed as a bug.",
+	},
+	{
+		Code:     "0069",
+		Category: CategoryTracking,
+		Summary:  "The dirty table could not be created.",
+		Cause:    "Creating <table>_dirty failed.",
+		Remedy:   "Check the wrapped database error and that the role may create tables.",
+	},
+	{
+		Code:     "0070",
+		Category: CategoryTracking,
+		Summary:  "Reading the dirty markers failed.",
+		Cause:    "The query on <table>_dirty failed.",
+		Remedy:   "Check the wrapped database error and the privileges of the role.",
+	},
+	{
+		Code:     "0071",
+		Category: CategoryTracking,
+		Summary:  "A dirty marker could not be read.",
+		Cause:    "A row of <table>_dirty does not match the expected columns.",
+		Remedy:   "Check that the table was created by schemer and not changed by hand.",
+	},
+	{
+		Code:     "0072",
+		Category: CategoryTracking,
+		Summary:  "Reading the dirty markers was interrupted.",
+		Cause:    "The connection failed while the rows of <table>_dirty were read.",
+		Remedy:   "Retry, and check the wrapped error.",
+	},
+	{
+		Code:     "0073",
+		Category: CategoryExecution,
+		Summary:  "The database is dirty.",
+		Cause:    "A previous run stopped in the middle of a delta, so the schema may be half changed.",
+		Remedy:   "Inspect the database, then resolve each delta with schemer repair --mark-applied or --mark-rolled-back.",
+	},
+	{
+		Code:     "0074",
+		Category: CategoryTracking,
+		Summary:  "The dirty marker could not be written.",
+		Cause:    "Recording the delta about to run in <table>_dirty failed, so it was not executed.",
+		Remedy:   "Check the wrapped database error and the privileges of the role.",
+	},
+	{
+		Code:     "0075",
+		Category: CategoryTracking,
+		Summary:  "The error could not be recorded on the dirty marker.",
+		Cause:    "The delta failed and its error could not be stored in <table>_dirty.",
+		Remedy:   "Check the wrapped database error. The marker still blocks further runs until it is repaired.",
+	},
+	{
+		Code:     "0076",
+		Category: CategoryTracking,
+		Summary:  "The dirty marker could not be cleared.",
+		Cause:    "The delta succeeded but its marker could not be removed from <table>_dirty.",
+		Remedy:   "Check the wrapped database error, then resolve it with schemer repair --mark-applied.",
+	},
+	{
+		Code:     "0077",
+		Category: CategoryUsage,
+		Summary:  "--mark-applied combined with --mark-rolled-back.",
+		Cause:    "A dirty delta can only be resolved one way.",
+		Remedy:   "Pass only one of the flags.",
+	},
+	{
+		Code:     "0078",
+		Category: CategoryTracking,
+		Summary:  "A dirty marker has an unknown direction.",
+		Cause:    "The direction column of <table>_dirty holds something other than up, down or post.",
+		Remedy:   "Check the table for manual changes and fix or delete the row.",
+	},
+	{
+		Code:     "0079",
+		Category: CategoryTracking,
+		Summary:  "The repair could not update the tracking table.",
+		Cause:    "Recording the resolved delta failed.",
+		Remedy:   "Check the wrapped database error and retry schemer repair.",
+	},
+	{
+		Code:     "0080",
+		Category: CategoryTracking,
+		Summary:  "No connection to create the history table with.",
+		Cause:    "Internal error: the history table was created without an open connection.",
+		Remedy:   "Report the command that failed as a bug.",
+	},
+	{
+		Code:     "0081",
+		Category: CategoryTracking,
+		Summary:  "The history table could not be created.",
+		Cause:    "Creating <table>_history failed.",
+		Remedy:   "Check the wrapped database error and that the role may create tables.",
+	},
+	{
+		Code:     "0082",
+		Category: CategoryTracking,
+		Summary:  "A history entry could not be recorded.",
+		Cause:    "Appending to <table>_history failed. The delta itself was not affected.",
+		Remedy:   "Check the wrapped database error and the privileges of the role.",
+	},
+	{
+		Code:     "0083",
+		Category: CategoryTracking,
+		Summary:  "Reading the history failed.",
+		Cause:    "The query on <table>_history failed.",
+		Remedy:   "Check the wrapped database error and the privileges of the role.",
+	},
+	{
+		Code:     "0084",
+		Category: CategoryTracking,
+		Summary:  "A history entry could not be read.",
+		Cause:    "A row of <table>_history does not match the expected columns.",
+		Remedy:   "Check that the table was created by schemer and not changed by hand.",
+	},
+	{
+		Code:     "0085",
+		Category: CategoryTracking,
+		Summary:  "Reading the history was interrupted.",
+		Cause:    "The connection failed while the rows of <table>_history were read.",
+		Remedy:   "Retry, and check the wrapped error.",
+	},
+	{
+		Code:     "0086",
+		Category: CategoryUsage,
+		Summary:  "The --tag value is not a tag.",
+		Cause:    "The value is neither a number nor a label defined in labels.txt.",
+		Remedy:   "Pass a tag such as 4 or 004, or a label from labels.txt.",
+	},
+	{
+		Code:     "0087",
+		Category: CategoryUsage,
+		Summary:  "Unknown --direction.",
+		Cause:    "The history direction filter is not one of the recorded directions.",
+		Remedy:   "Use up, down, post, baseline, mark, seed, repeatable or skip.",
+	},
+	{
+		Code:     "0088",
+		Category: CategoryUsage,
+		Summary:  "Invalid --since or --until date.",
+		Cause:    "The value is neither a date nor an RFC3339 timestamp.",
+		Remedy:   "Use 2006-01-02 or 2006-01-02T15:04:05Z.",
+	},
+	{
+		Code:     "0089",
+		Category: CategoryExecution,
+		Summary:  "Pending deltas are older than the highest applied delta.",
+		Cause:    "Usually a branch with older tags was merged after a newer delta was applied.",
+		Remedy:   "Renumber the pending deltas with schemer renumber, or pass --allow-out-of-order if they are independent.",
+	},
+	{
+		Code:     "0090",
+		Category: CategoryDeltas,
+		Summary:  "A path in the deltas directory could not be read.",
+		Cause:    "Walking the deltas directory failed, e.g. on a broken link or missing permission.",
+		Remedy:   "Check the path in the message.",
+	},
+	{
+		Code:     "0091",
+		Category: CategoryDeltas,
+		Summary:  "A delta file could not be read.",
+		Cause:    "Reading the file for validation failed.",
+		Remedy:   "Check the permissions of the file.",
+	},
+	{
+		Code:     "0092",
+		Category: CategoryDeltas,
+		Summary:  "A path in the deltas directory could not be read.",
+		Cause:    "Walking the deltas directory for lint failed.",
+		Remedy:   "Check the path in the message.",
+	},
+	{
+		Code:     "0093",
+		Category: CategoryDeltas,
+		Summary:  "A file could not be read for lint.",
+		Cause:    "The file passed to lint, or found in the deltas directory, is unreadable.",
+		Remedy:   "Check the path and the permissions of the file.",
+	},
+	{
+		Code:     "0094",
+		Category: CategoryUsage,
+		Summary:  "Invalid --rule.",
+		Cause:    "The override is not of the form <rule>=<off|warning|error>.",
+		Remedy:   "Use e.g. --rule lock-table=off.",
+	},
+	{
+		Code:     "0095",
+		Category: CategoryUsage,
+		Summary:  "Unknown lint rule.",
+		Cause:    "--rule names a rule lint does not have.",
+		Remedy:   "Run schemer lint --help for the list of rules.",
+	},
+	{
+		Code:     "0096",
+		Category: CategoryUsage,
+		Summary:  "Unknown lint --format.",
+		Cause:    "The report format is not supported.",
+		Remedy:   "Use text, json or sarif.",
+	},
+	{
+		Code:     "0097",
+		Category: CategoryOutput,
+		Summary:  "The lint report could not be written.",
+		Cause:    "Writing to stdout failed.",
+		Remedy:   "Check where stdout is redirected to.",
+	},
+	{
+		Code:     "0107",
+		Category: CategoryOutput,
+		Summary:  "The snapshot directory could not be created.",
+		Cause:    "The directory of the --snapshot or --file path could not be created.",
+		Remedy:   "Check the path and the permissions of its parent.",
+	},
+	{
+		Code:     "0108",
+		Category: CategoryOutput,
+		Summary:  "The snapshot could not be written.",
+		Cause:    "Writing the snapshot file failed.",
+		Remedy:   "Check the path, the permissions and the free disk space.",
+	},
+	{
+		Code:     "0109",
+		Category: CategorySchema,
+		Summary:  "Not a schemer snapshot.",
+		Cause:    "The file does not start with the header written by schemer dump.",
+		Remedy:   "Pass a file written by schemer dump, or a connection string.",
+	},
+	{
+		Code:     "0110",
+		Category: CategorySchema,
+		Summary:  "The snapshot is empty or unreadable.",
+		Cause:    "The snapshot file has no content.",
+		Remedy:   "Write it again with schemer dump.",
+	},
+	{
+		Code:     "0111",
+		Category: CategorySchema,
+		Summary:  "The snapshot could not be read.",
+		Cause:    "Opening the snapshot file failed.",
+		Remedy:   "Check the path and the permissions of the file.",
+	},
+	{
+		Code:     "0112",
+		Category: CategorySchema,
+		Summary:  "The snapshot is invalid.",
+		Cause:    "The snapshot file could not be parsed.",
+		Remedy:   "Write it again with schemer dump.",
+	},
+	{
+		Code:     "0113",
+		Category: CategorySchema,
+		Summary:  "The extensions of the schema could not be read.",
+		Cause:    "The catalog query for extensions failed while dumping or diffing a database.",
+		Remedy:   "Check the wrapped database error and that the role may read pg_catalog.",
+	},
+	{
+		Code:     "0114",
+		Category: CategorySchema,
+		Summary:  "The enum types of the schema could not be read.",
+		Cause:    "The catalog query for enum types failed while dumping or diffing a database.",
+		Remedy:   "Check the wrapped database error and that the role may read pg_catalog.",
+	},
+	{
+		Code:     "0115",
+		Category: CategorySchema,
+		Summary:  "The sequences of the schema could not be read.",
+		Cause:    "The catalog query for sequences failed while dumping or diffing a database.",
+		Remedy:   "Check the wrapped database error and that the role may read pg_catalog.",
+	},
+	{
+		Code:     "0116",
+		Category: CategorySchema,
+		Summary:  "The tables of the schema could not be read.",
+		Cause:    "The catalog query for tables failed while dumping or diffing a database.",
+		Remedy:   "Check the wrapped database error and that the role may read pg_catalog.",
+	},
+	{
+		Code:     "0117",
+		Category: CategorySchema,
+		Summary:  "The columns of the schema could not be read.",
+		Cause:    "The catalog query for columns failed while dumping or diffing a database.",
+		Remedy:   "Check the wrapped database error and that the role may read pg_catalog.",
+	},
+	{
+		Code:     "0118",
+		Category: CategorySchema,
+		Summary:  "The constraints of the schema could not be read.",
+		Cause:    "The catalog query for constraints failed while dumping or diffing a database.",
+		Remedy:   "Check the wrapped database error and that the role may read pg_catalog.",
+	},
+	{
+		Code:     "0119",
+		Category: CategorySchema,
+		Summary:  "The indexes of the schema could not be read.",
+		Cause:    "The catalog query for indexes failed while dumping or diffing a database.",
+		Remedy:   "Check the wrapped database error and that the role may read pg_catalog.",
+	},
+	{
+		Code:     "0120",
+		Category: CategorySchema,
+		Summary:  "The views of the schema could not be read.",
+		Cause:    "The catalog query for views failed while dumping or diffing a database.",
+		Remedy:   "Check the wrapped database error and that the role may read pg_catalog.",
+	},
+	{
+		Code:     "0121",
+		Category: CategorySchema,
+		Summary:  "The functions of the schema could not be read.",
+		Cause:    "The catalog query for functions failed while dumping or diffing a database.",
+		Remedy:   "Check the wrapped database error and that the role may read pg_catalog.",
+	},
+	{
+		Code:     "0122",
+		Category: CategoryOutput,
+		Summary:  "The snapshot could not be written to stdout.",
+		Cause:    "Writing to stdout failed.",
+		Remedy:   "Check where stdout is redirected to, or use --file.",
+	},
+	{
+		Code:     "0123",
+		Category: CategoryUsage,
+		Summary:  "--source or --target is missing.",
+		Cause:    "diff compares two schemas and needs both sides.",
+		Remedy:   "Pass both --source and --target.",
+	},
+	{
+		Code:     "0124",
+		Category: CategoryUsage,
+		Summary:  "A diff side could not be resolved.",
+		Cause:    "The value is not an existing snapshot file, a connection string or a set environment variable.",
+		Remedy:   "Check the path, or export the environment variable.",
+	},
+	{
+		Code:     "0125",
+		Category: CategoryUsage,
+		Summary:  "Unknown diff --format.",
+		Cause:    "The report format is not supported.",
+		Remedy:   "Use text or json.",
+	},
+	{
+		Code:     "0126",
+		Category: CategoryOutput,
+		Summary:  "The diff report could not be written.",
+		Cause:    "Writing to stdout failed.",
+		Remedy:   "Check where stdout is redirected to.",
+	},
+	{
+		Code:     "0127",
+		Category: CategoryOutput,
+		Summary:  "The diff report could not be encoded.",
+		Cause:    "Internal error while encoding the JSON report.",
+		Remedy:   "Report it as a bug.",
+	},
+	{
+		Code:     "0128",
+		Category: CategoryConnection,
+		Summary:  "No scratch database name could be generated.",
+		Cause:    "Reading random bytes from the operating system failed.",
+		Remedy:   "Retry, and check the wrapped error.",
+	},
+	{
+		Code:     "0129",
+		Category: CategoryConnection,
+		Summary:  "The scratch database could not be created.",
+		Cause:    "The role lacks the CREATEDB privilege, or the --template database has open connections.",
+		Remedy:   "Grant CREATEDB, or close the connections to the template.",
+	},
+	{
+		Code:     "0130",
+		Category: CategoryConnection,
+		Summary:  "A scratch database could not be dropped.",
+		Cause:    "DROP DATABASE failed, usually because of missing privileges.",
+		Remedy:   "Drop it by hand or with a role owning the database.",
+	},
+	{
+		Code:     "0131",
+		Category: CategoryUsage,
+		Summary:  "Invalid server connection string.",
+		Cause:    "The admin connection string for scratch databases could not be parsed.",
+		Remedy:   "Check the connection string.",
+	},
+	{
+		Code:     "0132",
+		Category: CategoryUsage,
+		Summary:  "--scratch is required.",
+		Cause:    "--from-diff names a snapshot file, so schemer needs a server to build the current schema on.",
+		Remedy:   "Pass --scratch with a connection string or environment key of a server where databases can be created.",
+	},
+	{
+		Code:     "0133",
+		Category: CategoryOutput,
+		Summary:  "The round-trip report could not be written.",
+		Cause:    "Writing to stdout failed.",
+		Remedy:   "Check where stdout is redirected to.",
+	},
+	{
+		Code:     "0134",
+		Category: CategoryOutput,
+		Summary:  "The JUnit report could not be encoded.",
+		Cause:    "Internal error while encoding the XML report.",
+		Remedy:   "Report it as a bug.",
+	},
+	{
+		Code:     "0135",
+		Category: CategoryOutput,
+		Summary:  "The JUnit report could not be written.",
+		Cause:    "Writing the --junit file failed.",
+		Remedy:   "Check the path and its permissions.",
+	},
+	{
+		Code:     "0136",
+		Category: CategoryExecution,
+		Summary:  "The scratch database could not be set up.",
+		Cause:    "Applying the deltas to the new scratch database failed. The database was dropped.",
+		Remedy:   "Read the wrapped error, usually a failing delta.",
+	},
+	{
+		Code:     "0137",
+		Category: CategoryConnection,
+		Summary:  "The scratch databases could not be listed.",
+		Cause:    "Querying pg_database on the server failed.",
+		Remedy:   "Check the wrapped database error and the connection string.",
+	},
+	{
+		Code:     "0138",
+		Category: CategoryUsage,
+		Summary:  "Not a scratch database.",
+		Cause:    "scratch drop only drops databases named by schemer scratch create.",
+		Remedy:   "Pass a name starting with the scratch prefix, or drop the database by hand.",
+	},
+	{
+		Code:     "0139",
+		Category: CategoryUsage,
+		Summary:  "No scratch database to drop.",
+		Cause:    "scratch drop got neither a database nor --all.",
+		Remedy:   "Pass the names or connection strings of the databases, or --all.",
+	},
+	{
+		Code:     "0140",
+		Category: CategoryUsage,
+		Summary:  "Invalid scratch connection string.",
+		Cause:    "An argument of scratch drop looks like a connection string but could not be parsed.",
+		Remedy:   "Pass the database name or the connection string printed by scratch create.",
+	},
+	{
+		Code:     "0141",
+		Category: CategoryOutput,
+		Summary:  "Could not write to stdout.",
+		Cause:    "Writing the scratch database to stdout failed.",
+		Remedy:   "Check where stdout is redirected to, or use --file.",
+	},
+	{
+		Code:     "0142",
+		Category: CategoryOutput,
+		Summary:  "The connection string could not be written.",
+		Cause:    "Writing the --file of scratch create failed.",
+		Remedy:   "Check the path and its permissions.",
+	},
+	{
+		Code:     "0143",
+		Category: CategoryTracking,
+		Summary:  "No connection to create the seeds table with.",
+		Cause:    "Internal error: the seeds table was created without an open connection.",
+		Remedy:   "Report the command that failed as a bug.",
+	},
+	{
+		Code:     "0144",
+		Category: CategoryTracking,
+		Summary:  "The seeds table could not be created.",
+		Cause:    "Creating <table>_seeds failed.",
+		Remedy:   "Check the wrapped database error and that the role may create tables.",
+	},
+	{
+		Code:     "0145",
+		Category: CategoryTracking,
+		Summary:  "Reading the applied seeds failed.",
+		Cause:    "The query on <table>_seeds failed.",
+		Remedy:   "Check the wrapped database error and the privileges of the role.",
+	},
+	{
+		Code:     "0146",
+		Category: CategoryTracking,
+		Summary:  "An applied seed could not be read.",
+		Cause:    "A row of <table>_seeds does not match the expected columns.",
+		Remedy:   "Check that the table was created by schemer and not changed by hand.",
+	},
+	{
+		Code:     "0148",
+		Category: CategoryTracking,
+		Summary:  "A seed could not be recorded.",
+		Cause:    "Storing the checksum in <table>_seeds failed, so the seed was rolled back.",
+		Remedy:   "Check the wrapped database error and retry.",
+	},
+	{
+		Code:     "0149",
+		Category: CategoryTracking,
+		Summary:  "Reading the applied seeds was interrupted.",
+		Cause:    "The connection failed while the rows of <table>_seeds were read.",
+		Remedy:   "Retry, and check the wrapped error.",
+	},
+	{
+		Code:     "0150",
+		Category: CategoryUsage,
+		Summary:  "Refusing to seed production.",
+		Cause:    "The active environment looks like production.",
+		Remedy:   "Pass --allow-production if seeding it is really intended.",
+	},
+	{
+		Code:     "0151",
+		Category: CategoryUsage,
+		Summary:  "Invalid seed environment.",
+		Cause:    "The environment is not a plain folder name in the seeds directory.",
+		Remedy:   "Select a profile such as dev with --env, matching a folder in seeds/.",
+	},
+	{
+		Code:     "0152",
+		Category: CategoryDeltas,
+		Summary:  "The seeds directory could not be read.",
+		Cause:    "Listing seeds/ or the folder of the environment failed.",
+		Remedy:   "Check that the directory exists and is readable.",
+	},
+	{
+		Code:     "0153",
+		Category: CategoryDeltas,
+		Summary:  "A seed file could not be read.",
+		Cause:    "Reading the file failed.",
+		Remedy:   "Check the permissions of the file.",
+	},
+	{
+		Code:     "0154",
+		Category: CategoryExecution,
+		Summary:  "A seed failed.",
+		Cause:    "PostgreSQL rejected the statements of the seed. Its transaction was rolled back.",
+		Remedy:   "Read the wrapped database error and fix the seed file.",
+	},
+	{
+		Code:     "0155",
+		Category: CategoryTracking,
+		Summary:  "No connection to create the repeatable table with.",
+		Cause:    "Internal error: the repeatable table was created without an open connection.",
+		Remedy:   "Report the command that failed as a bug.",
+	},
+	{
+		Code:     "0156",
+		Category: CategoryTracking,
+		Summary:  "The repeatable table could not be created.",
+		Cause:    "Creating <table>_repeatable failed.",
+		Remedy:   "Check the wrapped database error and that the role may create tables.",
+	},
+	{
+		Code:     "0157",
+		Category: CategoryTracking,
+		Summary:  "Reading the applied repeatable deltas failed.",
+		Cause:    "The query on <table>_repeatable failed.",
+		Remedy:   "Check the wrapped database error and the privileges of the role.",
+	},
+	{
+		Code:     "0158",
+		Category: CategoryTracking,
+		Summary:  "An applied repeatable delta could not be read.",
+		Cause:    "A row of <table>_repeatable does not match the expected columns.",
+		Remedy:   "Check that the table was created by schemer and not changed by hand.",
+	},
+	{
+		Code:     "0159",
+		Category: CategoryTracking,
+		Summary:  "Reading the applied repeatable deltas was interrupted.",
+		Cause:    "The connection failed while the rows of <table>_repeatable were read.",
+		Remedy:   "Retry, and check the wrapped error.",
+	},
+	{
+		Code:     "0160",
+		Category: CategoryTracking,
+		Summary:  "A repeatable delta could not be recorded.",
+		Cause:    "Storing the checksum in <table>_repeatable failed, so the delta was rolled back.",
+		Remedy:   "Check the wrapped database error and retry.",
+	},
+	{
+		Code:     "0161",
+		Category: CategoryDeltas,
+		Summary:  "A path in the deltas directory could not be read.",
+		Cause:    "Walking the deltas directory for repeatable deltas failed.",
+		Remedy:   "Check the path in the message.",
+	},
+	{
+		Code:     "0162",
+		Category: CategoryDeltas,
+		Summary:  "A repeatable delta could not be read.",
+		Cause:    "Reading the R_ file failed.",
+		Remedy:   "Check the permissions of the file.",
+	},
+	{
+		Code:     "0163",
+		Category: CategoryExecution,
+		Summary:  "A repeatable delta failed.",
+		Cause:    "PostgreSQL rejected the statements of the repeatable delta. Its transaction was rolled back.",
+		Remedy:   "Read the wrapped database error and fix the file.",
+	},
+	{
+		Code:     "0164",
+		Category: CategoryOutput,
+		Summary:  "The status could not be written.",
+		Cause:    "Writing to stdout failed.",
+		Remedy:   "Check where stdout is redirected to.",
+	},
+	{
+		Code:     "0165",
+		Category: CategoryConfig,
+		Summary:  "The current working directory is unavailable.",
+		Cause:    "The directory schemer was started in was removed or is not accessible.",
+		Remedy:   "Run schemer from an existing directory, or pass --project-dir.",
+	},
+	{
+		Code:     "0166",
+		Category: CategoryConfig,
+		Summary:  "The config file could not be read.",
+		Cause:    "The file is unreadable or not valid YAML.",
+		Remedy:   "Fix the YAML syntax, or point --config at the right file.",
+	},
+	{
+		Code:     "0167",
+		Category: CategoryUsage,
+		Summary:  "Unknown profile.",
+		Cause:    "--env or SCHEMER_PROFILE names a profile the config file does not define.",
+		Remedy:   "Use one of the profiles listed in the message, or add it to the profiles section.",
+	},
+	{
+		Code:     "0168",
+		Category: CategoryUsage,
+		Summary:  "Invalid flag value from the environment or config file.",
+		Cause:    "A SCHEMER_* variable or config key holds a value the flag does not accept.",
+		Remedy:   "Fix the value named in the message, e.g. true or false for boolean flags.",
+	},
+	{
+		Code:     "0169",
+		Category: CategoryUsage,
+		Summary:  "Invalid tracking table name.",
+		Cause:    "--table or the table config key is not a plain identifier.",
+		Remedy:   "Use letters, digits and underscores only.",
+	},
+	{
+		Code:     "0170",
+		Category: CategoryUsage,
+		Summary:  "The profile is protected.",
+		Cause:    "down, repair and seed are refused against a profile with protected: true.",
+		Remedy:   "Repeat the profile name with --confirm, e.g. --env prod --confirm prod.",
+	},
+	{
+		Code:     "0171",
+		Category: CategoryUsage,
+		Summary:  "Invalid project directory.",
+		Cause:    "--project-dir or SCHEMER_PROJECT_DIR does not name an existing directory.",
+		Remedy:   "Pass the directory holding schemer.yaml or deltas/.",
+	},
+	{
+		Code:     "0172",
+		Category: CategoryDeltas,
+		Summary:  "A delta template could not be parsed.",
+		Cause:    "The delta has the schemer:template directive and uses {{ }} with invalid text/template syntax.",
+		Remedy:   "Fix the template action named in the wrapped error, or remove the directive if the file is not a template.",
+	},
+	{
+		Code:     "0173",
+		Category: CategoryDeltas,
+		Summary:  "A delta template could not be rendered.",
+		Cause:    "The delta uses a variable that is not defined, or a template function failed.",
+		Remedy:   "Define the variable under vars, with SCHEMER_VAR_<NAME> or --var name=value.",
+	},
+	{
+		Code:     "0174",
+		Category: CategoryUsage,
+		Summary:  "Invalid --var.",
+		Cause:    "The assignment has no equals sign.",
+		Remedy:   "Use --var name=value.",
+	},
+	{
+		Code:     "0175",
+		Category: CategoryUsage,
+		Summary:  "No environment selected for an environment-specific delta.",
+		Cause:    "The delta has a schemer:env directive and no profile is active.",
+		Remedy:   "Select the environment with --env or SCHEMER_PROFILE.",
+	},
+	{
+		Code:     "0176",
+		Category: CategoryDeltas,
+		Summary:  "An up delta could not be read for its schemer:env directive.",
+		Cause:    "Reading the up file of the delta group failed.",
+		Remedy:   "Check the permissions of the file.",
+	},
+	{
+		Code:     "0177",
+		Category: CategoryUsage,
+		Summary:  "Unknown versioning.",
+		Cause:    "--versioning or the versioning config key is not supported.",
+		Remedy:   "Use sequential or timestamp.",
+	},
+	{
+		Code:     "0178",
+		Category: CategoryTracking,
+		Summary:  "The tag columns could not be inspected.",
+		Cause:    "Querying information_schema for the tracking tables failed.",
+		Remedy:   "Check the wrapped database error and the privileges of the role.",
+	},
+	{
+		Code:     "0179",
+		Category: CategoryTracking,
+		Summary:  "The tag columns could not be read.",
+		Cause:    "Reading the information_schema rows failed.",
+		Remedy:   "Retry, and check the wrapped error.",
+	},
+	{
+		Code:     "0180",
+		Category: CategoryTracking,
+		Summary:  "A tag column could not be widened to BIGINT.",
+		Cause:    "ALTER TABLE on a tracking table failed, usually because the role does not own it.",
+		Remedy:   "Run schemer once as the owner of the tracking tables, or alter the column by hand.",
+	},
+	{
+		Code:     "0181",
+		Category: CategoryDeltas,
+		Summary:  "A path in the deltas directory could not be read.",
+		Cause:    "Walking the deltas directory for renumber failed.",
+		Remedy:   "Check the path in the message.",
+	},
+	{
+		Code:     "0182",
+		Category: CategoryDeltas,
+		Summary:  "A delta file has a malformed tag.",
+		Cause:    "The file name does not start with a number followed by an underscore.",
+		Remedy:   "Rename the file to <tag>_<name>.<direction>.sql.",
+	},
+	{
+		Code:     "0183",
+		Category: CategoryDeltas,
+		Summary:  "The applied group of a shared tag is unknown.",
+		Cause:    "Several groups share an applied tag and the history does not record which one was applied.",
+		Remedy:   "Rename the groups that were not applied by hand.",
+	},
+	{
+		Code:     "0184",
+		Category: CategoryDeltas,
+		Summary:  "A renumbered file would overwrite another file.",
+		Cause:    "The file name at the new tag already exists.",
+		Remedy:   "Remove or rename the existing file, then run renumber again.",
+	},
+	{
+		Code:     "0185",
+		Category: CategoryOutput,
+		Summary:  "A delta file could not be renamed.",
+		Cause:    "Renaming the file failed. Files renamed before it keep their new names.",
+		Remedy:   "Check the permissions of the deltas directory, and finish or undo the renames with git.",
+	},
+	{
+		Code:     "0186",
+		Category: CategoryDeltas,
+		Summary:  "labels.txt could not be read.",
+		Cause:    "The file exists but is unreadable.",
+		Remedy:   "Check the permissions of deltas/labels.txt.",
+	},
+	{
+		Code:     "0187",
+		Category: CategoryDeltas,
+		Summary:  "Malformed line in labels.txt.",
+		Cause:    "A line is not of the form <label> = <tag>.",
+		Remedy:   "Fix the line named in the message.",
+	},
+	{
+		Code:     "0188",
+		Category: CategoryDeltas,
+		Summary:  "Invalid label name in labels.txt.",
+		Cause:    "Labels are made of letters, digits, dots, dashes and underscores and cannot be plain numbers.",
+		Remedy:   "Rename the label, e.g. release-2.3.",
+	},
+	{
+		Code:     "0189",
+		Category: CategoryDeltas,
+		Summary:  "Duplicate label in labels.txt.",
+		Cause:    "The same label is defined twice.",
+		Remedy:   "Remove one of the lines.",
+	},
+	{
+		Code:     "0190",
+		Category: CategoryUsage,
+		Summary:  "Unknown tag or label.",
+		Cause:    "The value is neither a number nor a label defined in labels.txt.",
+		Remedy:   "Pass a tag such as 4 or 004, or add the label to deltas/labels.txt.",
+	},
+	{
+		Code:     "0191",
+		Category: CategoryUsage,
+		Summary:  "Unknown --output.",
+		Cause:    "The output format is not supported.",
+		Remedy:   "Use text or json.",
+	},
+	{
+		Code:     "0192",
+		Category: CategoryCheck,
+		Summary:  "The schemas differ.",
+		Cause:    "diff --fail-on-diff found differences between the source and the target.",
+		Remedy:   "Review the reported differences, and add a delta or update the snapshot.",
+	},
+	{
+		Code:     "0193",
+		Category: CategoryCheck,
+		Summary:  "The round-trip test failed.",
+		Cause:    "A down delta is missing, fails, or does not restore the schema its up delta changed.",
+		Remedy:   "Fix the down deltas of the failing tags listed in the report.",
+	},
+	{
+		Code:     "0194",
+		Category: CategoryCheck,
+		Summary:  "Lint failed.",
+		Cause:    "lint found errors, or warnings with --strict.",
+		Remedy:   "Fix the findings, or turn a rule off with --rule <rule>=off.",
+	},
+	{
+		Code:     "0195",
+		Category: CategoryCheck,
+		Summary:  "Validation failed.",
+		Cause:    "validate found errors, or warnings with --strict.",
+		Remedy:   "Fix the reported issues in the deltas directory.",
+	},
+	{
+		Code:     "0196",
+		Category: CategoryDeltas,
+		Summary:  "A delta file has a malformed tag.",
+		Cause:    "The file name does not start with a number followed by an underscore.",
+		Remedy:   "Rename the file to <tag>_<name>.<direction>.sql.",
+	},
+	{
+		Code:     "0197",
+		Category: CategoryDeltas,
+		Summary:  "The next tag could not be determined.",
+		Cause:    "Reading the deltas directory failed.",
+		Remedy:   "Check that the deltas directory exists and is readable.",
+	},
+	{
+		Code:     "0198",
+		Category: CategoryDeltas,
+		Summary:  "Duplicate delta tag.",
+		Cause:    "Two up deltas share a tag, so the next tag is ambiguous.",
+		Remedy:   "Renumber one of them with schemer renumber.",
+	},
+	{
+		Code:     "0199",
+		Category: CategoryOutput,
+		Summary:  "The target directory could not be created.",
+		Cause:    "Creating the directory for the new delta failed.",
+		Remedy:   "Check the path and the permissions of its parent.",
+	},
+	{
+		Code:     "0200",
+		Category: CategoryDeltas,
+		Summary:  "No down deltas were requested.",
+		Cause:    "Internal error: down deltas were loaded without a request.",
+		Remedy:   "Report the command that failed as a bug.",
+	},
+	{
+		Code:     "0201",
+		Category: CategoryDeltas,
+		Summary:  "A path in the deltas directory could not be read.",
+		Cause:    "Walking the deltas directory for down deltas failed.",
+		Remedy:   "Check the path in the message.",
+	},
+	{
+		Code:     "0202",
+		Category: CategoryDeltas,
+		Summary:  "A down delta has a malformed file name.",
+		Cause:    "The file name does not start with a number followed by an underscore.",
+		Remedy:   "Rename the file to <tag>_<name>.down.sql.",
+	},
+	{
+		Code:     "0203",
+		Category: CategoryDeltas,
+		Summary:  "The down delta of the last applied delta could not be read.",
+		Cause:    "Reading the file failed.",
+		Remedy:   "Check the permissions of the file.",
+	},
+	{
+		Code:     "0204",
+		Category: CategoryDeltas,
+		Summary:  "A down delta could not be read.",
+		Cause:    "Reading the file failed.",
+		Remedy:   "Check the permissions of the file.",
+	},
+	{
+		Code:     "0205",
+		Category: CategoryDeltas,
+		Summary:  "Duplicate down delta tag.",
+		Cause:    "Two down deltas share a tag.",
+		Remedy:   "Renumber one of the groups with schemer renumber.",
+	},
+	{
+		Code:     "0206",
+		Category: CategoryDeltas,
+		Summary:  "A path in the deltas directory could not be read.",
+		Cause:    "Walking the deltas directory for post deltas failed.",
+		Remedy:   "Check the path in the message.",
+	},
+	{
+		Code:     "0207",
+		Category: CategoryDeltas,
+		Summary:  "A post delta has a malformed tag.",
+		Cause:    "The file name does not start with a number followed by an underscore.",
+		Remedy:   "Rename the file to <tag>_<name>.post.sql.",
+	},
+	{
+		Code:     "0208",
+		Category: CategoryDeltas,
+		Summary:  "A post delta could not be read.",
+		Cause:    "Reading the file failed.",
+		Remedy:   "Check the permissions of the file.",
+	},
+	{
+		Code:     "0209",
+		Category: CategoryDeltas,
+		Summary:  "Duplicate post delta tag.",
+		Cause:    "Two post deltas share a tag.",
+		Remedy:   "Renumber one of the groups with schemer renumber.",
+	},
+	{
+		Code:     "0210",
+		Category: CategoryDeltas,
+		Summary:  "A path in the deltas directory could not be read.",
+		Cause:    "Walking the deltas directory for up deltas failed.",
+		Remedy:   "Check the path in the message.",
+	},
+	{
+		Code:     "0211",
+		Category: CategoryDeltas,
+		Summary:  "An up delta has a malformed tag.",
+		Cause:    "The file name does not start with a number followed by an underscore.",
+		Remedy:   "Rename the file to <tag>_<name>.up.sql.",
+	},
+	{
+		Code:     "0212",
+		Category: CategoryDeltas,
+		Summary:  "An up delta could not be read.",
+		Cause:    "Reading the file failed.",
+		Remedy:   "Check the permissions of the file.",
+	},
+	{
+		Code:     "0213",
+		Category: CategoryDeltas,
+		Summary:  "Duplicate up delta tag.",
+		Cause:    "Two up deltas share a tag, usually after merging branches.",
+		Remedy:   "Renumber the conflicting groups with schemer renumber.",
+	},
+	{
+		Code:     "0214",
+		Category: CategoryUsage,
+		Summary:  "Unknown error code.",
+		Cause:    "schemer explain was given a code that is not in the catalog.",
+		Remedy:   "Run schemer explain without arguments to list every code.",
+	},
+	{
+		Code:     "0215",
+		Category: CategoryUsage,
+		Summary:  "Conflicting log level flags.",
+		Cause:    "More than one of --log-level, --quiet and --verbose was passed.",
+		Remedy:   "Pass only one of them.",
+	},
+	{
+		Code:     "0216",
+		Category: CategoryUsage,
+		Summary:  "Unknown log level.",
+		Cause:    "--log-level or $SCHEMER_LOG_LEVEL is not a level schemer knows.",
+		Remedy:   "Use debug, info, warn or error.",
+	},
+	{
+		Code:     "0217",
+		Category: CategoryUsage,
+		Summary:  "Unknown --log-format.",
+		Cause:    "--log-format or $SCHEMER_LOG_FORMAT is not supported.",
+		Remedy:   "Use text or json.",
+	},
+	{
+		Code:     "0218",
+		Category: CategoryOutput,
+		Summary:  "The log file could not be opened.",
+		Cause:    "--log-file or $SCHEMER_LOG_FILE names a path that cannot be created or written.",
+		Remedy:   "Check the path and the permissions of its directory.",
+	},
+	{
+		Code:     "0219",
+		Category: CategoryExecution,
+		Summary:  "A delta raised a warning.",
+		Cause:    "--fail-on-warning is set and the server raised a WARNING, e.g. a RAISE WARNING or an identifier being truncated. The delta was rolled back, unless it controls transactions itself or cannot run inside one, in which case it keeps its dirty marker.",
+		Remedy:   "Fix the statement the warning names and run the command again, resolving a dirty marker with schemer repair first. Drop --fail-on-warning to only log warnings.",
+	},
+	{
+		Code:     "0220",
+		Category: CategoryTracking,
+		Summary:  "A tracking table could not be looked up.",
+		Cause:    "The query checking whether a tracking table exists failed.",
+		Remedy:   "Check the wrapped database error, usually missing privileges on the schema.",
+	},
+	{
+		Code:     "0221",
+		Category: CategoryConnection,
+		Summary:  "The connection string could not be parsed.",
+		Cause:    "The connection string is not a valid PostgreSQL URL or key/value connection string.",
+		Remedy:   "Check the connection string passed with --conn-string or read from the --conn-key environment variable.",
+	},
+	{
+		Code:     "0222",
+		Category: CategoryConnection,
+		Summary:  "Sessions on a scratch database could not be terminated.",
+		Cause:    "Before dropping a scratch database schemer disconnects the sessions still using it, and pg_terminate_backend failed.",
+		Remedy:   "The admin role needs to own those sessions, be a superuser or be a member of pg_signal_backend. Close the sessions, then run schemer scratch drop again.",
+	},
+}
+
+// Lookup finds the catalog entry of a code.
+//
+// Params:
+//   - code: the code, with or without leading zeros.
+//
+// Returns:
+//   - Entry: the entry of the code.
+//   - bool: false if the code is not in the catalog.
+func Lookup(code string) (Entry, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) < 4 {
+		code = strings.Repeat("0", 4-len(code)) + code
+	}
+	for _, entry := range Catalog {
+		if entry.Code == code {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Markdown renders the catalog as the SCHEMER_ERRORS.md document.
+//
+// Returns:
+//   - string: the document, grouped by code in ascending order.
+func Markdown() string {
+	var b strings.Builder
+	b.WriteString("# Schemer Error Codes\n\n")
+	b.WriteString("<!-- Generated by go generate ./internal/errschemer. DO NOT EDIT. -->\n\n")
+	b.WriteString("Every error schemer reports carries a four digit code. `schemer explain <code>` prints the entry of a code.\n")
+	for _, entry := range Catalog {
+		fmt.Fprintf(&b, "\n### `%s` %s\n\n", entry.Code, entry.Summary)
+		fmt.Fprintf(&b, "**Category:** %s\n\n", entry.Category)
+		fmt.Fprintf(&b, "**Cause:** %s\n\n", entry.Cause)
+		fmt.Fprintf(&b, "**Remedy:** %s\n", entry.Remedy)
+	}
+	return b.String()
+}
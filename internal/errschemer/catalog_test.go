@@ -0,0 +1,151 @@
+package errschemer
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var codePattern = regexp.MustCompile(`^[0-9]{4}$`)
+
+func TestCatalog(t *testing.T) {
+	seen := map[string]bool{}
+	for _, entry := range Catalog {
+		if !codePattern.MatchString(entry.Code) {
+			t.Errorf("code %q is not four digits", entry.Code)
+		}
+		if seen[entry.Code] {
+			t.Errorf("code %s is registered twice", entry.Code)
+		}
+		seen[entry.Code] = true
+		if entry.Category == "" || entry.Summary == "" || entry.Cause == "" || entry.Remedy == "" {
+			t.Errorf("code %s has an empty field", entry.Code)
+		}
+	}
+
+	used := usedCodes(t, filepath.Join("..", ".."))
+	for code, sites := range used {
+		if !seen[code] {
+			t.Errorf("code %s used at %s is not in the catalog", code, sites[0])
+		}
+		if len(sites) > 1 {
+			t.Errorf("code %s is used at more than one site: %s", code, strings.Join(sites, ", "))
+		}
+	}
+	for code := range seen {
+		if _, ok := used[code]; !ok {
+			t.Errorf("code %s is in the catalog but not used", code)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, code := range []string{"0021", "21", " 021 "} {
+		entry, ok := Lookup(code)
+		if !ok || entry.Code != "0021" {
+			t.Errorf("Lookup(%q) = %q, %v", code, entry.Code, ok)
+		}
+	}
+	if _, ok := Lookup("9999"); ok {
+		t.Error("Lookup(9999) found an entry")
+	}
+}
+
+func TestMarkdownUpToDate(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "SCHEMER_ERRORS.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != Markdown() {
+		t.Error("SCHEMER_ERRORS.md is out of date, run go generate ./internal/errschemer")
+	}
+}
+
+// usedCodes collects the position of every site using each code, from the SchemerErr
+// literals in the non-test sources under root.
+func usedCodes(t *testing.T, root string) map[string][]string {
+	t.Helper()
+	codes := map[string][]string{}
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			// internal/schema passes the code of each catalog query to collect.
+			if call, ok := n.(*ast.CallExpr); ok && isCollect(call.Fun) && len(call.Args) > 2 {
+				if value, ok := call.Args[2].(*ast.BasicLit); ok && value.Kind == token.STRING {
+					code, _ := strconv.Unquote(value.Value)
+					codes[code] = append(codes[code], fset.Position(value.Pos()).String())
+				}
+				return true
+			}
+			lit, ok := n.(*ast.CompositeLit)
+			if !ok || !isSchemerErr(lit.Type) {
+				return true
+			}
+			for _, elt := range lit.Elts {
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+				if key, ok := kv.Key.(*ast.Ident); !ok || key.Name != "Code" {
+					continue
+				}
+				value, ok := kv.Value.(*ast.BasicLit)
+				if !ok || value.Kind != token.STRING {
+					if ident, ok := kv.Value.(*ast.Ident); !ok || ident.Name != "code" {
+						t.Errorf("%s: SchemerErr code is not a string literal", fset.Position(kv.Pos()))
+					}
+					continue
+				}
+				code, _ := strconv.Unquote(value.Value)
+				codes[code] = append(codes[code], fset.Position(value.Pos()).String())
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return codes
+}
+
+func isSchemerErr(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name == "SchemerErr"
+	case *ast.SelectorExpr:
+		return e.Sel.Name == "SchemerErr"
+	}
+	return false
+}
+
+func isCollect(expr ast.Expr) bool {
+	if index, ok := expr.(*ast.IndexExpr); ok {
+		expr = index.X
+	}
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == "collect"
+}
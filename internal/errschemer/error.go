@@ -6,10 +6,22 @@ import (
 	"strings"
 )
 
+// Kind classifies an error for the process exit code.
+type Kind int
+
+const (
+	KindGeneral     Kind = iota // any failure not covered below
+	KindUsage                   // invalid flags, arguments or config values
+	KindConnection              // the database could not be reached
+	KindExecution               // a delta, repeatable delta or seed failed to apply
+	KindNothingToDo             // the command had nothing to apply
+)
+
 type SchemerErr struct {
 	Code    string
 	Message string
 	Err     error
+	Kind    Kind // zero for general errors
 }
 
 func (e *SchemerErr) Error() string {
@@ -49,3 +61,16 @@ func FormatChain(err error) string {
 
 	return b.String()
 }
+
+// KindOf returns the innermost Kind set in the chain of err, the one closest to the cause,
+// KindGeneral if there is none.
+func KindOf(err error) Kind {
+	kind := KindGeneral
+	for err != nil {
+		if se, ok := err.(*SchemerErr); ok && se.Kind != KindGeneral {
+			kind = se.Kind
+		}
+		err = errors.Unwrap(err)
+	}
+	return kind
+}
@@ -19,24 +19,22 @@ LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
 OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 THE SOFTWARE.
 */
-
-package glog
+// Command gen writes SCHEMER_ERRORS.md from the error catalog. It runs through
+// go generate in internal/errschemer.
+package main
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
-	"github.com/inskribe/schemer/internal/glog/enums"
+	"github.com/inskribe/schemer/internal/errschemer"
 )
 
-func productionLogger(category enums.LogCategory, msg string, args ...interface{}) {
-	if category == enums.Debug {
-		return
-	}
-	formattedMessage := fmt.Sprintf(msg, args...)
-	outputString := fmt.Sprintf("[%s]: %s\n", category, formattedMessage)
-	if category == enums.Fatal {
-		panic(outputString)
+func main() {
+	path := filepath.Join("..", "..", "SCHEMER_ERRORS.md")
+	if err := os.WriteFile(path, []byte(errschemer.Markdown()), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-
-	fmt.Print(outputString)
 }
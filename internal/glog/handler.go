@@ -0,0 +1,173 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package glog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	Red    = "\033[31m"
+	Green  = "\033[32m"
+	Yellow = "\033[33m"
+	Blue   = "\033[34m"
+	White  = "\033[97m"
+	Reset  = "\033[0m"
+)
+
+// newHandler builds the slog handler for options.
+func newHandler(options Options) slog.Handler {
+	if options.Format == FormatJSON {
+		return slog.NewJSONHandler(options.Writer, &slog.HandlerOptions{
+			Level: options.Level,
+			ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+				if len(groups) == 0 && attr.Key == slog.LevelKey {
+					attr.Value = slog.StringValue(strings.ToLower(levelName(attr.Value.Any().(slog.Level))))
+				}
+				return attr
+			},
+		})
+	}
+	return &textHandler{mu: &sync.Mutex{}, w: options.Writer, level: options.Level, color: options.Color}
+}
+
+// levelName returns the name printed for level, e.g. Info.
+func levelName(level slog.Level) string {
+	switch {
+	case level >= LevelFatal:
+		return "Fatal"
+	case level >= slog.LevelError:
+		return "Error"
+	case level >= slog.LevelWarn:
+		return "Warn"
+	case level >= slog.LevelInfo:
+		return "Info"
+	}
+	return "Debug"
+}
+
+// levelColor returns the escape sequence records at level are colored with.
+func levelColor(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return Red
+	case level >= slog.LevelWarn:
+		return Yellow
+	case level >= slog.LevelInfo:
+		return ""
+	}
+	return Blue
+}
+
+// textHandler writes records as "[Level]: message key=value", the format schemer always logged in.
+// The time is left out, log lines of a single run are read by people.
+type textHandler struct {
+	mu     *sync.Mutex // shared by the handlers derived with WithAttrs and WithGroup
+	w      io.Writer
+	level  slog.Level
+	color  bool
+	attrs  []byte // preformatted attributes of WithAttrs
+	prefix string // group prefix of the keys, e.g. "delta."
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(_ context.Context, record slog.Record) error {
+	color := ""
+	if h.color {
+		color = levelColor(record.Level)
+	}
+
+	line := make([]byte, 0, 128)
+	line = append(line, color...)
+	line = append(line, '[')
+	line = append(line, levelName(record.Level)...)
+	line = append(line, "]: "...)
+	line = append(line, record.Message...)
+	line = append(line, h.attrs...)
+	record.Attrs(func(attr slog.Attr) bool {
+		line = appendAttr(line, h.prefix, attr)
+		return true
+	})
+	if color != "" {
+		line = append(line, Reset...)
+	}
+	line = append(line, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(line)
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]byte{}, h.attrs...)
+	for _, attr := range attrs {
+		clone.attrs = appendAttr(clone.attrs, h.prefix, attr)
+	}
+	return &clone
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+// appendAttr appends " key=value" to line, flattening groups into dotted keys. Values holding
+// spaces or quotes are quoted.
+func appendAttr(line []byte, prefix string, attr slog.Attr) []byte {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return line
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, member := range attr.Value.Group() {
+			line = appendAttr(line, prefix, member)
+		}
+		return line
+	}
+
+	value := attr.Value.String()
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		value = strconv.Quote(value)
+	}
+	line = append(line, ' ')
+	line = append(line, prefix...)
+	line = append(line, attr.Key...)
+	line = append(line, '=')
+	return append(line, value...)
+}
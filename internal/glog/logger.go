@@ -19,67 +19,165 @@ LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
 OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 THE SOFTWARE.
 */
-
 package glog
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
 	"github.com/inskribe/schemer/internal/build"
-	"github.com/inskribe/schemer/internal/glog/enums"
 )
 
-type Logger struct {
-	loggerFunc loggingFunc
+// LevelFatal is logged by Fatal right before it panics.
+const LevelFatal = slog.LevelError + 4
+
+// Format selects how log records are written.
+type Format string
+
+const (
+	FormatText Format = "text" // [Level]: message key=value
+	FormatJSON Format = "json" // one JSON object per record
+)
+
+// Options configures the logger, see Configure.
+type Options struct {
+	Level  slog.Level // records below Level are dropped
+	Format Format
+	Writer io.Writer // destination of the records, stderr by default
+	Color  bool      // color text records by level
 }
 
-type loggingFunc func(category enums.LogCategory, msg string, args ...interface{})
+// logger is usable before Configure is called, writing text to stderr.
+var logger = slog.New(newHandler(DefaultOptions()))
+
+// DefaultOptions returns the options the logger starts with: info level, or debug level in debug
+// builds, text written to stderr and colored when stderr is a terminal.
+//
+// Returns:
+//   - Options: the default options.
+func DefaultOptions() Options {
+	level := slog.LevelInfo
+	if build.IsDebug() {
+		level = slog.LevelDebug
+	}
+	return Options{
+		Level:  level,
+		Format: FormatText,
+		Writer: os.Stderr,
+		Color:  UseColor(os.Stderr),
+	}
+}
 
-var Log Logger
+// Configure replaces the logger.
+//
+// Params:
+//   - options: how and where records are written, a nil Writer means stderr.
+func Configure(options Options) {
+	if options.Writer == nil {
+		options.Writer = os.Stderr
+	}
+	logger = slog.New(newHandler(options))
+}
 
+// InitializeLogger resets the logger to its defaults, or discards every record if suppress is set.
+//
+// Params:
+//   - suppress: discard every record, used by the tests.
 func InitializeLogger(suppress bool) {
-	var loggerFunc loggingFunc
 	if suppress {
-		loggerFunc = suppressLogger
-	} else {
-		if build.IsDebug() {
-			loggerFunc = debugLogger
-		} else {
-			loggerFunc = productionLogger
-		}
+		logger = slog.New(slog.DiscardHandler)
+		return
 	}
-	Log = Logger{
-		loggerFunc,
+	Configure(DefaultOptions())
+}
+
+// ParseLevel parses a level name as accepted by --log-level.
+//
+// Params:
+//   - name: debug, info, warn or error, in any case.
+//
+// Returns:
+//   - slog.Level: the level.
+//   - error: if the name is unknown.
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
 	}
+	return 0, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", name)
 }
 
-func suppressLogger(category enums.LogCategory, msg string, args ...interface{}) {
+// UseColor reports whether records written to w should be colored: w is a terminal and
+// $NO_COLOR is unset or empty.
+//
+// Params:
+//   - w: destination of the records.
+//
+// Returns:
+//   - bool: true to color the records.
+func UseColor(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
-func Debug(msg string, args ...interface{}) {
-	if !build.IsDebug() {
+// Enabled reports whether records at level are written.
+func Enabled(level slog.Level) bool {
+	return logger.Enabled(context.Background(), level)
+}
+
+// LogAttrs writes a record with structured attributes, e.g. the tag of the delta it is about.
+func LogAttrs(level slog.Level, msg string, attrs ...slog.Attr) {
+	logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
+
+func logf(level slog.Level, msg string, args ...interface{}) {
+	if !logger.Enabled(context.Background(), level) {
 		return
 	}
+	logger.Log(context.Background(), level, fmt.Sprintf(msg, args...))
+}
 
-	Log.loggerFunc(enums.Debug, msg, args...)
+func Debug(msg string, args ...interface{}) {
+	logf(slog.LevelDebug, msg, args...)
 }
 
 func Info(msg string, args ...interface{}) {
-	Log.loggerFunc(enums.Info, msg, args...)
+	logf(slog.LevelInfo, msg, args...)
 }
 
 func Warn(msg string, args ...interface{}) {
-	Log.loggerFunc(enums.Warn, msg, args...)
+	logf(slog.LevelWarn, msg, args...)
 }
 
 func Error(msg string, args ...interface{}) {
-	Log.loggerFunc(enums.Error, msg, args...)
+	logf(slog.LevelError, msg, args...)
 }
 
 func Fatal(msg string, args ...interface{}) {
-	Log.loggerFunc(enums.Fatal, msg, args...)
+	message := fmt.Sprintf(msg, args...)
+	logger.Log(context.Background(), LevelFatal, message)
+	panic(message)
 }
 
 func PanicUnreachable[T any](reason string) T {
-	Log.loggerFunc(enums.Fatal, reason)
+	Fatal("%s", reason)
 	var zero T
 	return zero
 }
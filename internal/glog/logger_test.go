@@ -0,0 +1,85 @@
+package glog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestTextFormat(t *testing.T) {
+	var buf bytes.Buffer
+	Configure(Options{Level: slog.LevelInfo, Format: FormatText, Writer: &buf})
+	defer InitializeLogger(true)
+
+	Debug("hidden")
+	Info("applied %d deltas", 2)
+	LogAttrs(slog.LevelWarn, "notice", slog.Int64("tag", 7), slog.String("message", "identifier truncated"))
+
+	want := "[Info]: applied 2 deltas\n[Warn]: notice tag=7 message=\"identifier truncated\"\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestTextFormat_Color(t *testing.T) {
+	var buf bytes.Buffer
+	Configure(Options{Level: slog.LevelInfo, Format: FormatText, Writer: &buf, Color: true})
+	defer InitializeLogger(true)
+
+	Error("failed")
+	if want := Red + "[Error]: failed" + Reset + "\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	Configure(Options{Level: slog.LevelWarn, Format: FormatJSON, Writer: &buf})
+	defer InitializeLogger(true)
+
+	Info("hidden")
+	Warn("slow delta %s", "004")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("not a single JSON record: %q", buf.String())
+	}
+	if record["level"] != "warn" || record["msg"] != "slow delta 004" {
+		t.Errorf("got %v", record)
+	}
+}
+
+func TestFatal(t *testing.T) {
+	var buf bytes.Buffer
+	Configure(Options{Level: slog.LevelError, Format: FormatText, Writer: &buf})
+	defer InitializeLogger(true)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Fatal did not panic")
+		}
+		if buf.String() != "[Fatal]: unreachable\n" {
+			t.Errorf("got %q", buf.String())
+		}
+	}()
+	Fatal("unreachable")
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"debug":   slog.LevelDebug,
+		"INFO":    slog.LevelInfo,
+		"warning": slog.LevelWarn,
+		" error ": slog.LevelError,
+	}
+	for name, want := range tests {
+		got, err := ParseLevel(name)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v", name, got, err)
+		}
+	}
+	if _, err := ParseLevel("trace"); err == nil {
+		t.Error("ParseLevel(trace) did not fail")
+	}
+}
@@ -0,0 +1,243 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Package output writes machine-readable results for --output json.
+//
+// In JSON mode every line written to stdout is one JSON object, an event, and log lines go
+// to stderr. Every event carries its name, the command that emitted it and a timestamp.
+// Commands that execute deltas stream a delta event per delta; reporting commands emit one
+// event holding their whole report. Every run ends with a single result event.
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+)
+
+// Format selects how commands report their results on stdout.
+type Format string
+
+const (
+	FormatText Format = "text" // human readable tables and log lines
+	FormatJSON Format = "json" // newline delimited JSON events
+)
+
+var (
+	// Current is the format selected with --output.
+	Current = FormatText
+
+	// Writer receives the JSON events.
+	Writer io.Writer = os.Stdout
+
+	command string
+)
+
+// Configure selects the output format for the running command.
+//
+// Params:
+//   - format: text or json
+//   - commandPath: name of the running command, e.g. "up" or "scratch create"
+//
+// Returns:
+//   - error: non-nil if the format is unknown
+func Configure(format string, commandPath string) error {
+	switch Format(format) {
+	case FormatText, FormatJSON:
+		Current = Format(format)
+	default:
+		return &er.SchemerErr{
+			Code:    "0191",
+			Kind:    er.KindUsage,
+			Message: fmt.Sprintf("unknown --output %q, expected %s or %s", format, FormatText, FormatJSON),
+		}
+	}
+	command = commandPath
+	return nil
+}
+
+// JSON reports whether results are written as JSON events.
+func JSON() bool {
+	return Current == FormatJSON
+}
+
+// Header is embedded in every event.
+type Header struct {
+	Event   string    `json:"event"`   // kind of event, e.g. delta or result
+	Command string    `json:"command"` // command that emitted the event, e.g. up
+	Time    time.Time `json:"time"`    // when the event was emitted
+}
+
+func (header *Header) header() *Header { return header }
+
+// Event is a payload that can be emitted.
+type Event interface {
+	header() *Header
+}
+
+// Emit writes an event as a single JSON line. Nothing is written in text mode, so callers
+// can emit unconditionally. Failing to write an event is reported on stderr only, the
+// outcome of the command must not depend on it.
+//
+// Params:
+//   - name: kind of event, e.g. delta or status
+//   - event: the payload, a struct embedding Header
+func Emit(name string, event Event) {
+	if !JSON() {
+		return
+	}
+	header := event.header()
+	header.Event = name
+	header.Command = command
+	header.Time = time.Now().UTC()
+
+	line, err := json.Marshal(event)
+	if err == nil {
+		_, err = Writer.Write(append(line, '\n'))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write %s event: %v\n", name, err)
+	}
+}
+
+// Delta statuses reported in delta events.
+const (
+	StatusPending = "pending" // about to be applied
+	StatusPlanned = "planned" // would be applied, reported by dry runs
+	StatusApplied = "applied" // executed successfully
+	StatusSkipped = "skipped" // not executed, see the reason
+	StatusFailed  = "failed"  // executed with an error
+)
+
+// DeltaEvent reports a delta, repeatable delta or seed considered by a command.
+type DeltaEvent struct {
+	Header
+	Direction  string     `json:"direction"`             // up, down, post, repeatable or seed
+	Tag        *int64     `json:"tag,omitempty"`         // tag of versioned deltas
+	File       string     `json:"file,omitempty"`        // file relative to the deltas or seeds directory
+	Status     string     `json:"status"`                // pending, planned, applied, skipped or failed
+	Reason     string     `json:"reason,omitempty"`      // why the delta was skipped
+	DurationMs *int64     `json:"duration_ms,omitempty"` // execution time of applied and failed deltas
+	Notices    []Notice   `json:"notices,omitempty"`     // notices the server raised while the delta ran
+	Error      *ErrorInfo `json:"error,omitempty"`       // the failure of failed deltas
+}
+
+// Notice is a NOTICE, WARNING or other message the server raised while a delta ran.
+type Notice struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Detail   string `json:"detail,omitempty"`
+	Hint     string `json:"hint,omitempty"`
+}
+
+// ResultEvent is the last event of every run.
+type ResultEvent struct {
+	Header
+	Success  bool       `json:"success"`         // false if the command failed
+	ExitCode int        `json:"exit_code"`       // exit code of the process
+	Error    *ErrorInfo `json:"error,omitempty"` // the failure, if any
+}
+
+// ErrorInfo describes an error for JSON output.
+type ErrorInfo struct {
+	Code     string         `json:"code,omitempty"`     // code of the outermost SchemerErr
+	Message  string         `json:"message"`            // message of the outermost SchemerErr, or the error text
+	Chain    []string       `json:"chain"`              // every error in the chain, outermost first
+	Postgres *PostgresError `json:"postgres,omitempty"` // the PostgreSQL error found in the chain
+}
+
+// PostgresError holds the fields of an error reported by the PostgreSQL server.
+type PostgresError struct {
+	Severity   string `json:"severity,omitempty"`
+	Code       string `json:"code,omitempty"` // SQLSTATE, e.g. 42P07
+	Message    string `json:"message,omitempty"`
+	Detail     string `json:"detail,omitempty"`
+	Hint       string `json:"hint,omitempty"`
+	Position   int32  `json:"position,omitempty"`
+	Where      string `json:"where,omitempty"`
+	Schema     string `json:"schema,omitempty"`
+	Table      string `json:"table,omitempty"`
+	Column     string `json:"column,omitempty"`
+	Constraint string `json:"constraint,omitempty"`
+}
+
+// NewErrorInfo describes err and the errors it wraps.
+//
+// Params:
+//   - err: the error to describe
+//
+// Returns:
+//   - *ErrorInfo: the description, nil if err is nil
+func NewErrorInfo(err error) *ErrorInfo {
+	if err == nil {
+		return nil
+	}
+
+	info := &ErrorInfo{Message: err.Error()}
+	var schemerErr *er.SchemerErr
+	if errors.As(err, &schemerErr) {
+		info.Code = schemerErr.Code
+		info.Message = schemerErr.Message
+	}
+
+	for current := err; current != nil; current = errors.Unwrap(current) {
+		if link, ok := current.(*er.SchemerErr); ok {
+			info.Chain = append(info.Chain, fmt.Sprintf("Error %s: %s", link.Code, link.Message))
+			continue
+		}
+		info.Chain = append(info.Chain, current.Error())
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		info.Postgres = &PostgresError{
+			Severity:   pgErr.Severity,
+			Code:       pgErr.Code,
+			Message:    pgErr.Message,
+			Detail:     pgErr.Detail,
+			Hint:       pgErr.Hint,
+			Position:   pgErr.Position,
+			Where:      pgErr.Where,
+			Schema:     pgErr.SchemaName,
+			Table:      pgErr.TableName,
+			Column:     pgErr.ColumnName,
+			Constraint: pgErr.ConstraintName,
+		}
+	}
+	return info
+}
+
+// EmitResult writes the result event of the run.
+//
+// Params:
+//   - err: the error the command failed with, nil on success
+//   - exitCode: exit code of the process
+func EmitResult(err error, exitCode int) {
+	Emit("result", &ResultEvent{Success: err == nil, ExitCode: exitCode, Error: NewErrorInfo(err)})
+}
@@ -0,0 +1,96 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+)
+
+func useJSON(t *testing.T) *bytes.Buffer {
+	var out bytes.Buffer
+	previous, previousWriter := Current, Writer
+	t.Cleanup(func() { Current, Writer = previous, previousWriter })
+
+	if err := Configure("json", "up"); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	Writer = &out
+	return &out
+}
+
+func TestConfigure(t *testing.T) {
+	t.Cleanup(func() { Current = FormatText })
+
+	err := Configure("yaml", "up")
+	if schemerErr, ok := err.(*er.SchemerErr); !ok || schemerErr.Code != "0191" {
+		t.Fatalf("expected an unknown format to fail with 0191, got %v", err)
+	}
+	if JSON() {
+		t.Fatalf("expected the format to stay text after an error")
+	}
+}
+
+func TestEmit(t *testing.T) {
+	Emit("result", &ResultEvent{Success: true})
+
+	out := useJSON(t)
+	tag := int64(3)
+	Emit("delta", &DeltaEvent{Direction: "up", Tag: &tag, File: "003_add_email.up.sql", Status: StatusSkipped, Reason: "already applied"})
+	EmitResult(nil, 0)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 events, got:\n%s", out.String())
+	}
+
+	var delta map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &delta); err != nil {
+		t.Fatalf("invalid event %s: %v", lines[0], err)
+	}
+	for key, expected := range map[string]any{"event": "delta", "command": "up", "tag": float64(3), "status": "skipped", "reason": "already applied"} {
+		if delta[key] != expected {
+			t.Errorf("expected %s=%v, got %v", key, expected, delta[key])
+		}
+	}
+	if _, ok := delta["duration_ms"]; ok {
+		t.Errorf("expected no duration for a skipped delta")
+	}
+	if !strings.Contains(lines[1], `"event":"result"`) || !strings.Contains(lines[1], `"success":true,"exit_code":0`) {
+		t.Errorf("unexpected result event %s", lines[1])
+	}
+}
+
+func TestNewErrorInfo(t *testing.T) {
+	if NewErrorInfo(nil) != nil {
+		t.Fatalf("expected no info for a nil error")
+	}
+
+	pgErr := &pgconn.PgError{Severity: "ERROR", Code: "42P07", Message: `relation "users" already exists`, TableName: "users"}
+	err := &er.SchemerErr{
+		Code:    "0053",
+		Message: "failed to apply delta 001",
+		Err:     fmt.Errorf("exec: %w", pgErr),
+	}
+
+	info := NewErrorInfo(err)
+	if info.Code != "0053" || info.Message != "failed to apply delta 001" {
+		t.Fatalf("expected the outermost SchemerErr, got %+v", info)
+	}
+	if len(info.Chain) != 3 || info.Chain[0] != "Error 0053: failed to apply delta 001" {
+		t.Fatalf("unexpected chain %q", info.Chain)
+	}
+	if info.Postgres == nil || info.Postgres.Code != "42P07" || info.Postgres.Table != "users" {
+		t.Fatalf("expected the pg error fields, got %+v", info.Postgres)
+	}
+
+	plain := NewErrorInfo(fmt.Errorf("boom"))
+	if plain.Code != "" || plain.Message != "boom" || plain.Postgres != nil {
+		t.Fatalf("unexpected info for a plain error: %+v", plain)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package schema
+
+import (
+	"sort"
+	"strings"
+)
+
+// ChangeType describes how an object differs between two snapshots.
+type ChangeType string
+
+const (
+	ChangeAdded   ChangeType = "added"   // object exists only in the target
+	ChangeRemoved ChangeType = "removed" // object exists only in the source
+	ChangeChanged ChangeType = "changed" // object exists in both with different definitions
+)
+
+// Change is a single difference between two snapshots.
+type Change struct {
+	Type   ChangeType `json:"type"`             // added, removed or changed
+	Kind   Kind       `json:"kind"`             // type of the object
+	Name   string     `json:"name"`             // schema qualified name of the object
+	Source string     `json:"source,omitempty"` // definition in the source, empty if added
+	Target string     `json:"target,omitempty"` // definition in the target, empty if removed
+
+	SourceColumns []Column `json:"-"` // columns of a table in the source, nil unless read by Introspect
+	TargetColumns []Column `json:"-"` // columns of a table in the target, nil unless read by Introspect
+}
+
+// Diff compares two snapshots and returns what would have to change to turn source into target.
+//
+// Params:
+//   - source: the snapshot considered the starting point
+//   - target: the snapshot compared against the source
+//
+// Returns:
+//   - []Change: differences sorted by kind and name, the same order snapshots are written in
+func Diff(source, target *Snapshot) []Change {
+	targetObjects := make(map[string]Object, len(target.Objects))
+	for _, object := range target.Objects {
+		targetObjects[object.Key()] = object
+	}
+
+	var changes []Change
+	seen := make(map[string]bool, len(source.Objects))
+	for _, object := range source.Objects {
+		seen[object.Key()] = true
+		other, ok := targetObjects[object.Key()]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Type: ChangeRemoved, Kind: object.Kind, Name: object.Name, Source: object.Definition})
+		case strings.TrimSpace(other.Definition) != strings.TrimSpace(object.Definition):
+			changes = append(changes, Change{
+				Type: ChangeChanged, Kind: object.Kind, Name: object.Name, Source: object.Definition, Target: other.Definition,
+				SourceColumns: object.Columns, TargetColumns: other.Columns,
+			})
+		}
+	}
+	for _, object := range target.Objects {
+		if !seen[object.Key()] {
+			changes = append(changes, Change{Type: ChangeAdded, Kind: object.Kind, Name: object.Name, Target: object.Definition})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return kindOrder[changes[i].Kind] < kindOrder[changes[j].Kind]
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// LineDiff returns a line based diff of two definitions. Unchanged lines are prefixed
+// with two spaces, removed lines with "- " and added lines with "+ ".
+//
+// Params:
+//   - source: the original definition
+//   - target: the new definition
+//
+// Returns:
+//   - []string: diff lines in order
+func LineDiff(source, target string) []string {
+	left := strings.Split(strings.TrimSpace(source), "\n")
+	right := strings.Split(strings.TrimSpace(target), "\n")
+
+	// longest common subsequence table, lengths[i][j] covers left[i:] and right[j:]
+	lengths := make([][]int, len(left)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(right)+1)
+	}
+	for i := len(left) - 1; i >= 0; i-- {
+		for j := len(right) - 1; j >= 0; j-- {
+			if left[i] == right[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var result []string
+	i, j := 0, 0
+	for i < len(left) && j < len(right) {
+		switch {
+		case left[i] == right[j]:
+			result = append(result, "  "+left[i])
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			result = append(result, "- "+left[i])
+			i++
+		default:
+			result = append(result, "+ "+right[j])
+			j++
+		}
+	}
+	for ; i < len(left); i++ {
+		result = append(result, "- "+left[i])
+	}
+	for ; j < len(right); j++ {
+		result = append(result, "+ "+right[j])
+	}
+	return result
+}
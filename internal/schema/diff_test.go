@@ -0,0 +1,52 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	source := &Snapshot{Objects: []Object{
+		{Kind: KindTable, Name: "public.users", Definition: "CREATE TABLE public.users (\n    id integer NOT NULL\n);"},
+		{Kind: KindIndex, Name: "public.users_old_idx", Definition: "CREATE INDEX users_old_idx ON public.users USING btree (id);"},
+		{Kind: KindView, Name: "public.active_users", Definition: "CREATE VIEW public.active_users AS\nSELECT 1;"},
+	}}
+	target := &Snapshot{Objects: []Object{
+		{Kind: KindTable, Name: "public.users", Definition: "CREATE TABLE public.users (\n    id bigint NOT NULL\n);"},
+		{Kind: KindTable, Name: "public.orders", Definition: "CREATE TABLE public.orders (\n);"},
+		{Kind: KindView, Name: "public.active_users", Definition: "CREATE VIEW public.active_users AS\nSELECT 1;\n"},
+	}}
+
+	changes := Diff(source, target)
+	var summary []string
+	for _, change := range changes {
+		summary = append(summary, string(change.Type)+" "+string(change.Kind)+" "+change.Name)
+	}
+	expected := "added TABLE public.orders|changed TABLE public.users|removed INDEX public.users_old_idx"
+	if strings.Join(summary, "|") != expected {
+		t.Fatalf("Diff() = %v, expected %s", summary, expected)
+	}
+
+	if changes := Diff(source, source); len(changes) != 0 {
+		t.Fatalf("expected no changes comparing a snapshot to itself, got %+v", changes)
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	source := "CREATE TABLE a (\n    id integer,\n    name text\n);"
+	target := "CREATE TABLE a (\n    id bigint,\n    name text,\n    email text\n);"
+
+	expected := []string{
+		"  CREATE TABLE a (",
+		"-     id integer,",
+		"-     name text",
+		"+     id bigint,",
+		"+     name text,",
+		"+     email text",
+		"  );",
+	}
+	actual := LineDiff(source, target)
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("LineDiff() =\n%s\nexpected\n%s", strings.Join(actual, "\n"), strings.Join(expected, "\n"))
+	}
+}
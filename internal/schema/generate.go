@@ -0,0 +1,295 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnsupportedMarker starts every comment written for a change that could not be generated.
+const UnsupportedMarker = "-- TODO(schemer): "
+
+// generation phases, statements are emitted phase by phase so that dependencies exist
+// before they are used and are removed only after nothing uses them
+const (
+	phaseDropConstraints = iota
+	phaseDropIndexes
+	phaseTypes
+	phaseSequences
+	phaseTables
+	phaseAddConstraints
+	phaseAddForeignKeys
+	phaseAddIndexes
+	phaseDropTables
+	phaseDropSequences
+	phaseDropTypes
+	phaseUnsupported
+	phaseCount
+)
+
+// GenerateMigration produces SQL that turns the source schema into the target schema and
+// SQL that reverts it. Tables, columns, constraints, indexes, sequences and enum types are
+// handled. Columns of a changed table are only altered when both snapshots were read by
+// Introspect, a snapshot file does not carry the column details.
+// Anything else is written as a comment starting with UnsupportedMarker.
+//
+// Params:
+//   - source: the schema the deltas currently produce
+//   - target: the desired schema
+//
+// Returns:
+//   - string: SQL for the up delta, empty if there are no differences
+//   - string: SQL for the down delta, empty if there are no differences
+func GenerateMigration(source, target *Snapshot) (string, string) {
+	return generateStatements(Diff(source, target)), generateStatements(Diff(target, source))
+}
+
+// generateStatements converts changes into SQL statements ordered by phase.
+func generateStatements(changes []Change) string {
+	phases := make([][]string, phaseCount)
+	add := func(phase int, statements ...string) {
+		phases[phase] = append(phases[phase], statements...)
+	}
+
+	for _, change := range changes {
+		switch change.Kind {
+		case KindTable:
+			switch change.Type {
+			case ChangeAdded:
+				add(phaseTables, change.Target)
+			case ChangeRemoved:
+				add(phaseDropTables, fmt.Sprintf("DROP TABLE %s;", change.Name))
+			case ChangeChanged:
+				if change.SourceColumns == nil || change.TargetColumns == nil {
+					add(phaseUnsupported, unsupported(change, "column changes are only generated when both schemas are read from a database"))
+					break
+				}
+				statements, ok := alterTable(change)
+				if !ok {
+					add(phaseUnsupported, unsupported(change, "only added, removed and altered columns can be generated"))
+				}
+				add(phaseTables, statements...)
+			}
+
+		case KindConstraint:
+			table, name := constraintParts(change)
+			if change.Type != ChangeAdded {
+				add(phaseDropConstraints, fmt.Sprintf("ALTER TABLE ONLY %s DROP CONSTRAINT %s;", table, name))
+			}
+			if change.Type != ChangeRemoved {
+				if strings.Contains(change.Target, " FOREIGN KEY ") {
+					add(phaseAddForeignKeys, change.Target)
+				} else {
+					add(phaseAddConstraints, change.Target)
+				}
+			}
+
+		case KindIndex:
+			if change.Type != ChangeAdded {
+				add(phaseDropIndexes, fmt.Sprintf("DROP INDEX %s;", change.Name))
+			}
+			if change.Type != ChangeRemoved {
+				add(phaseAddIndexes, change.Target)
+			}
+
+		case KindSequence:
+			switch change.Type {
+			case ChangeAdded:
+				// before the tables, a serial column defaults to nextval of its sequence
+				add(phaseSequences, change.Target)
+			case ChangeRemoved:
+				// dropping the table already dropped a sequence it owned
+				add(phaseDropSequences, fmt.Sprintf("DROP SEQUENCE IF EXISTS %s;", change.Name))
+			case ChangeChanged:
+				add(phaseUnsupported, unsupported(change, "only added and removed sequences can be generated"))
+			}
+
+		case KindEnum:
+			switch change.Type {
+			case ChangeAdded:
+				add(phaseTypes, change.Target)
+			case ChangeRemoved:
+				add(phaseDropTypes, fmt.Sprintf("DROP TYPE %s;", change.Name))
+			case ChangeChanged:
+				statements, ok := alterEnum(change)
+				if !ok {
+					add(phaseUnsupported, unsupported(change, "enum values can only be added, not removed or reordered"))
+				} else {
+					add(phaseTypes, statements...)
+				}
+			}
+
+		default:
+			add(phaseUnsupported, unsupported(change, string(change.Kind)+" changes are not generated"))
+		}
+	}
+
+	var blocks []string
+	for _, statements := range phases {
+		blocks = append(blocks, statements...)
+	}
+	if len(blocks) == 0 {
+		return ""
+	}
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+// unsupported writes a change that must be handled by hand as a commented block.
+func unsupported(change Change, reason string) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s%s %s was %s, %s. Write this change by hand.", UnsupportedMarker, change.Kind, change.Name, change.Type, reason)
+	if change.Target != "" {
+		builder.WriteString("\n-- Desired definition:")
+		for _, line := range strings.Split(strings.TrimSpace(change.Target), "\n") {
+			builder.WriteString("\n--   " + line)
+		}
+	}
+	return builder.String()
+}
+
+// alterTable generates column level changes from the columns introspected for both tables.
+//
+// Returns:
+//   - []string: the ALTER TABLE statements
+//   - bool: false if the tables differ in ways other than their columns
+func alterTable(change Change) ([]string, bool) {
+	sourceByName := make(map[string]Column, len(change.SourceColumns))
+	for _, col := range change.SourceColumns {
+		sourceByName[col.Name] = col
+	}
+	targetByName := make(map[string]Column, len(change.TargetColumns))
+	for _, col := range change.TargetColumns {
+		targetByName[col.Name] = col
+	}
+
+	var actions []string
+	for _, col := range change.TargetColumns {
+		existing, ok := sourceByName[col.Name]
+		if !ok {
+			actions = append(actions, "ADD COLUMN "+col.String())
+			continue
+		}
+		if existing.Identity != col.Identity || existing.Generated != col.Generated ||
+			(col.Generated != "" && existing.Default != col.Default) {
+			return nil, false
+		}
+		if existing.DataType != col.DataType {
+			actions = append(actions, fmt.Sprintf("ALTER COLUMN %s TYPE %s USING %s::%s", col.Name, col.DataType, col.Name, col.DataType))
+		}
+		if col.Generated == "" && existing.Default != col.Default {
+			if col.Default == "" {
+				actions = append(actions, fmt.Sprintf("ALTER COLUMN %s DROP DEFAULT", col.Name))
+			} else {
+				actions = append(actions, fmt.Sprintf("ALTER COLUMN %s SET DEFAULT %s", col.Name, col.Default))
+			}
+		}
+		if existing.NotNull != col.NotNull {
+			if col.NotNull {
+				actions = append(actions, fmt.Sprintf("ALTER COLUMN %s SET NOT NULL", col.Name))
+			} else {
+				actions = append(actions, fmt.Sprintf("ALTER COLUMN %s DROP NOT NULL", col.Name))
+			}
+		}
+	}
+	for _, col := range change.SourceColumns {
+		if _, ok := targetByName[col.Name]; !ok {
+			actions = append(actions, "DROP COLUMN "+col.Name)
+		}
+	}
+
+	if len(actions) == 0 {
+		// only the column order or table options differ
+		return nil, false
+	}
+	return []string{fmt.Sprintf("ALTER TABLE %s\n    %s;", change.Name, strings.Join(actions, ",\n    "))}, true
+}
+
+// constraintParts reads the table and constraint name from a constraint definition.
+func constraintParts(change Change) (string, string) {
+	definition := change.Source
+	if definition == "" {
+		definition = change.Target
+	}
+	lines := strings.Split(strings.TrimSpace(definition), "\n")
+	table := strings.TrimPrefix(lines[0], "ALTER TABLE ONLY ")
+	name := strings.TrimPrefix(change.Name, table+".")
+	return table, name
+}
+
+// alterEnum generates ALTER TYPE ... ADD VALUE statements for appended enum labels.
+//
+// Returns:
+//   - []string: the ALTER TYPE statements
+//   - bool: false if labels were removed or reordered
+func alterEnum(change Change) ([]string, bool) {
+	sourceLabels := enumLabels(change.Source)
+	targetLabels := enumLabels(change.Target)
+
+	position := make(map[string]int, len(targetLabels))
+	for i, label := range targetLabels {
+		position[label] = i
+	}
+	previous := -1
+	for _, label := range sourceLabels {
+		index, ok := position[label]
+		if !ok || index < previous {
+			return nil, false
+		}
+		previous = index
+	}
+
+	existing := make(map[string]bool, len(sourceLabels))
+	for _, label := range sourceLabels {
+		existing[label] = true
+	}
+
+	var statements []string
+	for i, label := range targetLabels {
+		if existing[label] {
+			continue
+		}
+		switch {
+		case i > 0:
+			statements = append(statements, fmt.Sprintf("ALTER TYPE %s ADD VALUE %s AFTER %s;", change.Name, label, targetLabels[i-1]))
+		case len(targetLabels) > 1:
+			statements = append(statements, fmt.Sprintf("ALTER TYPE %s ADD VALUE %s BEFORE %s;", change.Name, label, targetLabels[1]))
+		default:
+			statements = append(statements, fmt.Sprintf("ALTER TYPE %s ADD VALUE %s;", change.Name, label))
+		}
+		existing[label] = true
+	}
+	return statements, true
+}
+
+// enumLabels reads the quoted labels of a CREATE TYPE ... AS ENUM statement.
+func enumLabels(definition string) []string {
+	var labels []string
+	for _, line := range strings.Split(definition, "\n") {
+		line = strings.TrimSuffix(strings.TrimSpace(line), ",")
+		if strings.HasPrefix(line, "'") {
+			labels = append(labels, line)
+		}
+	}
+	return labels
+}
@@ -0,0 +1,154 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateMigration_Columns(t *testing.T) {
+	source := &Snapshot{Objects: []Object{
+		{Kind: KindTable, Name: "public.users", Definition: "CREATE TABLE public.users (\n    id integer NOT NULL,\n    name text,\n    legacy text\n);",
+			Columns: []Column{
+				{Name: "id", DataType: "integer", NotNull: true},
+				{Name: "name", DataType: "text"},
+				{Name: "legacy", DataType: "text"},
+			}},
+	}}
+	target := &Snapshot{Objects: []Object{
+		{Kind: KindTable, Name: "public.users", Definition: "CREATE TABLE public.users (\n    id bigint NOT NULL,\n    name text DEFAULT 'a DEFAULT b NOT NULL'::text NOT NULL,\n    email text\n);",
+			Columns: []Column{
+				{Name: "id", DataType: "bigint", NotNull: true},
+				{Name: "name", DataType: "text", NotNull: true, Default: "'a DEFAULT b NOT NULL'::text"},
+				{Name: "email", DataType: "text"},
+			}},
+	}}
+
+	up, down := GenerateMigration(source, target)
+	expectedUp := "ALTER TABLE public.users\n" +
+		"    ALTER COLUMN id TYPE bigint USING id::bigint,\n" +
+		"    ALTER COLUMN name SET DEFAULT 'a DEFAULT b NOT NULL'::text,\n" +
+		"    ALTER COLUMN name SET NOT NULL,\n" +
+		"    ADD COLUMN email text,\n" +
+		"    DROP COLUMN legacy;\n"
+	if up != expectedUp {
+		t.Fatalf("unexpected up SQL:\n%s\nexpected:\n%s", up, expectedUp)
+	}
+
+	expectedDown := "ALTER TABLE public.users\n" +
+		"    ALTER COLUMN id TYPE integer USING id::integer,\n" +
+		"    ALTER COLUMN name DROP DEFAULT,\n" +
+		"    ALTER COLUMN name DROP NOT NULL,\n" +
+		"    ADD COLUMN legacy text,\n" +
+		"    DROP COLUMN email;\n"
+	if down != expectedDown {
+		t.Fatalf("unexpected down SQL:\n%s\nexpected:\n%s", down, expectedDown)
+	}
+}
+
+func TestGenerateMigration_ColumnsFromSnapshotFile(t *testing.T) {
+	source := &Snapshot{Objects: []Object{
+		{Kind: KindTable, Name: "public.users", Definition: "CREATE TABLE public.users (\n    id integer\n);",
+			Columns: []Column{{Name: "id", DataType: "integer"}}},
+	}}
+	// Parse leaves Columns empty, the rendered definition is not read back into columns.
+	target := &Snapshot{Objects: []Object{
+		{Kind: KindTable, Name: "public.users", Definition: "CREATE TABLE public.users (\n    id bigint\n);"},
+	}}
+
+	up, _ := GenerateMigration(source, target)
+	if !strings.HasPrefix(up, UnsupportedMarker+"TABLE public.users was changed, column changes are only generated when both schemas are read from a database") {
+		t.Fatalf("expected the table change to be left for the user, got:\n%s", up)
+	}
+}
+
+func TestGenerateMigration_Ordering(t *testing.T) {
+	orders := "CREATE TABLE public.orders (\n    id integer NOT NULL,\n    user_id integer\n);"
+	primaryKey := "ALTER TABLE ONLY public.orders\n    ADD CONSTRAINT orders_pkey PRIMARY KEY (id);"
+	foreignKey := "ALTER TABLE ONLY public.orders\n    ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id);"
+	index := "CREATE INDEX orders_user_id_idx ON public.orders USING btree (user_id);"
+
+	source := &Snapshot{}
+	target := &Snapshot{Objects: []Object{
+		{Kind: KindIndex, Name: "public.orders_user_id_idx", Definition: index},
+		{Kind: KindConstraint, Name: "public.orders.orders_user_id_fkey", Definition: foreignKey},
+		{Kind: KindConstraint, Name: "public.orders.orders_pkey", Definition: primaryKey},
+		{Kind: KindTable, Name: "public.orders", Definition: orders},
+	}}
+
+	up, down := GenerateMigration(source, target)
+	expectedUp := strings.Join([]string{orders, primaryKey, foreignKey, index}, "\n\n") + "\n"
+	if up != expectedUp {
+		t.Fatalf("unexpected up SQL:\n%s\nexpected:\n%s", up, expectedUp)
+	}
+
+	expectedDown := strings.Join([]string{
+		"ALTER TABLE ONLY public.orders DROP CONSTRAINT orders_pkey;",
+		"ALTER TABLE ONLY public.orders DROP CONSTRAINT orders_user_id_fkey;",
+		"DROP INDEX public.orders_user_id_idx;",
+		"DROP TABLE public.orders;",
+	}, "\n\n") + "\n"
+	if down != expectedDown {
+		t.Fatalf("unexpected down SQL:\n%s\nexpected:\n%s", down, expectedDown)
+	}
+}
+
+func TestGenerateMigration_Enum(t *testing.T) {
+	source := &Snapshot{Objects: []Object{
+		{Kind: KindEnum, Name: "public.status", Definition: "CREATE TYPE public.status AS ENUM (\n    'active',\n    'closed'\n);"},
+	}}
+	target := &Snapshot{Objects: []Object{
+		{Kind: KindEnum, Name: "public.status", Definition: "CREATE TYPE public.status AS ENUM (\n    'pending',\n    'active',\n    'paused',\n    'closed'\n);"},
+	}}
+
+	up, down := GenerateMigration(source, target)
+	expectedUp := "ALTER TYPE public.status ADD VALUE 'pending' BEFORE 'active';\n\n" +
+		"ALTER TYPE public.status ADD VALUE 'paused' AFTER 'active';\n"
+	if up != expectedUp {
+		t.Fatalf("unexpected up SQL:\n%s\nexpected:\n%s", up, expectedUp)
+	}
+	if !strings.HasPrefix(down, UnsupportedMarker+"TYPE public.status was changed") {
+		t.Fatalf("expected removing enum values to be unsupported, got:\n%s", down)
+	}
+}
+
+func TestGenerateMigration_Unsupported(t *testing.T) {
+	source := &Snapshot{}
+	target := &Snapshot{Objects: []Object{
+		{Kind: KindView, Name: "public.active_users", Definition: "CREATE VIEW public.active_users AS\nSELECT 1;"},
+	}}
+
+	up, _ := GenerateMigration(source, target)
+	expected := UnsupportedMarker + "VIEW public.active_users was added, VIEW changes are not generated. Write this change by hand.\n" +
+		"-- Desired definition:\n" +
+		"--   CREATE VIEW public.active_users AS\n" +
+		"--   SELECT 1;\n"
+	if up != expected {
+		t.Fatalf("unexpected up SQL:\n%s\nexpected:\n%s", up, expected)
+	}
+
+	if up, down := GenerateMigration(target, target); up != "" || down != "" {
+		t.Fatalf("expected no SQL for identical snapshots, got %q and %q", up, down)
+	}
+}
+
+func TestGenerateMigration_SerialColumn(t *testing.T) {
+	sequence := "CREATE SEQUENCE public.users_id_seq\n    AS integer\n    START WITH 1\n    INCREMENT BY 1\n    MINVALUE 1\n    MAXVALUE 2147483647\n    CACHE 1;"
+	users := "CREATE TABLE public.users (\n    id integer DEFAULT nextval('public.users_id_seq'::regclass) NOT NULL\n);"
+
+	source := &Snapshot{}
+	target := &Snapshot{Objects: []Object{
+		{Kind: KindTable, Name: "public.users", Definition: users},
+		{Kind: KindSequence, Name: "public.users_id_seq", Definition: sequence},
+	}}
+
+	up, down := GenerateMigration(source, target)
+	expectedUp := sequence + "\n\n" + users + "\n"
+	if up != expectedUp {
+		t.Fatalf("unexpected up SQL:\n%s\nexpected:\n%s", up, expectedUp)
+	}
+
+	expectedDown := "DROP TABLE public.users;\n\nDROP SEQUENCE IF EXISTS public.users_id_seq;\n"
+	if down != expectedDown {
+		t.Fatalf("unexpected down SQL:\n%s\nexpected:\n%s", down, expectedDown)
+	}
+}
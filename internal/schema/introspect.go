@@ -0,0 +1,354 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package schema
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+// DefaultSchemas is used when no schemas are configured.
+var DefaultSchemas = []string{"public"}
+
+// notExtensionMember excludes objects created by an extension, they are covered
+// by the CREATE EXTENSION statement.
+const notExtensionMember = `NOT EXISTS (SELECT 1 FROM pg_depend dep WHERE dep.objid = %s AND dep.deptype = 'e')`
+
+type extensionRow struct {
+	Name   string
+	Schema string
+}
+
+type enumRow struct {
+	Name   string
+	Labels []string
+}
+
+type sequenceRow struct {
+	Name      string
+	DataType  string
+	Start     int64
+	Min       int64
+	Max       int64
+	Increment int64
+	Cycle     bool
+	Cache     int64
+}
+
+type tableRow struct {
+	Name         string
+	Kind         string
+	PartitionKey string
+	Parent       string
+	Bound        string
+}
+
+type columnRow struct {
+	Table     string
+	Name      string
+	DataType  string
+	NotNull   bool
+	Default   string
+	Identity  string
+	Generated string
+}
+
+// column drops the table name from the row.
+func (row columnRow) column() Column {
+	return Column{
+		Name:      row.Name,
+		DataType:  row.DataType,
+		NotNull:   row.NotNull,
+		Default:   row.Default,
+		Identity:  row.Identity,
+		Generated: row.Generated,
+	}
+}
+
+type constraintRow struct {
+	Table      string
+	Name       string
+	Definition string
+}
+
+type definitionRow struct {
+	Name       string
+	Kind       string
+	Definition string
+}
+
+// Introspect reads the catalog and builds a snapshot of the given schemas.
+// The schemer tracking tables and objects owned by extensions are left out.
+//
+// Params:
+//   - connection: pointer to a pgx.Conn for executing SQL statements
+//   - ctx: context for query execution
+//   - schemas: schemas to include, DefaultSchemas if empty
+//
+// Returns:
+//   - *Snapshot: the sorted snapshot
+//   - error: non-nil if any catalog query fails
+func Introspect(connection *pgx.Conn, ctx context.Context, schemas []string) (*Snapshot, error) {
+	if len(schemas) == 0 {
+		schemas = DefaultSchemas
+	}
+	snapshot := &Snapshot{Schemas: append([]string{}, schemas...)}
+
+	extensions, err := collect[extensionRow](connection, ctx, "0113", "extensions", `
+		SELECT quote_ident(e.extname), quote_ident(n.nspname)
+		FROM pg_extension e
+		JOIN pg_namespace n ON n.oid = e.extnamespace
+		WHERE n.nspname = ANY($1) AND e.extname <> 'plpgsql'`, schemas)
+	if err != nil {
+		return nil, err
+	}
+	for _, extension := range extensions {
+		snapshot.Objects = append(snapshot.Objects, Object{
+			Kind:       KindExtension,
+			Name:       extension.Name,
+			Definition: fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s WITH SCHEMA %s;", extension.Name, extension.Schema),
+		})
+	}
+
+	enums, err := collect[enumRow](connection, ctx, "0114", "enum types", `
+		SELECT quote_ident(n.nspname) || '.' || quote_ident(t.typname),
+		       array_agg(quote_literal(e.enumlabel) ORDER BY e.enumsortorder)
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		JOIN pg_enum e ON e.enumtypid = t.oid
+		WHERE n.nspname = ANY($1) AND `+fmt.Sprintf(notExtensionMember, "t.oid")+`
+		GROUP BY n.nspname, t.typname`, schemas)
+	if err != nil {
+		return nil, err
+	}
+	for _, enum := range enums {
+		snapshot.Objects = append(snapshot.Objects, Object{
+			Kind:       KindEnum,
+			Name:       enum.Name,
+			Definition: fmt.Sprintf("CREATE TYPE %s AS ENUM (\n    %s\n);", enum.Name, strings.Join(enum.Labels, ",\n    ")),
+		})
+	}
+
+	sequences, err := collect[sequenceRow](connection, ctx, "0115", "sequences", `
+		SELECT quote_ident(n.nspname) || '.' || quote_ident(c.relname), format_type(s.seqtypid, NULL),
+		       s.seqstart, s.seqmin, s.seqmax, s.seqincrement, s.seqcycle, s.seqcache
+		FROM pg_sequence s
+		JOIN pg_class c ON c.oid = s.seqrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = ANY($1)
+		  AND NOT EXISTS (SELECT 1 FROM pg_depend dep WHERE dep.objid = c.oid AND dep.deptype IN ('i', 'e'))
+		  AND NOT EXISTS (
+		      SELECT 1 FROM pg_depend dep
+		      JOIN pg_class owner ON owner.oid = dep.refobjid
+		      WHERE dep.objid = c.oid AND dep.classid = 'pg_class'::regclass
+		        AND dep.refclassid = 'pg_class'::regclass AND dep.deptype = 'a'
+		        AND owner.relname = ANY($2))`, schemas, utils.TrackingTables())
+	if err != nil {
+		return nil, err
+	}
+	for _, sequence := range sequences {
+		definition := fmt.Sprintf("CREATE SEQUENCE %s\n    AS %s\n    START WITH %d\n    INCREMENT BY %d\n    MINVALUE %d\n    MAXVALUE %d\n    CACHE %d",
+			sequence.Name, sequence.DataType, sequence.Start, sequence.Increment, sequence.Min, sequence.Max, sequence.Cache)
+		if sequence.Cycle {
+			definition += "\n    CYCLE"
+		}
+		snapshot.Objects = append(snapshot.Objects, Object{Kind: KindSequence, Name: sequence.Name, Definition: definition + ";"})
+	}
+
+	tableObjects, err := introspectTables(connection, ctx, schemas)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Objects = append(snapshot.Objects, tableObjects...)
+
+	constraints, err := collect[constraintRow](connection, ctx, "0118", "constraints", `
+		SELECT quote_ident(n.nspname) || '.' || quote_ident(c.relname), quote_ident(con.conname),
+		       pg_get_constraintdef(con.oid, true)
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = ANY($1) AND NOT (c.relname = ANY($2))
+		  AND con.contype IN ('p', 'u', 'f', 'c', 'x') AND con.conislocal
+		  AND `+fmt.Sprintf(notExtensionMember, "c.oid"), schemas, utils.TrackingTables())
+	if err != nil {
+		return nil, err
+	}
+	for _, constraint := range constraints {
+		snapshot.Objects = append(snapshot.Objects, Object{
+			Kind:       KindConstraint,
+			Name:       constraint.Table + "." + constraint.Name,
+			Definition: fmt.Sprintf("ALTER TABLE ONLY %s\n    ADD CONSTRAINT %s %s;", constraint.Table, constraint.Name, constraint.Definition),
+		})
+	}
+
+	indexes, err := collect[definitionRow](connection, ctx, "0119", "indexes", `
+		SELECT quote_ident(n.nspname) || '.' || quote_ident(i.relname), i.relkind::text, pg_get_indexdef(i.oid)
+		FROM pg_index x
+		JOIN pg_class i ON i.oid = x.indexrelid
+		JOIN pg_class t ON t.oid = x.indrelid
+		JOIN pg_namespace n ON n.oid = i.relnamespace
+		WHERE n.nspname = ANY($1) AND NOT (t.relname = ANY($2))
+		  AND NOT EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conindid = i.oid AND con.contype IN ('p', 'u', 'x'))
+		  AND `+fmt.Sprintf(notExtensionMember, "t.oid"), schemas, utils.TrackingTables())
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		snapshot.Objects = append(snapshot.Objects, Object{Kind: KindIndex, Name: index.Name, Definition: index.Definition + ";"})
+	}
+
+	views, err := collect[definitionRow](connection, ctx, "0120", "views", `
+		SELECT quote_ident(n.nspname) || '.' || quote_ident(c.relname), c.relkind::text, pg_get_viewdef(c.oid, true)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('v', 'm') AND n.nspname = ANY($1)
+		  AND `+fmt.Sprintf(notExtensionMember, "c.oid"), schemas)
+	if err != nil {
+		return nil, err
+	}
+	for _, view := range views {
+		statement := "CREATE VIEW"
+		if view.Kind == "m" {
+			statement = "CREATE MATERIALIZED VIEW"
+		}
+		body := strings.TrimSuffix(strings.TrimSpace(view.Definition), ";")
+		snapshot.Objects = append(snapshot.Objects, Object{
+			Kind:       KindView,
+			Name:       view.Name,
+			Definition: fmt.Sprintf("%s %s AS\n%s;", statement, view.Name, body),
+		})
+	}
+
+	functions, err := collect[definitionRow](connection, ctx, "0121", "functions", `
+		SELECT quote_ident(n.nspname) || '.' || quote_ident(p.proname) || '(' || pg_get_function_identity_arguments(p.oid) || ')',
+		       p.prokind::text, pg_get_functiondef(p.oid)
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE p.prokind IN ('f', 'p') AND n.nspname = ANY($1)
+		  AND `+fmt.Sprintf(notExtensionMember, "p.oid"), schemas)
+	if err != nil {
+		return nil, err
+	}
+	for _, function := range functions {
+		snapshot.Objects = append(snapshot.Objects, Object{
+			Kind:       KindFunction,
+			Name:       function.Name,
+			Definition: strings.TrimSpace(function.Definition) + ";",
+		})
+	}
+
+	snapshot.Sort()
+	return snapshot, nil
+}
+
+// introspectTables builds CREATE TABLE statements with columns in their physical order.
+// Constraints and indexes are reported as separate objects so they diff independently.
+func introspectTables(connection *pgx.Conn, ctx context.Context, schemas []string) ([]Object, error) {
+	tables, err := collect[tableRow](connection, ctx, "0116", "tables", `
+		SELECT quote_ident(n.nspname) || '.' || quote_ident(c.relname), c.relkind::text,
+		       COALESCE(pg_get_partkeydef(c.oid), ''),
+		       COALESCE((SELECT quote_ident(pn.nspname) || '.' || quote_ident(pc.relname)
+		                 FROM pg_inherits i
+		                 JOIN pg_class pc ON pc.oid = i.inhparent
+		                 JOIN pg_namespace pn ON pn.oid = pc.relnamespace
+		                 WHERE i.inhrelid = c.oid AND c.relispartition), ''),
+		       COALESCE(pg_get_expr(c.relpartbound, c.oid), '')
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND n.nspname = ANY($1) AND NOT (c.relname = ANY($2))
+		  AND `+fmt.Sprintf(notExtensionMember, "c.oid"), schemas, utils.TrackingTables())
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := collect[columnRow](connection, ctx, "0117", "columns", `
+		SELECT quote_ident(n.nspname) || '.' || quote_ident(c.relname), quote_ident(a.attname),
+		       format_type(a.atttypid, a.atttypmod), a.attnotnull,
+		       COALESCE(pg_get_expr(d.adbin, d.adrelid), ''), a.attidentity::text, a.attgenerated::text
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE c.relkind IN ('r', 'p') AND n.nspname = ANY($1) AND NOT (c.relname = ANY($2))
+		  AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attrelid, a.attnum`, schemas, utils.TrackingTables())
+	if err != nil {
+		return nil, err
+	}
+
+	columnsByTable := make(map[string][]Column)
+	for _, column := range columns {
+		columnsByTable[column.Table] = append(columnsByTable[column.Table], column.column())
+	}
+
+	objects := make([]Object, 0, len(tables))
+	for _, table := range tables {
+		object := Object{Kind: KindTable, Name: table.Name}
+		if table.Parent != "" {
+			object.Definition = fmt.Sprintf("CREATE TABLE %s PARTITION OF %s\n    %s", table.Name, table.Parent, table.Bound)
+		} else {
+			tableColumns := columnsByTable[table.Name]
+			rendered := make([]string, 0, len(tableColumns))
+			for _, column := range tableColumns {
+				rendered = append(rendered, column.String())
+			}
+			object.Definition = "CREATE TABLE " + table.Name + " ("
+			if len(rendered) > 0 {
+				object.Definition += "\n    " + strings.Join(rendered, ",\n    ") + "\n"
+			}
+			object.Definition += ")"
+			if table.PartitionKey == "" {
+				// partitioned tables are left without columns, their changes are not generated
+				object.Columns = append([]Column{}, tableColumns...)
+			}
+		}
+		if table.PartitionKey != "" {
+			object.Definition += "\nPARTITION BY " + table.PartitionKey
+		}
+		object.Definition += ";"
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// collect runs a catalog query and scans each row into T by column position.
+func collect[T any](connection *pgx.Conn, ctx context.Context, code string, what string, query string, args ...any) ([]T, error) {
+	rows, err := connection.Query(ctx, query, args...)
+	if err == nil {
+		var result []T
+		if result, err = pgx.CollectRows(rows, pgx.RowToStructByPos[T]); err == nil {
+			return result, nil
+		}
+	}
+	return nil, &errschemer.SchemerErr{
+		Code:    code,
+		Message: "failed to read " + what + " from the catalog",
+		Err:     err,
+	}
+}
@@ -0,0 +1,262 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Package schema builds deterministic schema snapshots from the PostgreSQL catalog.
+package schema
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/inskribe/schemer/internal/errschemer"
+)
+
+// Kind is the type of a schema object.
+type Kind string
+
+const (
+	KindExtension  Kind = "EXTENSION"
+	KindEnum       Kind = "TYPE"
+	KindSequence   Kind = "SEQUENCE"
+	KindTable      Kind = "TABLE"
+	KindConstraint Kind = "CONSTRAINT"
+	KindIndex      Kind = "INDEX"
+	KindView       Kind = "VIEW"
+	KindFunction   Kind = "FUNCTION"
+)
+
+// kindOrder is the order objects are written in, so that dependencies come first.
+var kindOrder = map[Kind]int{
+	KindExtension:  0,
+	KindEnum:       1,
+	KindSequence:   2,
+	KindTable:      3,
+	KindConstraint: 4,
+	KindIndex:      5,
+	KindView:       6,
+	KindFunction:   7,
+}
+
+const (
+	snapshotHeader = "-- Schema snapshot generated by schemer. Do not edit by hand."
+	schemasPrefix  = "-- Schemas: "
+	objectPrefix   = "-- Type: "
+	namePrefix     = "; Name: "
+)
+
+// Object is a single schema object and the SQL that defines it.
+type Object struct {
+	Kind       Kind     // type of the object
+	Name       string   // schema qualified name, unique within its kind
+	Definition string   // SQL definition as reported by the catalog
+	Columns    []Column // columns of a plain table in physical order, nil unless read by Introspect
+}
+
+// Column is a table column as reported by the catalog.
+type Column struct {
+	Name      string // quoted column name
+	DataType  string // type as formatted by format_type, including the type modifier
+	NotNull   bool   // true if the column is NOT NULL
+	Default   string // default expression, or the expression of a generated column
+	Identity  string // "a" for GENERATED ALWAYS, "d" for GENERATED BY DEFAULT, empty otherwise
+	Generated string // "s" for a stored generated column, empty otherwise
+}
+
+// String formats the column as it appears in CREATE TABLE and ADD COLUMN.
+func (c Column) String() string {
+	definition := c.Name + " " + c.DataType
+	switch {
+	case c.Generated == "s":
+		definition += " GENERATED ALWAYS AS (" + c.Default + ") STORED"
+	case c.Identity == "a":
+		definition += " GENERATED ALWAYS AS IDENTITY"
+	case c.Identity == "d":
+		definition += " GENERATED BY DEFAULT AS IDENTITY"
+	case c.Default != "":
+		definition += " DEFAULT " + c.Default
+	}
+	if c.NotNull {
+		definition += " NOT NULL"
+	}
+	return definition
+}
+
+// Key returns the identity of the object, used to match objects between snapshots.
+func (o Object) Key() string {
+	return string(o.Kind) + " " + o.Name
+}
+
+// Snapshot is the set of objects found in the included schemas.
+type Snapshot struct {
+	Schemas []string // schemas the snapshot was taken from
+	Objects []Object // objects sorted by kind and name
+}
+
+// Sort orders the objects by kind and name so rendering is deterministic.
+func (s *Snapshot) Sort() {
+	sort.Strings(s.Schemas)
+	sort.SliceStable(s.Objects, func(i, j int) bool {
+		left, right := s.Objects[i], s.Objects[j]
+		if left.Kind != right.Kind {
+			return kindOrder[left.Kind] < kindOrder[right.Kind]
+		}
+		return left.Name < right.Name
+	})
+}
+
+// Render returns the snapshot as SQL. Each object is preceded by a header comment
+// naming it, which is what Parse uses to read the snapshot back.
+//
+// Returns:
+//   - string: the snapshot as SQL
+func (s *Snapshot) Render() string {
+	var builder strings.Builder
+	builder.WriteString(snapshotHeader + "\n")
+	builder.WriteString(schemasPrefix + strings.Join(s.Schemas, ", ") + "\n")
+	for _, object := range s.Objects {
+		builder.WriteString("\n" + objectPrefix + string(object.Kind) + namePrefix + object.Name + "\n")
+		builder.WriteString(strings.TrimSpace(object.Definition) + "\n")
+	}
+	return builder.String()
+}
+
+// WriteFile renders the snapshot to path, creating parent directories as needed.
+//
+// Params:
+//   - path: destination of the snapshot
+//
+// Returns:
+//   - error: non-nil if the file cannot be written
+func (s *Snapshot) WriteFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return &errschemer.SchemerErr{
+			Code:    "0107",
+			Message: "failed to create directory for snapshot: " + path,
+			Err:     err,
+		}
+	}
+	if err := os.WriteFile(path, []byte(s.Render()), 0o644); err != nil {
+		return &errschemer.SchemerErr{
+			Code:    "0108",
+			Message: "failed to write snapshot: " + path,
+			Err:     err,
+		}
+	}
+	return nil
+}
+
+// Parse reads a snapshot produced by Render.
+//
+// Params:
+//   - data: snapshot contents
+//
+// Returns:
+//   - *Snapshot: the parsed snapshot, sorted
+//   - error: non-nil if data is not a schemer snapshot
+func Parse(data string) (*Snapshot, error) {
+	snapshot := &Snapshot{}
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+
+	var current *Object
+	var definition []string
+	flush := func() {
+		if current != nil {
+			current.Definition = strings.TrimSpace(strings.Join(definition, "\n"))
+			snapshot.Objects = append(snapshot.Objects, *current)
+		}
+		current, definition = nil, nil
+	}
+
+	headerSeen := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case !headerSeen:
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if line != snapshotHeader {
+				return nil, &errschemer.SchemerErr{
+					Code:    "0109",
+					Message: "file is not a schemer snapshot, expected first line: " + snapshotHeader,
+				}
+			}
+			headerSeen = true
+		case current == nil && strings.HasPrefix(line, schemasPrefix):
+			for _, name := range strings.Split(strings.TrimPrefix(line, schemasPrefix), ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					snapshot.Schemas = append(snapshot.Schemas, name)
+				}
+			}
+		case strings.HasPrefix(line, objectPrefix) && strings.Contains(line, namePrefix):
+			flush()
+			kind, name, _ := strings.Cut(strings.TrimPrefix(line, objectPrefix), namePrefix)
+			current = &Object{Kind: Kind(kind), Name: name}
+		case current != nil:
+			definition = append(definition, line)
+		}
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil || !headerSeen {
+		return nil, &errschemer.SchemerErr{
+			Code:    "0110",
+			Message: "failed to read snapshot, file is empty or unreadable",
+			Err:     err,
+		}
+	}
+
+	snapshot.Sort()
+	return snapshot, nil
+}
+
+// ReadFile reads and parses a snapshot file.
+//
+// Params:
+//   - path: location of the snapshot
+//
+// Returns:
+//   - *Snapshot: the parsed snapshot
+//   - error: non-nil if the file cannot be read or parsed
+func ReadFile(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &errschemer.SchemerErr{
+			Code:    "0111",
+			Message: "failed to read snapshot: " + path,
+			Err:     err,
+		}
+	}
+	snapshot, err := Parse(string(data))
+	if err != nil {
+		return nil, &errschemer.SchemerErr{
+			Code:    "0112",
+			Message: "invalid snapshot: " + path,
+			Err:     err,
+		}
+	}
+	return snapshot, nil
+}
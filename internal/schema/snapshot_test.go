@@ -0,0 +1,68 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderParse(t *testing.T) {
+	snapshot := &Snapshot{
+		Schemas: []string{"public", "billing"},
+		Objects: []Object{
+			{Kind: KindIndex, Name: "public.users_email_idx", Definition: "CREATE INDEX users_email_idx ON public.users USING btree (email);"},
+			{Kind: KindTable, Name: "public.users", Definition: "CREATE TABLE public.users (\n    id integer NOT NULL,\n\n    email text\n);"},
+			{Kind: KindExtension, Name: "pgcrypto", Definition: "CREATE EXTENSION IF NOT EXISTS pgcrypto WITH SCHEMA public;"},
+			{Kind: KindTable, Name: "billing.invoices", Definition: "CREATE TABLE billing.invoices (\n);"},
+		},
+	}
+	snapshot.Sort()
+
+	rendered := snapshot.Render()
+	if !strings.HasPrefix(rendered, snapshotHeader+"\n-- Schemas: billing, public\n") {
+		t.Fatalf("unexpected header:\n%s", rendered)
+	}
+
+	var order []string
+	for _, object := range snapshot.Objects {
+		order = append(order, object.Key())
+	}
+	if strings.Join(order, "|") != "EXTENSION pgcrypto|TABLE billing.invoices|TABLE public.users|INDEX public.users_email_idx" {
+		t.Fatalf("unexpected order: %v", order)
+	}
+
+	parsed, err := Parse(rendered)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if parsed.Render() != rendered {
+		t.Fatalf("expected round trip to be stable, got:\n%s", parsed.Render())
+	}
+	if parsed.Objects[2].Definition != snapshot.Objects[2].Definition {
+		t.Fatalf("definition changed in round trip: %q", parsed.Objects[2].Definition)
+	}
+}
+
+func TestParse_NotSnapshot(t *testing.T) {
+	for _, input := range []string{"", "CREATE TABLE a ();"} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("expected error for input %q", input)
+		}
+	}
+}
+
+func TestColumnString(t *testing.T) {
+	testCases := []struct {
+		column   Column
+		expected string
+	}{
+		{Column{Name: "id", DataType: "bigint", NotNull: true, Identity: "d"}, "id bigint GENERATED BY DEFAULT AS IDENTITY NOT NULL"},
+		{Column{Name: "total", DataType: "numeric(10,2)", Generated: "s", Default: "(price * qty)"}, "total numeric(10,2) GENERATED ALWAYS AS ((price * qty)) STORED"},
+		{Column{Name: "created_at", DataType: "timestamp without time zone", Default: "now()"}, "created_at timestamp without time zone DEFAULT now()"},
+		{Column{Name: `"Name"`, DataType: "text"}, `"Name" text`},
+	}
+	for _, tc := range testCases {
+		if actual := tc.column.String(); actual != tc.expected {
+			t.Errorf("String() = %q, expected %q", actual, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Package scratch creates and drops throwaway databases.
+package scratch
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/utils"
+)
+
+// NamePrefix starts the name of every scratch database.
+const NamePrefix = "schemer_scratch_"
+
+// Database is a throwaway database created on the server of an admin connection.
+type Database struct {
+	Name       string // generated database name
+	ConnString string // connection string for the scratch database
+	admin      string // connection string used to create and drop the database
+}
+
+// Options controls how a scratch database is created.
+type Options struct {
+	Template string                                 // optional database to clone, it must have no open connections
+	Setup    func(*pgx.Conn, context.Context) error // optional function run against the new database, e.g. to apply deltas
+}
+
+// Create creates a uniquely named database on the server of adminConnString.
+// The role used by adminConnString needs the CREATEDB privilege.
+// If options.Setup fails the database is dropped again.
+//
+// Params:
+//   - adminConnString: connection string of any database on the target server
+//   - options: optional template and setup function
+//
+// Returns:
+//   - *Database: the created database
+//   - error: non-nil if the connection string is invalid, the database cannot be created or setup fails
+func Create(adminConnString string, options Options) (*Database, error) {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, &errschemer.SchemerErr{
+			Code:    "0128",
+			Message: "failed to generate scratch database name",
+			Err:     err,
+		}
+	}
+	name := NamePrefix + hex.EncodeToString(suffix)
+
+	connString, err := WithDatabase(adminConnString, name)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := "CREATE DATABASE " + pgx.Identifier{name}.Sanitize()
+	message := "failed to create scratch database " + name + ", the role needs the CREATEDB privilege"
+	if options.Template != "" {
+		statement += " TEMPLATE " + pgx.Identifier{options.Template}.Sanitize()
+		message += " and the template " + options.Template + " must have no open connections"
+	}
+
+	err = utils.WithConn(adminConnString, func(connection *pgx.Conn, ctx context.Context) error {
+		if _, err := connection.Exec(ctx, statement); err != nil {
+			return &errschemer.SchemerErr{
+				Code:    "0129",
+				Message: message,
+				Err:     err,
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	database := &Database{Name: name, ConnString: connString, admin: adminConnString}
+	if options.Setup == nil {
+		return database, nil
+	}
+
+	if err := utils.WithConn(connString, options.Setup); err != nil {
+		setupErr := &errschemer.SchemerErr{
+			Code:    "0136",
+			Message: "failed to set up scratch database " + name,
+			Err:     err,
+		}
+		if dropErr := database.Drop(); dropErr != nil {
+			return nil, fmt.Errorf("drop error: %v\nsetup error: %w", dropErr, setupErr)
+		}
+		return nil, setupErr
+	}
+	return database, nil
+}
+
+// Existing returns a previously created scratch database so that it can be dropped.
+//
+// Params:
+//   - adminConnString: connection string of any database on the server holding the database
+//   - name: name of the scratch database, it must start with NamePrefix
+//
+// Returns:
+//   - *Database: the scratch database
+//   - error: non-nil if name is not a scratch database name or the connection string is invalid
+func Existing(adminConnString string, name string) (*Database, error) {
+	if !strings.HasPrefix(name, NamePrefix) {
+		return nil, &errschemer.SchemerErr{
+			Code:    "0138",
+			Message: fmt.Sprintf("%s is not a scratch database, names start with %s", name, NamePrefix),
+		}
+	}
+	connString, err := WithDatabase(adminConnString, name)
+	if err != nil {
+		return nil, err
+	}
+	return &Database{Name: name, ConnString: connString, admin: adminConnString}, nil
+}
+
+// List returns the names of all scratch databases on the server of adminConnString.
+//
+// Params:
+//   - adminConnString: connection string of any database on the target server
+//
+// Returns:
+//   - []string: scratch database names in alphabetical order
+//   - error: non-nil if the server cannot be queried
+func List(adminConnString string) ([]string, error) {
+	var names []string
+	err := utils.WithConn(adminConnString, func(connection *pgx.Conn, ctx context.Context) error {
+		rows, err := connection.Query(ctx, `SELECT datname FROM pg_database WHERE starts_with(datname, $1) ORDER BY datname`, NamePrefix)
+		if err == nil {
+			names, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		}
+		if err != nil {
+			return &errschemer.SchemerErr{
+				Code:    "0137",
+				Message: "failed to list scratch databases",
+				Err:     err,
+			}
+		}
+		return nil
+	})
+	return names, err
+}
+
+// Drop removes the database, disconnecting any remaining sessions.
+// Sessions are terminated with pg_terminate_backend rather than DROP DATABASE ... WITH (FORCE),
+// which only exists since PostgreSQL 13.
+//
+// Returns:
+//   - error: non-nil if the sessions cannot be terminated or the database cannot be dropped
+func (d *Database) Drop() error {
+	return utils.WithConn(d.admin, func(connection *pgx.Conn, ctx context.Context) error {
+		if _, err := connection.Exec(ctx,
+			`SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()`, d.Name); err != nil {
+			return &errschemer.SchemerErr{
+				Code:    "0222",
+				Message: "failed to disconnect the sessions of scratch database " + d.Name,
+				Err:     err,
+			}
+		}
+		if _, err := connection.Exec(ctx, "DROP DATABASE IF EXISTS "+pgx.Identifier{d.Name}.Sanitize()); err != nil {
+			return &errschemer.SchemerErr{
+				Code:    "0130",
+				Message: "failed to drop scratch database " + d.Name,
+				Err:     err,
+			}
+		}
+		return nil
+	})
+}
+
+// WithDatabase returns connString pointing at database name instead of its own database.
+//
+// Params:
+//   - connString: URL or keyword/value connection string
+//   - name: database to connect to
+//
+// Returns:
+//   - string: the rewritten connection string
+//   - error: non-nil if connString is not a valid connection string
+func WithDatabase(connString string, name string) (string, error) {
+	if strings.HasPrefix(connString, "postgres://") || strings.HasPrefix(connString, "postgresql://") {
+		parsed, err := url.Parse(connString)
+		if err != nil {
+			return "", &errschemer.SchemerErr{
+				Code:    "0131",
+				Message: "failed to parse connection string",
+				Err:     err,
+			}
+		}
+		parsed.Path = "/" + name
+		return parsed.String(), nil
+	}
+
+	// later keywords override earlier ones in keyword/value connection strings
+	return strings.TrimSpace(connString) + " dbname=" + name, nil
+}
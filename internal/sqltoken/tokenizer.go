@@ -0,0 +1,304 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Package sqltoken splits PostgreSQL source into tokens.
+// It understands comments, quoted strings and identifiers, dollar quoting and
+// statement boundaries well enough to reason about delta files without a database.
+package sqltoken
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Kind identifies the type of a token.
+type Kind int
+
+const (
+	Whitespace   Kind = iota // spaces, tabs and newlines
+	LineComment              // -- comment to end of line
+	BlockComment             // /* comment */, may be nested
+	Word                     // keyword or unquoted identifier
+	QuotedIdent              // "quoted identifier"
+	String                   // 'string', E'string' or B'string'
+	DollarString             // $tag$ string $tag$
+	Number                   // numeric literal
+	Parameter                // $1 positional parameter
+	Punct                    // operators and punctuation other than semicolons
+	Semicolon                // statement terminator
+)
+
+// Token is a single lexical element of SQL source.
+type Token struct {
+	Kind Kind   // type of the token
+	Text string // exact source text of the token
+	Line int    // 1-based line the token starts on
+}
+
+// IsComment reports whether the token is a line or block comment.
+func (t Token) IsComment() bool {
+	return t.Kind == LineComment || t.Kind == BlockComment
+}
+
+// IsTrivia reports whether the token has no effect on execution.
+func (t Token) IsTrivia() bool {
+	return t.Kind == Whitespace || t.IsComment()
+}
+
+// IsKeyword reports whether the token is an unquoted word equal to keyword, ignoring case.
+func (t Token) IsKeyword(keyword string) bool {
+	return t.Kind == Word && strings.EqualFold(t.Text, keyword)
+}
+
+// Tokenize splits sql into tokens. Unterminated strings and comments run to the end
+// of the input rather than failing, the database will report them when executed.
+//
+// Params:
+//   - sql: PostgreSQL source
+//
+// Returns:
+//   - []Token: every token in order, including whitespace and comments
+func Tokenize(sql string) []Token {
+	scanner := scanner{input: []rune(sql), line: 1}
+	var tokens []Token
+	for scanner.pos < len(scanner.input) {
+		tokens = append(tokens, scanner.next())
+	}
+	return tokens
+}
+
+// Statements splits tokens into statements at top level semicolons.
+// Trivia is dropped and empty statements are skipped.
+//
+// Params:
+//   - tokens: output of Tokenize
+//
+// Returns:
+//   - [][]Token: significant tokens of each statement, without the terminating semicolon
+func Statements(tokens []Token) [][]Token {
+	var result [][]Token
+	var current []Token
+	for _, token := range tokens {
+		if token.IsTrivia() {
+			continue
+		}
+		if token.Kind == Semicolon {
+			if len(current) > 0 {
+				result = append(result, current)
+			}
+			current = nil
+			continue
+		}
+		current = append(current, token)
+	}
+	if len(current) > 0 {
+		result = append(result, current)
+	}
+	return result
+}
+
+// Comments returns every comment in tokens with the comment markers and surrounding
+// whitespace removed from the text.
+func Comments(tokens []Token) []Token {
+	var result []Token
+	for _, token := range tokens {
+		switch token.Kind {
+		case LineComment:
+			result = append(result, Token{Kind: LineComment, Text: strings.TrimSpace(strings.TrimPrefix(token.Text, "--")), Line: token.Line})
+		case BlockComment:
+			text := strings.TrimSuffix(strings.TrimPrefix(token.Text, "/*"), "*/")
+			result = append(result, Token{Kind: BlockComment, Text: strings.TrimSpace(text), Line: token.Line})
+		}
+	}
+	return result
+}
+
+type scanner struct {
+	input []rune
+	pos   int
+	line  int
+}
+
+func (s *scanner) peek(offset int) rune {
+	if s.pos+offset >= len(s.input) {
+		return 0
+	}
+	return s.input[s.pos+offset]
+}
+
+func (s *scanner) emit(kind Kind, start int, line int) Token {
+	text := string(s.input[start:s.pos])
+	s.line += strings.Count(text, "\n")
+	return Token{Kind: kind, Text: text, Line: line}
+}
+
+func (s *scanner) next() Token {
+	start, line := s.pos, s.line
+	current := s.peek(0)
+
+	switch {
+	case unicode.IsSpace(current):
+		for s.pos < len(s.input) && unicode.IsSpace(s.input[s.pos]) {
+			s.pos++
+		}
+		return s.emit(Whitespace, start, line)
+
+	case current == '-' && s.peek(1) == '-':
+		for s.pos < len(s.input) && s.input[s.pos] != '\n' {
+			s.pos++
+		}
+		return s.emit(LineComment, start, line)
+
+	case current == '/' && s.peek(1) == '*':
+		s.pos += 2
+		depth := 1
+		for s.pos < len(s.input) && depth > 0 {
+			if s.peek(0) == '/' && s.peek(1) == '*' {
+				depth++
+				s.pos += 2
+			} else if s.peek(0) == '*' && s.peek(1) == '/' {
+				depth--
+				s.pos += 2
+			} else {
+				s.pos++
+			}
+		}
+		return s.emit(BlockComment, start, line)
+
+	case current == '\'':
+		s.scanQuoted('\'', false)
+		return s.emit(String, start, line)
+
+	case (current == 'E' || current == 'e') && s.peek(1) == '\'':
+		s.pos++
+		s.scanQuoted('\'', true)
+		return s.emit(String, start, line)
+
+	case (current == 'B' || current == 'b' || current == 'X' || current == 'x' ||
+		current == 'N' || current == 'n') && s.peek(1) == '\'':
+		s.pos++
+		s.scanQuoted('\'', false)
+		return s.emit(String, start, line)
+
+	case current == '"':
+		s.scanQuoted('"', false)
+		return s.emit(QuotedIdent, start, line)
+
+	case current == '$':
+		if unicode.IsDigit(s.peek(1)) {
+			s.pos++
+			for s.pos < len(s.input) && unicode.IsDigit(s.input[s.pos]) {
+				s.pos++
+			}
+			return s.emit(Parameter, start, line)
+		}
+		if delimiter, ok := s.dollarDelimiter(); ok {
+			s.pos += len(delimiter)
+			for s.pos < len(s.input) && !s.hasPrefix(delimiter) {
+				s.pos++
+			}
+			s.pos = min(s.pos+len(delimiter), len(s.input))
+			return s.emit(DollarString, start, line)
+		}
+		s.pos++
+		return s.emit(Punct, start, line)
+
+	case current == ';':
+		s.pos++
+		return s.emit(Semicolon, start, line)
+
+	case unicode.IsDigit(current) || (current == '.' && unicode.IsDigit(s.peek(1))):
+		for s.pos < len(s.input) && (unicode.IsDigit(s.input[s.pos]) || s.input[s.pos] == '.' ||
+			s.input[s.pos] == 'e' || s.input[s.pos] == 'E' || s.input[s.pos] == '_') {
+			s.pos++
+		}
+		return s.emit(Number, start, line)
+
+	case isWordStart(current):
+		for s.pos < len(s.input) && isWordPart(s.input[s.pos]) {
+			s.pos++
+		}
+		return s.emit(Word, start, line)
+	}
+
+	s.pos++
+	return s.emit(Punct, start, line)
+}
+
+// scanQuoted consumes a quoted string or identifier starting at the opening quote.
+// A doubled quote is an escaped quote, backslash escapes are honoured if requested.
+func (s *scanner) scanQuoted(quote rune, backslashEscapes bool) {
+	s.pos++
+	for s.pos < len(s.input) {
+		current := s.input[s.pos]
+		if backslashEscapes && current == '\\' {
+			s.pos += 2
+			continue
+		}
+		if current == quote {
+			if s.peek(1) == quote {
+				s.pos += 2
+				continue
+			}
+			s.pos++
+			return
+		}
+		s.pos++
+	}
+	s.pos = min(s.pos, len(s.input))
+}
+
+// hasPrefix reports whether the input at the current position starts with prefix.
+func (s *scanner) hasPrefix(prefix []rune) bool {
+	if s.pos+len(prefix) > len(s.input) {
+		return false
+	}
+	for i, r := range prefix {
+		if s.input[s.pos+i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// dollarDelimiter returns the $tag$ delimiter starting at the current position.
+func (s *scanner) dollarDelimiter() ([]rune, bool) {
+	end := s.pos + 1
+	for end < len(s.input) && s.input[end] != '$' {
+		if !isWordStart(s.input[end]) && !unicode.IsDigit(s.input[end]) {
+			return nil, false
+		}
+		end++
+	}
+	if end >= len(s.input) {
+		return nil, false
+	}
+	return s.input[s.pos : end+1], true
+}
+
+func isWordStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isWordPart(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
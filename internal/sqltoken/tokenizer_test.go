@@ -0,0 +1,105 @@
+package sqltoken
+
+import (
+	"testing"
+)
+
+func significant(tokens []Token) []string {
+	var result []string
+	for _, token := range tokens {
+		if !token.IsTrivia() {
+			result = append(result, token.Text)
+		}
+	}
+	return result
+}
+
+func TestTokenize(t *testing.T) {
+	mockData := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "keywords and punctuation",
+			input:    "CREATE TABLE a (id INT);",
+			expected: []string{"CREATE", "TABLE", "a", "(", "id", "INT", ")", ";"},
+		},
+		{
+			name:     "nested block comment",
+			input:    "/* outer /* inner */ still comment */ SELECT 1",
+			expected: []string{"SELECT", "1"},
+		},
+		{
+			name:     "comment markers inside strings",
+			input:    "SELECT '-- not a comment', '/* nor this */'",
+			expected: []string{"SELECT", "'-- not a comment'", ",", "'/* nor this */'"},
+		},
+		{
+			name:     "escaped quotes",
+			input:    `SELECT 'it''s', E'a\'b', "odd""name"`,
+			expected: []string{"SELECT", "'it''s'", ",", `E'a\'b'`, ",", `"odd""name"`},
+		},
+		{
+			name:     "dollar quoted body",
+			input:    "DO $body$ BEGIN PERFORM 1; END $body$; SELECT $$;$$, $1",
+			expected: []string{"DO", "$body$ BEGIN PERFORM 1; END $body$", ";", "SELECT", "$$;$$", ",", "$1"},
+		},
+		{
+			name:     "unterminated string",
+			input:    "SELECT 'abc",
+			expected: []string{"SELECT", "'abc"},
+		},
+	}
+
+	for _, mock := range mockData {
+		t.Run(mock.name, func(t *testing.T) {
+			result := significant(Tokenize(mock.input))
+			if len(result) != len(mock.expected) {
+				t.Fatalf("Tokenize() = %q, expected %q", result, mock.expected)
+			}
+			for i := range result {
+				if result[i] != mock.expected[i] {
+					t.Fatalf("Tokenize() = %q, expected %q", result, mock.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenize_Lines(t *testing.T) {
+	tokens := Tokenize("-- header\n/* a\nb */\nSELECT\n  'x\ny' ,\n1")
+	lines := map[string]int{}
+	for _, token := range tokens {
+		if !token.IsTrivia() {
+			lines[token.Text] = token.Line
+		}
+	}
+	if lines["SELECT"] != 4 || lines[","] != 6 || lines["1"] != 7 {
+		t.Fatalf("unexpected token lines: %v", lines)
+	}
+}
+
+func TestStatements(t *testing.T) {
+	input := `
+-- leading comment;
+CREATE FUNCTION f() RETURNS void AS $$ BEGIN NULL; END $$ LANGUAGE plpgsql;
+;
+INSERT INTO a VALUES ('x;y');
+SELECT 1`
+
+	statements := Statements(Tokenize(input))
+	if len(statements) != 3 {
+		t.Fatalf("expected 3 statements, got %d", len(statements))
+	}
+	if !statements[0][0].IsKeyword("create") || !statements[1][0].IsKeyword("INSERT") || !statements[2][0].IsKeyword("select") {
+		t.Fatalf("unexpected statement starts: %q, %q, %q", statements[0][0].Text, statements[1][0].Text, statements[2][0].Text)
+	}
+}
+
+func TestComments(t *testing.T) {
+	comments := Comments(Tokenize("-- first\nSELECT '-- no';\n/* second */"))
+	if len(comments) != 2 || comments[0].Text != "first" || comments[1].Text != "second" || comments[1].Line != 3 {
+		t.Fatalf("unexpected comments: %+v", comments)
+	}
+}
@@ -1,5 +1,5 @@
 CREATE TABLE IF NOT EXISTS {{.TableName}} (
-  tag INTEGER PRIMARY KEY,
+  tag BIGINT PRIMARY KEY,
   post_status INTEGER DEFAULT 0,
   applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
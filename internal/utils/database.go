@@ -27,6 +27,8 @@ import (
 	"database/sql"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -65,8 +67,21 @@ func ConnectDatabase(connString string) (*sql.DB, error) {
 	return DB, nil
 }
 
+// ConnectionSettings are applied to every connection opened by WithConn.
+// A zero duration leaves the driver or server default in place.
+// Statements are traced when Trace is enabled, notices are always logged.
+type ConnectionSettings struct {
+	ConnectTimeout   time.Duration // how long to wait for the connection to be established
+	StatementTimeout time.Duration // sets statement_timeout for the session
+	LockTimeout      time.Duration // sets lock_timeout for the session
+}
+
+// Connection holds the settings used by WithConn, set from the --*-timeout flags.
+var Connection ConnectionSettings
+
 // WithConn establishes a pgx connection and executes the provided function with it.
 // Automatically handles connection opening, context setup, and deferred closing.
+// The timeouts in Connection are applied to the session.
 //
 // Params:
 //   - connString: PostgreSQL connection string
@@ -76,10 +91,22 @@ func ConnectDatabase(connString string) (*sql.DB, error) {
 //   - error: any error encountered during connection or from the callback execution
 var WithConn = func(connString string, fn func(*pgx.Conn, context.Context) error) error {
 	ctx := context.Background()
-	connection, err := pgx.Connect(ctx, connString)
+	config, err := pgx.ParseConfig(connString)
+	if err != nil {
+		return &er.SchemerErr{
+			Code:    "0221",
+			Kind:    er.KindConnection,
+			Message: "failed to parse the database connection string.",
+			Err:     err,
+		}
+	}
+	Connection.apply(config)
+
+	connection, err := pgx.ConnectConfig(ctx, config)
 	if err != nil {
 		return &er.SchemerErr{
 			Code:    "0008",
+			Kind:    er.KindConnection,
 			Message: "failed to conntect with database.",
 			Err:     err,
 		}
@@ -89,8 +116,51 @@ var WithConn = func(connString string, fn func(*pgx.Conn, context.Context) error
 	return fn(connection, ctx)
 }
 
+// apply copies the connection settings onto a parsed pgx config.
+func (settings ConnectionSettings) apply(config *pgx.ConnConfig) {
+	if settings.ConnectTimeout > 0 {
+		config.ConnectTimeout = settings.ConnectTimeout
+	}
+	if settings.StatementTimeout > 0 {
+		config.RuntimeParams["statement_timeout"] = strconv.FormatInt(settings.StatementTimeout.Milliseconds(), 10)
+	}
+	if settings.LockTimeout > 0 {
+		config.RuntimeParams["lock_timeout"] = strconv.FormatInt(settings.LockTimeout.Milliseconds(), 10)
+	}
+	if Trace.Enabled {
+		config.Tracer = sqlTracer{}
+	}
+	config.OnNotice = handleNotice
+}
+
+// TableExists reports whether a tracking table exists, resolving its name through the
+// search_path like the statements that use it. It runs no DDL, so read-only commands use it
+// to treat a missing table as empty.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn
+//   - ctx: context for executing the database operations
+//   - table: unqualified table name, e.g. TrackingTable or DirtyTable()
+//
+// Returns:
+//   - bool: true if the table exists
+//   - error: non-nil if the lookup fails
+func TableExists(database *pgx.Conn, ctx context.Context, table string) (bool, error) {
+	var exists bool
+	if err := database.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, table).Scan(&exists); err != nil {
+		return false, &er.SchemerErr{
+			Code:    "0220",
+			Message: "failed to look up table " + table + ".",
+			Err:     err,
+		}
+	}
+	return exists, nil
+}
+
 // CreateSchemerTable creates the schemer tracking table if it does not already exist.
 // Reads the table schema from schemer.sql located in the deltas directory.
+// The companion tables are created alongside it and INTEGER tag columns are widened to BIGINT.
+// The table is resolved through the search_path, the same way every other query finds it.
 //
 // Params:
 //   - database: pointer to an open pgx.Conn
@@ -120,13 +190,7 @@ func CreateSchemerTable(database *pgx.Conn, ctx context.Context) error {
 	}
 
 	var exists bool
-	err = database.QueryRow(ctx, `
-		SELECT EXISTS (
-			SELECT FROM information_schema.tables 
-			WHERE table_schema = 'public' 
-			AND table_name = 'schemer'
-		);
-	`).Scan(&exists)
+	err = database.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, TrackingTable).Scan(&exists)
 	if err != nil {
 		return &er.SchemerErr{
 			Code:    "0006",
@@ -137,7 +201,7 @@ func CreateSchemerTable(database *pgx.Conn, ctx context.Context) error {
 
 	if exists {
 		glog.Info("Schemer table already exists. Skipping table creation")
-		return nil
+		return ensureAndWiden(database, ctx)
 	}
 
 	_, err = database.Exec(ctx, string(statment))
@@ -152,5 +216,15 @@ func CreateSchemerTable(database *pgx.Conn, ctx context.Context) error {
 
 	glog.Info("Schemer table created successfuly.")
 
-	return nil
+	return ensureAndWiden(database, ctx)
+}
+
+// ensureAndWiden creates the companion tables and widens tag columns left as INTEGER by
+// earlier versions of schemer. Only state-changing commands reach this through CreateSchemerTable,
+// the ALTER takes an ACCESS EXCLUSIVE lock and must never run from a dry run.
+func ensureAndWiden(database *pgx.Conn, ctx context.Context) error {
+	if err := EnsureTrackingTables(database, ctx); err != nil {
+		return err
+	}
+	return widenTagColumns(database, ctx)
 }
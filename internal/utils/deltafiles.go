@@ -0,0 +1,176 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+)
+
+// EnvironmentDirective limits a delta group to the named environments, e.g.
+//
+//	-- schemer:env dev, staging
+//
+// It is read from the leading comments of the up delta and applies to the down and post
+// deltas of the same group.
+const EnvironmentDirective = "schemer:env"
+
+var deltaFileExpression = regexp.MustCompile(`^(\d+)_.*\.(up|down|post)\.sql$`)
+
+var repeatableFileExpression = regexp.MustCompile(`^R_[^.]+\.sql$`)
+
+// DeltaFileName holds the parts of a delta filename such as 004_add_users.up.sql.
+type DeltaFileName struct {
+	Tag       int64     // parsed tag, e.g. 4
+	Base      string    // filename before the first dot, e.g. 004_add_users
+	Direction Direction // up, down or post
+}
+
+// ParseDeltaFilename parses a delta filename of the form <tag>_<name>.<up|down|post>.sql.
+// This is the single source of the naming rules used when loading and creating deltas.
+//
+// Params:
+//   - filename: base name of the file, without directories
+//
+// Returns:
+//   - *DeltaFileName: the parsed parts, nil if the file is not a delta file
+//   - error: non-nil if the name matches but the tag cannot be parsed
+func ParseDeltaFilename(filename string) (*DeltaFileName, error) {
+	matches := deltaFileExpression.FindStringSubmatch(filename)
+	if matches == nil || len(matches) < 3 {
+		return nil, nil
+	}
+
+	tag, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	base, _, _ := strings.Cut(filename, ".")
+	return &DeltaFileName{
+		Tag:       tag,
+		Base:      base,
+		Direction: Direction(matches[2]),
+	}, nil
+}
+
+// HasDeltaSuffix reports whether filename ends like a delta file, regardless of whether
+// the rest of the name follows the naming rules.
+func HasDeltaSuffix(filename string) bool {
+	return strings.HasSuffix(filename, ".up.sql") ||
+		strings.HasSuffix(filename, ".down.sql") ||
+		strings.HasSuffix(filename, ".post.sql")
+}
+
+// IsRepeatableFilename reports whether filename is a repeatable delta of the form R_<name>.sql.
+// Repeatable deltas have no tag and are re-applied after up deltas whenever their contents change.
+func IsRepeatableFilename(filename string) bool {
+	return repeatableFileExpression.MatchString(filename)
+}
+
+// ParseEnvironments returns the environments named by the environment directive in the
+// leading comment lines of a delta file.
+//
+// Params:
+//   - data: contents of the delta file
+//
+// Returns:
+//   - []string: environment names, nil if the file has no directive
+func ParseEnvironments(data []byte) []string {
+	value, ok := leadingDirective(data, EnvironmentDirective)
+	if !ok {
+		return nil
+	}
+	var environments []string
+	for _, name := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+		environments = append(environments, strings.ToLower(name))
+	}
+	return environments
+}
+
+// leadingDirective finds a schemer directive in the comment lines at the top of a file.
+//
+// Params:
+//   - data: contents of the file
+//   - directive: directive name, e.g. EnvironmentDirective
+//
+// Returns:
+//   - string: the text following the directive
+//   - bool: true if the directive was found before the first statement
+func leadingDirective(data []byte, directive string) (string, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		comment, ok := strings.CutPrefix(line, "--")
+		if !ok {
+			return "", false
+		}
+		value, ok := strings.CutPrefix(strings.TrimSpace(comment), directive)
+		if ok && (value == "" || value[0] == ' ' || value[0] == '\t') {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// GroupEnvironments returns the environments a delta group is limited to, read from its up delta.
+//
+// Params:
+//   - dir: directory holding the delta group
+//   - base: filename of the group before the first dot, e.g. 004_add_users
+//
+// Returns:
+//   - []string: environment names, nil if the group runs everywhere or has no up delta
+//   - error: non-nil if the up delta exists but cannot be read
+func GroupEnvironments(dir string, base string) ([]string, error) {
+	path := filepath.Join(dir, base+".up.sql")
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &er.SchemerErr{
+			Code:    "0176",
+			Message: "failed to read up delta for environment directive: " + path,
+			Err:     err,
+		}
+	}
+	return ParseEnvironments(data), nil
+}
+
+// MatchesEnvironment reports whether a delta limited to environments runs in env.
+// Deltas without environments run everywhere.
+func MatchesEnvironment(environments []string, env string) bool {
+	return len(environments) == 0 || slices.Contains(environments, strings.ToLower(env))
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseEnvironments(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		expected []string
+	}{
+		{name: "no directive", data: "-- adds users\nCREATE TABLE users (id int);"},
+		{name: "single", data: "-- schemer:env dev\nINSERT INTO users VALUES (1);", expected: []string{"dev"}},
+		{name: "list", data: "-- adds fixtures\n\n--schemer:env Dev, staging\tqa\nSELECT 1;", expected: []string{"dev", "staging", "qa"}},
+		{name: "after sql", data: "SELECT 1;\n-- schemer:env dev", expected: nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ParseEnvironments([]byte(tc.data)); !slices.Equal(got, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestGroupEnvironments(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "001_fixtures.up.sql"), []byte("-- schemer:env dev\nSELECT 1;"), 0644); err != nil {
+		t.Fatalf("failed to write delta: %v", err)
+	}
+
+	environments, err := GroupEnvironments(dir, "001_fixtures")
+	if err != nil || !slices.Equal(environments, []string{"dev"}) {
+		t.Fatalf("expected [dev], got %v, %v", environments, err)
+	}
+	if environments, err := GroupEnvironments(dir, "002_missing"); err != nil || environments != nil {
+		t.Fatalf("expected a group without an up delta to run everywhere, got %v, %v", environments, err)
+	}
+
+	if !MatchesEnvironment(nil, "prod") || !MatchesEnvironment(environments, "DEV") || MatchesEnvironment(environments, "prod") {
+		t.Fatalf("unexpected environment matching for %v", environments)
+	}
+}
@@ -29,13 +29,23 @@ import (
 	"github.com/inskribe/schemer/internal/errschemer"
 )
 
-// GetDeltaPath returns the absolute path to the deltas directory.
-// Assumes the deltas directory is located in the current working directory.
+// ProjectDir is the project root, set by --project-dir or discovered by the root command.
+// The current working directory is used when it is empty.
+var ProjectDir string
+
+// DeltaDir is the deltas directory set with --deltas-dir. A relative path is resolved
+// against the project root.
+var DeltaDir = "deltas"
+
+// GetProjectDir returns the project root, falling back to the current working directory.
 //
 // Returns:
-//   - string: full path to the deltas directory
+//   - string: absolute path of the project root
 //   - error: non-nil if the current working directory cannot be resolved
-var GetDeltaPath = func() (string, error) {
+func GetProjectDir() (string, error) {
+	if ProjectDir != "" {
+		return ProjectDir, nil
+	}
 	cwd, err := os.Getwd()
 	if err != nil {
 		return "", &errschemer.SchemerErr{
@@ -44,5 +54,36 @@ var GetDeltaPath = func() (string, error) {
 			Err:     err,
 		}
 	}
-	return filepath.Join(cwd, "deltas"), nil
+	return cwd, nil
+}
+
+// GetDeltaPath returns the absolute path to the deltas directory.
+// Assumes the deltas directory is located in the project root unless DeltaDir is absolute.
+//
+// Returns:
+//   - string: full path to the deltas directory
+//   - error: non-nil if the project root cannot be resolved
+var GetDeltaPath = func() (string, error) {
+	if filepath.IsAbs(DeltaDir) {
+		return DeltaDir, nil
+	}
+	root, err := GetProjectDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, DeltaDir), nil
+}
+
+// GetSeedPath returns the absolute path to the seeds directory.
+// Assumes the seeds directory is located in the project root.
+//
+// Returns:
+//   - string: full path to the seeds directory
+//   - error: non-nil if the project root cannot be resolved
+var GetSeedPath = func() (string, error) {
+	root, err := GetProjectDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, "seeds"), nil
 }
@@ -0,0 +1,356 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+)
+
+// Direction identifies the kind of delta being executed against the database.
+type Direction string
+
+const (
+	DirectionUp         Direction = "up"
+	DirectionDown       Direction = "down"
+	DirectionPost       Direction = "post"
+	DirectionBaseline   Direction = "baseline"   // tags recorded as applied without executing them
+	DirectionMark       Direction = "mark"       // manual resolution recorded by schemer repair
+	DirectionSeed       Direction = "seed"       // seed file applied by schemer seed
+	DirectionRepeatable Direction = "repeatable" // repeatable delta re-applied after up deltas
+	DirectionSkip       Direction = "skip"       // delta recorded without executing it, limited to other environments
+)
+
+// DirtyDelta describes a delta whose execution started but was never confirmed as finished.
+type DirtyDelta struct {
+	Tag       int64     // tag of the delta that was executing
+	Direction Direction // up, down or post
+	File      string    // delta file relative to the deltas directory
+	StartedAt time.Time // when execution started
+	Error     string    // error reported by the database, empty if the process died mid-run
+}
+
+const dirtyTableStatement = `
+CREATE TABLE IF NOT EXISTS %s (
+  tag BIGINT NOT NULL,
+  direction TEXT NOT NULL,
+  file_name TEXT NOT NULL DEFAULT '',
+  started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  error TEXT,
+  PRIMARY KEY (tag, direction)
+);`
+
+// EnsureDirtyTable creates the schemer_dirty table if it does not already exist.
+// The table holds a marker for every delta that is currently executing or failed mid-run.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn
+//   - ctx: context for executing the database operations
+//
+// Returns:
+//   - error: non-nil if the table could not be created
+func EnsureDirtyTable(database *pgx.Conn, ctx context.Context) error {
+	if database == nil {
+		return &er.SchemerErr{
+			Code:    "0068",
+			Message: "recived nil database pointer.",
+		}
+	}
+
+	if _, err := database.Exec(ctx, fmt.Sprintf(dirtyTableStatement, DirtyTable())); err != nil {
+		return &er.SchemerErr{
+			Code:    "0069",
+			Message: "failed to create schemer_dirty table.",
+			Err:     err,
+		}
+	}
+	return nil
+}
+
+// DefaultTrackingTable is the name of the schemer tracking table unless --table is set.
+const DefaultTrackingTable = "schemer"
+
+// TrackingTable is the name of the schemer tracking table. The companion tables are named
+// after it, so several projects can keep separate bookkeeping in one database.
+var TrackingTable = DefaultTrackingTable
+
+// DirtyTable returns the name of the table holding dirty markers.
+func DirtyTable() string { return TrackingTable + "_dirty" }
+
+// HistoryTable returns the name of the append-only history table.
+func HistoryTable() string { return TrackingTable + "_history" }
+
+// SeedsTable returns the name of the table recording applied seeds.
+func SeedsTable() string { return TrackingTable + "_seeds" }
+
+// RepeatableTable returns the name of the table recording applied repeatable deltas.
+func RepeatableTable() string { return TrackingTable + "_repeatable" }
+
+// TrackingTables lists the tables schemer uses for its own bookkeeping.
+// They are left out of schema snapshots and diffs.
+func TrackingTables() []string {
+	return []string{TrackingTable, DirtyTable(), HistoryTable(), SeedsTable(), RepeatableTable()}
+}
+
+// CheckTrackingTable ensures TrackingTable is a plain identifier, since it is written into statements.
+//
+// Returns:
+//   - error: non-nil if the name is not a valid unquoted identifier
+func CheckTrackingTable() error {
+	if !trackingTablePattern.MatchString(TrackingTable) {
+		return &er.SchemerErr{
+			Code:    "0169",
+			Message: fmt.Sprintf("invalid tracking table name %q, expected letters, digits and underscores", TrackingTable),
+		}
+	}
+	return nil
+}
+
+var trackingTablePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// EnsureTrackingTables creates the companion tables used alongside the schemer table.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn
+//   - ctx: context for executing the database operations
+//
+// Returns:
+//   - error: non-nil if any table could not be created
+func EnsureTrackingTables(database *pgx.Conn, ctx context.Context) error {
+	if err := EnsureDirtyTable(database, ctx); err != nil {
+		return err
+	}
+	if err := EnsureHistoryTable(database, ctx); err != nil {
+		return err
+	}
+	if err := EnsureSeedTable(database, ctx); err != nil {
+		return err
+	}
+	return EnsureRepeatableTable(database, ctx)
+}
+
+// widenTagColumns converts INTEGER tag columns created by earlier versions of schemer to BIGINT,
+// so timestamp tags such as 20261017143000 can be recorded. Tables already using BIGINT are left alone.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn
+//   - ctx: context for executing the database operations
+//
+// Returns:
+//   - error: non-nil if the columns could not be inspected or altered
+func widenTagColumns(database *pgx.Conn, ctx context.Context) error {
+	rows, err := database.Query(ctx, `
+		SELECT t.name FROM unnest($1::text[]) AS t(name)
+		JOIN pg_attribute a ON a.attrelid = to_regclass(t.name)
+		WHERE a.attname = 'tag' AND NOT a.attisdropped
+		AND a.atttypid = 'integer'::regtype`,
+		[]string{TrackingTable, DirtyTable(), HistoryTable()})
+	if err != nil {
+		return &er.SchemerErr{
+			Code:    "0178",
+			Message: "failed to inspect tag columns of the tracking tables.",
+			Err:     err,
+		}
+	}
+	narrow, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return &er.SchemerErr{
+			Code:    "0179",
+			Message: "failed to inspect tag columns of the tracking tables.",
+			Err:     err,
+		}
+	}
+
+	for _, table := range narrow {
+		if _, err := database.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN tag TYPE BIGINT`, table)); err != nil {
+			return &er.SchemerErr{
+				Code:    "0180",
+				Message: "failed to widen the tag column of " + table + " to BIGINT.",
+				Err:     err,
+			}
+		}
+	}
+	return nil
+}
+
+// GetDirtyDeltas returns every dirty marker recorded in the schemer_dirty table.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn
+//   - ctx: context for executing the database operations
+//
+// Returns:
+//   - []DirtyDelta: dirty markers ordered by start time
+//   - error: non-nil if the query or scan fails
+func GetDirtyDeltas(database *pgx.Conn, ctx context.Context) ([]DirtyDelta, error) {
+	rows, err := database.Query(ctx,
+		fmt.Sprintf(`SELECT tag, direction, file_name, started_at, COALESCE(error, '') FROM %s ORDER BY started_at`, DirtyTable()))
+	if err != nil {
+		return nil, &er.SchemerErr{
+			Code:    "0070",
+			Message: "failed to query schemer_dirty table.",
+			Err:     err,
+		}
+	}
+	defer rows.Close()
+
+	var result []DirtyDelta
+	for rows.Next() {
+		var dirty DirtyDelta
+		var direction string
+		if err := rows.Scan(&dirty.Tag, &direction, &dirty.File, &dirty.StartedAt, &dirty.Error); err != nil {
+			return nil, &er.SchemerErr{
+				Code:    "0071",
+				Message: "failed to scan dirty delta.",
+				Err:     err,
+			}
+		}
+		dirty.Direction = Direction(direction)
+		result = append(result, dirty)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, &er.SchemerErr{
+			Code:    "0072",
+			Message: "row iteration error:",
+			Err:     err,
+		}
+	}
+	return result, nil
+}
+
+// CheckNotDirty returns an error if any delta is marked as dirty.
+// State-changing commands call this before touching the database so a partially
+// applied delta is never silently re-run. It only reads, a missing schemer_dirty table means
+// nothing is dirty, so dry runs can call it without creating or altering anything.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn
+//   - ctx: context for executing the database operations
+//
+// Returns:
+//   - error: non-nil if a dirty marker exists or the lookup fails
+func CheckNotDirty(database *pgx.Conn, ctx context.Context) error {
+	if exists, err := TableExists(database, ctx, DirtyTable()); err != nil || !exists {
+		return err
+	}
+
+	dirty, err := GetDirtyDeltas(database, ctx)
+	if err != nil {
+		return err
+	}
+	if len(dirty) == 0 {
+		return nil
+	}
+
+	var entries []string
+	for _, delta := range dirty {
+		entries = append(entries, fmt.Sprintf("%s (%s, %s)", ToPrefix(delta.Tag), delta.Direction, delta.File))
+	}
+	return &er.SchemerErr{
+		Code: "0073",
+		Message: fmt.Sprintf(`database is dirty, the following deltas did not finish: %s
+Inspect the database and resolve with [schemer repair]`, strings.Join(entries, ", ")),
+	}
+}
+
+// MarkDirty records that a delta is about to be executed.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn
+//   - ctx: context for executing the database operations
+//   - tag: tag of the delta
+//   - direction: up, down or post
+//   - file: delta file relative to the deltas directory
+//
+// Returns:
+//   - error: non-nil if the marker could not be written
+func MarkDirty(database *pgx.Conn, ctx context.Context, tag int64, direction Direction, file string) error {
+	_, err := database.Exec(ctx,
+		fmt.Sprintf(`INSERT INTO %s (tag, direction, file_name) VALUES ($1, $2, $3)`, DirtyTable()),
+		tag, string(direction), file)
+	if err != nil {
+		return &er.SchemerErr{
+			Code:    "0074",
+			Message: "failed to write dirty marker for delta: " + ToPrefix(tag),
+			Err:     err,
+		}
+	}
+	return nil
+}
+
+// RecordDirtyError attaches the execution error to an existing dirty marker.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn
+//   - ctx: context for executing the database operations
+//   - tag: tag of the delta
+//   - direction: up, down or post
+//   - execErr: the error returned while executing the delta
+//
+// Returns:
+//   - error: non-nil if the marker could not be updated
+func RecordDirtyError(database *pgx.Conn, ctx context.Context, tag int64, direction Direction, execErr error) error {
+	_, err := database.Exec(ctx,
+		fmt.Sprintf(`UPDATE %s SET error = $3 WHERE tag = $1 AND direction = $2`, DirtyTable()),
+		tag, string(direction), execErr.Error())
+	if err != nil {
+		return &er.SchemerErr{
+			Code:    "0075",
+			Message: "failed to record error on dirty marker for delta: " + ToPrefix(tag),
+			Err:     err,
+		}
+	}
+	return nil
+}
+
+// ClearDirty removes the dirty marker for a delta once it has finished executing.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn
+//   - ctx: context for executing the database operations
+//   - tag: tag of the delta
+//   - direction: up, down or post
+//
+// Returns:
+//   - error: non-nil if the marker could not be removed
+func ClearDirty(database *pgx.Conn, ctx context.Context, tag int64, direction Direction) error {
+	_, err := database.Exec(ctx,
+		fmt.Sprintf(`DELETE FROM %s WHERE tag = $1 AND direction = $2`, DirtyTable()),
+		tag, string(direction))
+	if err != nil {
+		return &er.SchemerErr{
+			Code:    "0076",
+			Message: "failed to clear dirty marker for delta: " + ToPrefix(tag),
+			Err:     err,
+		}
+	}
+	return nil
+}
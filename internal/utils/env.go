@@ -33,18 +33,18 @@ import (
 	"github.com/inskribe/schemer/internal/glog"
 )
 
-// LoadDotEnv attempts to load environment variables from a .env file in the current directory.
+// LoadDotEnv attempts to load environment variables from a .env file in the project root.
 // Logs a message if the file is missing (which is expected in production).
 //
 // Returns:
 //   - bool: true if the .env file was successfully loaded; false if it was not found
 //   - error: non-nil if an error occurred while attempting to read the file (other than not found)
 func LoadDotEnv() (bool, error) {
-	cwd, err := os.Getwd()
+	root, err := GetProjectDir()
 	if err != nil {
 		return false, err
 	}
-	envFilepath := filepath.Join(cwd, ".env")
+	envFilepath := filepath.Join(root, ".env")
 	err = godotenv.Load(envFilepath)
 	if err != nil && errors.Is(err, os.ErrNotExist) {
 		glog.Info(`Failed to load .env file. This is expexted behaviour in a production environment. 
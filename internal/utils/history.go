@@ -0,0 +1,262 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/inskribe/schemer/internal/build"
+	er "github.com/inskribe/schemer/internal/errschemer"
+)
+
+// HistoryEntry is a single row of the append-only schemer_history table.
+type HistoryEntry struct {
+	ID             int64         `json:"id"`                // sequential identifier assigned by the database
+	Direction      Direction     `json:"direction"`         // up, down, post, baseline, mark, seed, repeatable or skip
+	Tag            int64         `json:"tag"`               // tag of the delta
+	File           string        `json:"file"`              // delta file relative to the deltas directory
+	Checksum       string        `json:"checksum"`          // sha256 of the raw delta file
+	StartedAt      time.Time     `json:"started_at"`        // when execution started
+	FinishedAt     time.Time     `json:"finished_at"`       // when execution finished
+	Duration       time.Duration `json:"-"`                 // FinishedAt - StartedAt
+	DatabaseUser   string        `json:"database_user"`     // current_user of the session that executed the delta
+	OSUser         string        `json:"os_user"`           // operating system user running schemer
+	Hostname       string        `json:"hostname"`          // host running schemer
+	SchemerVersion string        `json:"schemer_version"`   // schemer release that executed the delta
+	Success        bool          `json:"success"`           // true if the delta executed without error
+	Error          string        `json:"error,omitempty"`   // error message if the delta failed
+	Note           string        `json:"note,omitempty"`    // free-form detail, e.g. the outcome chosen by schemer repair
+	Notices        []Notice      `json:"notices,omitempty"` // notices the server raised while the delta ran
+}
+
+// HistoryFilter narrows the rows returned by GetHistory. Zero values are ignored.
+type HistoryFilter struct {
+	Tag       *int64    // only entries for this tag
+	Direction Direction // only entries for this direction
+	Since     time.Time // only entries started at or after this time
+	Until     time.Time // only entries started before this time
+	Limit     int       // maximum number of entries, newest first
+}
+
+const historyTableStatement = `
+CREATE TABLE IF NOT EXISTS %s (
+  id BIGSERIAL PRIMARY KEY,
+  direction TEXT NOT NULL,
+  tag BIGINT NOT NULL,
+  file_name TEXT NOT NULL DEFAULT '',
+  checksum TEXT NOT NULL DEFAULT '',
+  started_at TIMESTAMP NOT NULL,
+  finished_at TIMESTAMP NOT NULL,
+  duration_ms BIGINT NOT NULL,
+  db_user TEXT NOT NULL DEFAULT current_user,
+  os_user TEXT NOT NULL DEFAULT '',
+  hostname TEXT NOT NULL DEFAULT '',
+  schemer_version TEXT NOT NULL DEFAULT '',
+  success BOOLEAN NOT NULL,
+  error TEXT,
+  note TEXT,
+  notices JSONB
+);`
+
+// Checksum returns the hex encoded sha256 of the given delta contents.
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// EnsureHistoryTable creates the schemer_history table if it does not already exist.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn
+//   - ctx: context for executing the database operations
+//
+// Returns:
+//   - error: non-nil if the table could not be created
+func EnsureHistoryTable(database *pgx.Conn, ctx context.Context) error {
+	if database == nil {
+		return &er.SchemerErr{
+			Code:    "0080",
+			Message: "recived nil database pointer.",
+		}
+	}
+
+	if _, err := database.Exec(ctx, fmt.Sprintf(historyTableStatement, HistoryTable())); err != nil {
+		return &er.SchemerErr{
+			Code:    "0081",
+			Message: "failed to create schemer_history table.",
+			Err:     err,
+		}
+	}
+	return nil
+}
+
+// RecordHistory appends an entry to the schemer_history table.
+// The OS user, hostname and schemer version are filled in from the running process,
+// the database user is taken from the session's current_user.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn
+//   - ctx: context for executing the database operations
+//   - entry: the entry to append; ID, DatabaseUser, OSUser, Hostname and SchemerVersion are ignored
+//
+// Returns:
+//   - error: non-nil if the entry could not be written
+func RecordHistory(database *pgx.Conn, ctx context.Context, entry HistoryEntry) error {
+	var errorMessage, note *string
+	if entry.Error != "" {
+		errorMessage = &entry.Error
+	}
+	if entry.Note != "" {
+		note = &entry.Note
+	}
+	var notices *[]Notice
+	if len(entry.Notices) > 0 {
+		notices = &entry.Notices
+	}
+
+	_, err := database.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (
+			direction, tag, file_name, checksum, started_at, finished_at, duration_ms,
+			os_user, hostname, schemer_version, success, error, note, notices
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`, HistoryTable()),
+		string(entry.Direction), entry.Tag, entry.File, entry.Checksum,
+		entry.StartedAt, entry.FinishedAt, entry.FinishedAt.Sub(entry.StartedAt).Milliseconds(),
+		currentOSUser(), currentHostname(), build.Version, entry.Success, errorMessage, note, notices)
+	if err != nil {
+		return &er.SchemerErr{
+			Code:    "0082",
+			Message: "failed to record history for delta: " + ToPrefix(entry.Tag),
+			Err:     err,
+		}
+	}
+	return nil
+}
+
+// GetHistory returns schemer_history entries matching the filter, newest first.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn
+//   - ctx: context for executing the database operations
+//   - filter: criteria used to narrow the result
+//
+// Returns:
+//   - []HistoryEntry: matching entries
+//   - error: non-nil if the query or scan fails
+func GetHistory(database *pgx.Conn, ctx context.Context, filter HistoryFilter) ([]HistoryEntry, error) {
+	if err := EnsureHistoryTable(database, ctx); err != nil {
+		return nil, err
+	}
+
+	var conditions []string
+	var args []any
+	if filter.Tag != nil {
+		args = append(args, *filter.Tag)
+		conditions = append(conditions, fmt.Sprintf("tag = $%d", len(args)))
+	}
+	if filter.Direction != "" {
+		args = append(args, string(filter.Direction))
+		conditions = append(conditions, fmt.Sprintf("direction = $%d", len(args)))
+	}
+	if !filter.Since.IsZero() {
+		args = append(args, filter.Since)
+		conditions = append(conditions, fmt.Sprintf("started_at >= $%d", len(args)))
+	}
+	if !filter.Until.IsZero() {
+		args = append(args, filter.Until)
+		conditions = append(conditions, fmt.Sprintf("started_at < $%d", len(args)))
+	}
+
+	statement := fmt.Sprintf(`SELECT id, direction, tag, file_name, checksum, started_at, finished_at, duration_ms,
+		db_user, os_user, hostname, schemer_version, success, COALESCE(error, ''), COALESCE(note, ''),
+		COALESCE(notices, '[]')
+		FROM %s`, HistoryTable())
+	if len(conditions) > 0 {
+		statement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	statement += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		statement += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := database.Query(ctx, statement, args...)
+	if err != nil {
+		return nil, &er.SchemerErr{
+			Code:    "0083",
+			Message: "failed to query schemer_history table.",
+			Err:     err,
+		}
+	}
+	defer rows.Close()
+
+	var result []HistoryEntry
+	for rows.Next() {
+		var entry HistoryEntry
+		var direction string
+		var durationMs int64
+		if err := rows.Scan(&entry.ID, &direction, &entry.Tag, &entry.File, &entry.Checksum,
+			&entry.StartedAt, &entry.FinishedAt, &durationMs, &entry.DatabaseUser, &entry.OSUser,
+			&entry.Hostname, &entry.SchemerVersion, &entry.Success, &entry.Error, &entry.Note, &entry.Notices); err != nil {
+			return nil, &er.SchemerErr{
+				Code:    "0084",
+				Message: "failed to scan history entry.",
+				Err:     err,
+			}
+		}
+		entry.Direction = Direction(direction)
+		entry.Duration = time.Duration(durationMs) * time.Millisecond
+		result = append(result, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, &er.SchemerErr{
+			Code:    "0085",
+			Message: "row iteration error:",
+			Err:     err,
+		}
+	}
+	return result, nil
+}
+
+func currentOSUser() string {
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return os.Getenv("USER")
+}
+
+func currentHostname() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return hostname
+}
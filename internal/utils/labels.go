@@ -0,0 +1,206 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+)
+
+// LabelsFile is the file in the deltas directory that names release tags, one per line:
+//
+//	# comments and blank lines are ignored
+//	release-2.3 = 027
+const LabelsFile = "labels.txt"
+
+// Labels maps release label names to tags.
+type Labels map[string]int64
+
+var labelExpression = regexp.MustCompile(`^[a-zA-Z0-9._-]*[a-zA-Z._-][a-zA-Z0-9._-]*$`)
+
+// LoadLabels reads the labels file of the deltas directory.
+//
+// Returns:
+//   - Labels: the release labels, empty if the project has no labels file
+//   - error: non-nil if the deltas directory cannot be resolved or the file is malformed
+func LoadLabels() (Labels, error) {
+	deltaPath, err := GetDeltaPath()
+	if err != nil {
+		return nil, err
+	}
+	return ReadLabels(deltaPath)
+}
+
+// ReadLabels reads the labels file in deltaPath.
+//
+// Params:
+//   - deltaPath: the deltas directory
+//
+// Returns:
+//   - Labels: the release labels, empty if deltaPath has no labels file
+//   - error: non-nil if the file cannot be read or is malformed
+func ReadLabels(deltaPath string) (Labels, error) {
+	data, err := os.ReadFile(filepath.Join(deltaPath, LabelsFile))
+	if os.IsNotExist(err) {
+		return Labels{}, nil
+	}
+	if err != nil {
+		return nil, &er.SchemerErr{
+			Code:    "0186",
+			Message: "failed to read " + LabelsFile,
+			Err:     err,
+		}
+	}
+	return ParseLabels(data)
+}
+
+// ParseLabels parses the contents of a labels file.
+//
+// Params:
+//   - data: contents of the labels file
+//
+// Returns:
+//   - Labels: the release labels
+//   - error: non-nil if a line is not <label> = <tag>, a label is invalid or defined twice
+func ParseLabels(data []byte) (Labels, error) {
+	labels := make(Labels)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, found := strings.Cut(line, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		tag, err := strconv.ParseInt(value, 10, 64)
+		if !found || err != nil {
+			return nil, &er.SchemerErr{
+				Code:    "0187",
+				Message: fmt.Sprintf("%s line %d: expected <label> = <tag>, got %q", LabelsFile, number, line),
+				Err:     err,
+			}
+		}
+		if !labelExpression.MatchString(name) {
+			return nil, &er.SchemerErr{
+				Code:    "0188",
+				Message: fmt.Sprintf("%s line %d: invalid label %q, expected letters, digits, dots, dashes and underscores with at least one non-digit", LabelsFile, number, name),
+			}
+		}
+		if _, exists := labels[name]; exists {
+			return nil, &er.SchemerErr{
+				Code:    "0189",
+				Message: fmt.Sprintf("%s line %d: label %s is defined twice", LabelsFile, number, name),
+			}
+		}
+		labels[name] = tag
+	}
+	return labels, nil
+}
+
+// ResolveTag converts a tag or release label given on the command line to a tag.
+//
+// Params:
+//   - value: a tag such as 27 or 027, or a label such as release-2.3
+//
+// Returns:
+//   - int64: the tag
+//   - error: non-nil if value is neither a tag nor a known label
+func (labels Labels) ResolveTag(value string) (int64, error) {
+	if tag, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return tag, nil
+	}
+	if tag, ok := labels[value]; ok {
+		return tag, nil
+	}
+	return 0, &er.SchemerErr{
+		Code:    "0190",
+		Message: fmt.Sprintf("%q is neither a tag nor a label defined in %s", value, LabelsFile),
+	}
+}
+
+// ResolveTag converts a tag or release label to a tag, reading the labels file only
+// when value is not a number.
+//
+// Params:
+//   - value: a tag such as 27 or 027, or a label such as release-2.3
+//
+// Returns:
+//   - int64: the tag
+//   - error: non-nil if the labels file cannot be read or value is neither a tag nor a known label
+func ResolveTag(value string) (int64, error) {
+	if tag, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return tag, nil
+	}
+	labels, err := LoadLabels()
+	if err != nil {
+		return 0, err
+	}
+	return labels.ResolveTag(value)
+}
+
+// Satisfied returns the label with the highest tag that the database fully satisfies:
+// its tag and every lower tag in pending are applied.
+//
+// Params:
+//   - applied: the applied tags
+//   - pending: tags of the up deltas that are not applied
+//
+// Returns:
+//   - string: the satisfied label, empty if none is satisfied
+//   - int64: the tag of the label
+func (labels Labels) Satisfied(applied map[int64]bool, pending []int64) (string, int64) {
+	lowestPending := int64(-1)
+	for _, tag := range pending {
+		if lowestPending < 0 || tag < lowestPending {
+			lowestPending = tag
+		}
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	// highest tag first, labels sharing a tag in name order
+	slices.SortFunc(names, func(a, b string) int {
+		return cmp.Or(cmp.Compare(labels[b], labels[a]), strings.Compare(a, b))
+	})
+
+	for _, name := range names {
+		tag := labels[name]
+		if applied[tag] && (lowestPending < 0 || lowestPending > tag) {
+			return name, tag
+		}
+	}
+	return "", 0
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"testing"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+)
+
+func TestParseLabels(t *testing.T) {
+	labels, err := ParseLabels([]byte("# releases\n\nrelease-2.3 = 027\nrelease_2.4=20261017143000\n"))
+	if err != nil {
+		t.Fatalf("ParseLabels failed: %v", err)
+	}
+	if labels["release-2.3"] != 27 || labels["release_2.4"] != 20261017143000 {
+		t.Fatalf("unexpected labels %v", labels)
+	}
+
+	for data, code := range map[string]string{
+		"release-2.3 027":    "0187",
+		"release-2.3 = next": "0187",
+		"027 = 027":          "0188",
+		"release 2.3 = 027":  "0188",
+		"v1 = 001\nv1 = 002": "0189",
+	} {
+		_, err := ParseLabels([]byte(data))
+		if schemerErr, ok := err.(*er.SchemerErr); !ok || schemerErr.Code != code {
+			t.Errorf("expected %q to fail with %s, got %v", data, code, err)
+		}
+	}
+}
+
+func TestLabels_ResolveTag(t *testing.T) {
+	labels := Labels{"release-2.3": 27}
+	for value, expected := range map[string]int64{"27": 27, "004": 4, "release-2.3": 27} {
+		if tag, err := labels.ResolveTag(value); err != nil || tag != expected {
+			t.Errorf("expected %s to resolve to %d, got %d, %v", value, expected, tag, err)
+		}
+	}
+	_, err := labels.ResolveTag("release-9")
+	if schemerErr, ok := err.(*er.SchemerErr); !ok || schemerErr.Code != "0190" {
+		t.Fatalf("expected an unknown label to fail with 0190, got %v", err)
+	}
+}
+
+func TestLabels_Satisfied(t *testing.T) {
+	labels := Labels{"release-1": 2, "release-1-final": 2, "release-2": 4, "release-3": 6}
+	applied := map[int64]bool{1: true, 2: true, 3: true, 4: true, 6: true}
+
+	if name, tag := labels.Satisfied(applied, []int64{5}); name != "release-2" || tag != 4 {
+		t.Errorf("expected release-2 while 005 is pending, got %s", name)
+	}
+	if name, _ := labels.Satisfied(applied, nil); name != "release-3" {
+		t.Errorf("expected release-3 once nothing is pending, got %s", name)
+	}
+	if name, _ := labels.Satisfied(map[int64]bool{1: true, 2: true}, []int64{3, 4, 5, 6}); name != "release-1" {
+		t.Errorf("expected labels sharing a tag to be picked by name, got %s", name)
+	}
+	if name, _ := labels.Satisfied(map[int64]bool{1: true}, []int64{2}); name != "" {
+		t.Errorf("expected no label to be satisfied, got %s", name)
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/glog"
+)
+
+// Notice is a message the server raised while a delta ran, e.g. with RAISE NOTICE or a
+// warning such as an identifier being truncated.
+type Notice struct {
+	Severity string `json:"severity"` // WARNING, NOTICE, INFO, LOG or DEBUG
+	Message  string `json:"message"`
+	Detail   string `json:"detail,omitempty"`
+	Hint     string `json:"hint,omitempty"`
+}
+
+// FailOnWarning makes a delta fail when the server raises a WARNING while it runs, set from
+// --fail-on-warning.
+var FailOnWarning bool
+
+// noticeCollector gathers the notices of the delta running on a connection.
+type noticeCollector struct {
+	scope   TraceScope
+	notices []Notice
+}
+
+var (
+	noticeMu         sync.Mutex
+	noticeCollectors = map[*pgconn.PgConn]*noticeCollector{}
+)
+
+// handleNotice is the OnNotice handler of every connection opened by WithConn. Notices are
+// logged as they arrive, so progress reported by a long delta shows up while it runs.
+// Notices raised by the statements schemer runs itself are only logged at debug level.
+func handleNotice(connection *pgconn.PgConn, pgNotice *pgconn.Notice) {
+	notice := Notice{
+		Severity: pgNotice.SeverityUnlocalized,
+		Message:  pgNotice.Message,
+		Detail:   pgNotice.Detail,
+		Hint:     pgNotice.Hint,
+	}
+	if notice.Severity == "" {
+		notice.Severity = pgNotice.Severity
+	}
+
+	noticeMu.Lock()
+	collector := noticeCollectors[connection]
+	if collector != nil {
+		collector.notices = append(collector.notices, notice)
+	}
+	noticeMu.Unlock()
+
+	var scope TraceScope
+	level := slog.LevelDebug
+	if collector != nil {
+		scope = collector.scope
+		level = notice.level()
+	}
+	attrs := append(scope.attrs(), slog.String("severity", notice.Severity))
+	if notice.Detail != "" {
+		attrs = append(attrs, slog.String("detail", notice.Detail))
+	}
+	if notice.Hint != "" {
+		attrs = append(attrs, slog.String("hint", notice.Hint))
+	}
+	glog.LogAttrs(level, notice.Message, attrs...)
+}
+
+// level returns the log level of a notice raised by a delta.
+func (notice Notice) level() slog.Level {
+	switch notice.Severity {
+	case "WARNING":
+		return slog.LevelWarn
+	case "DEBUG":
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
+
+// collectNotices starts gathering the notices raised on connection for a delta.
+//
+// Params:
+//   - connection: the connection the delta runs on
+//   - scope: the delta being executed
+//
+// Returns:
+//   - func() []Notice: stops gathering and returns the notices raised since the call
+func collectNotices(connection *pgconn.PgConn, scope TraceScope) func() []Notice {
+	collector := &noticeCollector{scope: scope}
+	noticeMu.Lock()
+	noticeCollectors[connection] = collector
+	noticeMu.Unlock()
+
+	return func() []Notice {
+		noticeMu.Lock()
+		defer noticeMu.Unlock()
+		delete(noticeCollectors, connection)
+		return collector.notices
+	}
+}
+
+// checkWarnings returns an error for the first WARNING in notices when FailOnWarning is set.
+//
+// Params:
+//   - scope: the delta that raised the notices
+//   - notices: notices raised by the delta
+//
+// Returns:
+//   - error: non-nil if the delta must be treated as failed
+func checkWarnings(scope TraceScope, notices []Notice) error {
+	if !FailOnWarning {
+		return nil
+	}
+	for _, notice := range notices {
+		if notice.Severity == "WARNING" {
+			return &er.SchemerErr{
+				Code:    "0219",
+				Kind:    er.KindExecution,
+				Message: fmt.Sprintf("%s raised a warning with --fail-on-warning: %s", scope.File, notice.Message),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+	"github.com/inskribe/schemer/internal/glog"
+)
+
+func TestCollectNotices(t *testing.T) {
+	var buf bytes.Buffer
+	glog.Configure(glog.Options{Level: slog.LevelInfo, Format: glog.FormatText, Writer: &buf})
+	t.Cleanup(func() { glog.InitializeLogger(true) })
+
+	connection := &pgconn.PgConn{}
+	tag := int64(7)
+	scope := TraceScope{Direction: DirectionUp, Tag: &tag, File: "007_backfill.up.sql"}
+
+	// Outside a delta notices are only logged at debug level.
+	handleNotice(connection, &pgconn.Notice{SeverityUnlocalized: "NOTICE", Message: "relation exists, skipping"})
+
+	stop := collectNotices(connection, scope)
+	handleNotice(connection, &pgconn.Notice{SeverityUnlocalized: "NOTICE", Message: "backfilled 1000 rows"})
+	handleNotice(connection, &pgconn.Notice{Severity: "WARNING", Message: "identifier truncated", Hint: "use a shorter name"})
+	notices := stop()
+
+	handleNotice(connection, &pgconn.Notice{SeverityUnlocalized: "NOTICE", Message: "after the delta"})
+
+	if len(notices) != 2 || notices[0].Message != "backfilled 1000 rows" || notices[1].Severity != "WARNING" {
+		t.Fatalf("got %+v", notices)
+	}
+	want := "[Info]: backfilled 1000 rows source=up tag=7 file=007_backfill.up.sql severity=NOTICE\n" +
+		"[Warn]: identifier truncated source=up tag=7 file=007_backfill.up.sql severity=WARNING hint=\"use a shorter name\"\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+}
+
+func TestCheckWarnings(t *testing.T) {
+	t.Cleanup(func() { FailOnWarning = false })
+	scope := TraceScope{Direction: DirectionSeed, File: "dev/users.sql"}
+	notices := []Notice{{Severity: "NOTICE", Message: "ok"}, {Severity: "WARNING", Message: "truncated"}}
+
+	if err := checkWarnings(scope, notices); err != nil {
+		t.Errorf("warnings failed without --fail-on-warning: %v", err)
+	}
+
+	FailOnWarning = true
+	if err := checkWarnings(scope, notices[:1]); err != nil {
+		t.Errorf("a notice failed the delta: %v", err)
+	}
+	err := checkWarnings(scope, notices)
+	var schemerErr *er.SchemerErr
+	if !errors.As(err, &schemerErr) || schemerErr.Code != "0219" || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("got %v", err)
+	}
+}
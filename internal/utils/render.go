@@ -0,0 +1,89 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	"github.com/jackc/pgx/v5"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+)
+
+// TemplateDirective opts a delta file into templating, e.g.
+//
+//	-- schemer:template
+//
+// It is read from the leading comments of each file, files without it run as written.
+const TemplateDirective = "schemer:template"
+
+// TemplateVars are the variables available to delta files as {{.name}}. They are set from
+// the vars in the config file, SCHEMER_VAR_* environment variables and --var.
+var TemplateVars = map[string]string{}
+
+// templateFuncs quote variables for the place they are used in a statement.
+var templateFuncs = template.FuncMap{
+	"ident": func(name string) string {
+		return pgx.Identifier{name}.Sanitize()
+	},
+	"literal": func(value string) string {
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	},
+}
+
+// RenderDelta renders the contents of a delta file through text/template with TemplateVars.
+// Referencing a variable that is not set is an error. Files without the template directive
+// are returned unchanged, even if they contain {{.
+//
+// Params:
+//   - file: delta file relative to the deltas directory, used in error messages
+//   - data: raw contents of the delta file
+//
+// Returns:
+//   - []byte: the SQL to execute
+//   - error: non-nil if the template is malformed or references a missing variable
+func RenderDelta(file string, data []byte) ([]byte, error) {
+	if _, ok := leadingDirective(data, TemplateDirective); !ok {
+		return data, nil
+	}
+
+	parsed, err := template.New(file).Funcs(templateFuncs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, &er.SchemerErr{
+			Code:    "0172",
+			Message: "failed to parse template in delta file: " + file,
+			Err:     err,
+		}
+	}
+
+	var rendered bytes.Buffer
+	if err := parsed.Execute(&rendered, TemplateVars); err != nil {
+		return nil, &er.SchemerErr{
+			Code:    "0173",
+			Message: "failed to render delta file: " + file,
+			Err:     err,
+		}
+	}
+	return rendered.Bytes(), nil
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"testing"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+)
+
+func TestRenderDelta(t *testing.T) {
+	original := TemplateVars
+	t.Cleanup(func() { TemplateVars = original })
+	TemplateVars = map[string]string{"app_role": "app", "tenant": "acme's"}
+
+	testCases := []struct {
+		name     string
+		data     string
+		expected string
+		code     string
+	}{
+		{name: "no actions", data: "-- schemer:template\nSELECT '{ not a template }';", expected: "-- schemer:template\nSELECT '{ not a template }';"},
+		{name: "no directive", data: "SELECT '{{.app_role}}', $${{ }}$$;", expected: "SELECT '{{.app_role}}', $${{ }}$$;"},
+		{name: "directive after statement", data: "SELECT 1;\n-- schemer:template\nSELECT '{{.app_role}}';", expected: "SELECT 1;\n-- schemer:template\nSELECT '{{.app_role}}';"},
+		{name: "variable", data: "-- schemer:template\nGRANT SELECT ON users TO {{.app_role}};", expected: "-- schemer:template\nGRANT SELECT ON users TO app;"},
+		{name: "after other directives", data: "-- schemer:env dev\n-- schemer:template\nGRANT SELECT ON users TO {{.app_role}};", expected: "-- schemer:env dev\n-- schemer:template\nGRANT SELECT ON users TO app;"},
+		{name: "quoting", data: "-- schemer:template\nCREATE SCHEMA {{ident .tenant}}; SELECT {{literal .tenant}};", expected: "-- schemer:template\nCREATE SCHEMA \"acme's\"; SELECT 'acme''s';"},
+		{name: "missing variable", data: "-- schemer:template\nGRANT SELECT ON users TO {{.reader_role}};", code: "0173"},
+		{name: "malformed", data: "-- schemer:template\nSELECT {{.app_role;", code: "0172"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rendered, err := RenderDelta("001_test.up.sql", []byte(tc.data))
+			if tc.code != "" {
+				if schemerErr, ok := err.(*er.SchemerErr); !ok || schemerErr.Code != tc.code {
+					t.Fatalf("expected error %s, got %v", tc.code, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderDelta failed: %v", err)
+			}
+			if string(rendered) != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, rendered)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+)
+
+const repeatableTableStatement = `
+CREATE TABLE IF NOT EXISTS %s (
+  file_name TEXT PRIMARY KEY,
+  checksum TEXT NOT NULL,
+  applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);`
+
+// EnsureRepeatableTable creates the schemer_repeatable table if it does not already exist.
+// The table holds the checksum of every repeatable delta as it was last applied.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn
+//   - ctx: context for executing the database operations
+//
+// Returns:
+//   - error: non-nil if the table could not be created
+func EnsureRepeatableTable(database *pgx.Conn, ctx context.Context) error {
+	if database == nil {
+		return &er.SchemerErr{
+			Code:    "0155",
+			Message: "recived nil database pointer.",
+		}
+	}
+
+	if _, err := database.Exec(ctx, fmt.Sprintf(repeatableTableStatement, RepeatableTable())); err != nil {
+		return &er.SchemerErr{
+			Code:    "0156",
+			Message: "failed to create schemer_repeatable table.",
+			Err:     err,
+		}
+	}
+	return nil
+}
+
+// GetAppliedRepeatables returns the checksum recorded for every applied repeatable delta.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn
+//   - ctx: context for executing the database operations
+//
+// Returns:
+//   - map[string]string: repeatable file relative to the deltas directory mapped to its checksum
+//   - error: non-nil if the query or scan fails
+func GetAppliedRepeatables(database *pgx.Conn, ctx context.Context) (map[string]string, error) {
+	rows, err := database.Query(ctx, fmt.Sprintf(`SELECT file_name, checksum FROM %s`, RepeatableTable()))
+	if err != nil {
+		return nil, &er.SchemerErr{
+			Code:    "0157",
+			Message: "failed to query schemer_repeatable table.",
+			Err:     err,
+		}
+	}
+	defer rows.Close()
+
+	result := make(map[string]string)
+	for rows.Next() {
+		var file, checksum string
+		if err := rows.Scan(&file, &checksum); err != nil {
+			return nil, &er.SchemerErr{
+				Code:    "0158",
+				Message: "failed to scan schemer_repeatable row.",
+				Err:     err,
+			}
+		}
+		result[file] = checksum
+	}
+	if err := rows.Err(); err != nil {
+		return nil, &er.SchemerErr{
+			Code:    "0159",
+			Message: "iteration failure on schemer_repeatable rows.",
+			Err:     err,
+		}
+	}
+	return result, nil
+}
+
+// RecordRepeatable stores the checksum of an applied repeatable delta, replacing any earlier record.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn, usually inside the delta's transaction
+//   - ctx: context for executing the database operations
+//   - file: repeatable file relative to the deltas directory
+//   - checksum: sha256 of the repeatable file
+//
+// Returns:
+//   - error: non-nil if the record could not be written
+func RecordRepeatable(database *pgx.Conn, ctx context.Context, file string, checksum string) error {
+	_, err := database.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (file_name, checksum, applied_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (file_name) DO UPDATE
+		SET checksum = EXCLUDED.checksum, applied_at = EXCLUDED.applied_at`, RepeatableTable()),
+		file, checksum)
+	if err != nil {
+		return &er.SchemerErr{
+			Code:    "0160",
+			Message: "failed to record repeatable delta: " + file,
+			Err:     err,
+		}
+	}
+	return nil
+}
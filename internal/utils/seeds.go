@@ -0,0 +1,143 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+)
+
+const seedTableStatement = `
+CREATE TABLE IF NOT EXISTS %s (
+  file_name TEXT PRIMARY KEY,
+  env TEXT NOT NULL,
+  checksum TEXT NOT NULL,
+  applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);`
+
+// EnsureSeedTable creates the schemer_seeds table if it does not already exist.
+// The table holds the checksum of every seed file applied by schemer seed.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn
+//   - ctx: context for executing the database operations
+//
+// Returns:
+//   - error: non-nil if the table could not be created
+func EnsureSeedTable(database *pgx.Conn, ctx context.Context) error {
+	if database == nil {
+		return &er.SchemerErr{
+			Code:    "0143",
+			Message: "recived nil database pointer.",
+		}
+	}
+
+	if _, err := database.Exec(ctx, fmt.Sprintf(seedTableStatement, SeedsTable())); err != nil {
+		return &er.SchemerErr{
+			Code:    "0144",
+			Message: "failed to create schemer_seeds table.",
+			Err:     err,
+		}
+	}
+	return nil
+}
+
+// GetAppliedSeeds returns the checksum recorded for every applied seed file.
+// A missing schemer_seeds table means nothing was seeded yet, the table is not created here.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn
+//   - ctx: context for executing the database operations
+//
+// Returns:
+//   - map[string]string: seed file relative to the seeds directory mapped to its checksum
+//   - error: non-nil if the query or scan fails
+func GetAppliedSeeds(database *pgx.Conn, ctx context.Context) (map[string]string, error) {
+	if exists, err := TableExists(database, ctx, SeedsTable()); err != nil {
+		return nil, err
+	} else if !exists {
+		return map[string]string{}, nil
+	}
+
+	rows, err := database.Query(ctx, fmt.Sprintf(`SELECT file_name, checksum FROM %s`, SeedsTable()))
+	if err != nil {
+		return nil, &er.SchemerErr{
+			Code:    "0145",
+			Message: "failed to query schemer_seeds table.",
+			Err:     err,
+		}
+	}
+	defer rows.Close()
+
+	result := make(map[string]string)
+	for rows.Next() {
+		var file, checksum string
+		if err := rows.Scan(&file, &checksum); err != nil {
+			return nil, &er.SchemerErr{
+				Code:    "0146",
+				Message: "failed to scan schemer_seeds row.",
+				Err:     err,
+			}
+		}
+		result[file] = checksum
+	}
+	if err := rows.Err(); err != nil {
+		return nil, &er.SchemerErr{
+			Code:    "0149",
+			Message: "iteration failure on schemer_seeds rows.",
+			Err:     err,
+		}
+	}
+	return result, nil
+}
+
+// RecordSeed stores the checksum of an applied seed file, replacing any earlier record.
+//
+// Params:
+//   - database: pointer to an open pgx.Conn, usually inside the seed's transaction
+//   - ctx: context for executing the database operations
+//   - file: seed file relative to the seeds directory
+//   - env: environment the seed was applied for
+//   - checksum: sha256 of the seed file
+//
+// Returns:
+//   - error: non-nil if the record could not be written
+func RecordSeed(database *pgx.Conn, ctx context.Context, file string, env string, checksum string) error {
+	_, err := database.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (file_name, env, checksum, applied_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT (file_name) DO UPDATE
+		SET env = EXCLUDED.env, checksum = EXCLUDED.checksum, applied_at = EXCLUDED.applied_at`, SeedsTable()),
+		file, env, checksum)
+	if err != nil {
+		return &er.SchemerErr{
+			Code:    "0148",
+			Message: "failed to record seed: " + file,
+			Err:     err,
+		}
+	}
+	return nil
+}
@@ -19,12 +19,68 @@ LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
 OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 THE SOFTWARE.
 */
-
 package utils
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+)
+
+// Versioning selects how schemer create tags a new delta group.
+type Versioning string
+
+const (
+	VersioningSequential Versioning = "sequential" // highest tag plus one, e.g. 015
+	VersioningTimestamp  Versioning = "timestamp"  // UTC creation time, e.g. 20261017143000
+)
+
+// TimestampTagLayout is the time layout of timestamp tags.
+const TimestampTagLayout = "20060102150405"
 
-// ToPrefix returns the tag as a zero padded string
-func ToPrefix(tag int) string {
+// ToPrefix returns the tag as a zero padded string. Timestamp tags are longer than the
+// padding and are returned as is.
+func ToPrefix(tag int64) string {
 	return fmt.Sprintf("%03d", tag)
 }
+
+// IsTimestampTag reports whether tag is a UTC timestamp created with VersioningTimestamp
+// rather than a sequential number.
+func IsTimestampTag(tag int64) bool {
+	_, err := time.Parse(TimestampTagLayout, strconv.FormatInt(tag, 10))
+	return err == nil
+}
+
+// NextTag returns the tag for a new delta group.
+// Timestamp tags always sort after legacy sequential tags, so both can live in one project.
+//
+// Params:
+//   - highest: the highest existing tag, 0 if there are no deltas
+//   - versioning: the versioning scheme of the project
+//   - now: the creation time used for timestamp tags
+//
+// Returns:
+//   - int64: the new tag, always greater than highest
+//   - error: non-nil if the versioning scheme is unknown
+func NextTag(highest int64, versioning Versioning, now time.Time) (int64, error) {
+	switch versioning {
+	case VersioningSequential, "":
+		return highest + 1, nil
+	case VersioningTimestamp:
+		tag, err := strconv.ParseInt(now.UTC().Format(TimestampTagLayout), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		// a delta created in the same second, or a clock behind an existing tag,
+		// must still sort after every existing delta
+		return max(tag, highest+1), nil
+	default:
+		return 0, &er.SchemerErr{
+			Code:    "0177",
+			Kind:    er.KindUsage,
+			Message: fmt.Sprintf("unknown versioning %q, expected %s or %s", versioning, VersioningSequential, VersioningTimestamp),
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	er "github.com/inskribe/schemer/internal/errschemer"
+)
+
+func TestNextTag(t *testing.T) {
+	now := time.Date(2026, 10, 17, 16, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	testCases := []struct {
+		name       string
+		highest    int64
+		versioning Versioning
+		expected   int64
+	}{
+		{name: "sequential", highest: 14, versioning: VersioningSequential, expected: 15},
+		{name: "default", highest: 0, expected: 1},
+		{name: "timestamp after legacy tags", highest: 14, versioning: VersioningTimestamp, expected: 20261017143000},
+		{name: "timestamp in the same second", highest: 20261017143000, versioning: VersioningTimestamp, expected: 20261017143001},
+		{name: "sequential after timestamps", highest: 20261017143000, versioning: VersioningSequential, expected: 20261017143001},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tag, err := NextTag(tc.highest, tc.versioning, now)
+			if err != nil {
+				t.Fatalf("NextTag failed: %v", err)
+			}
+			if tag != tc.expected {
+				t.Fatalf("expected %d, got %d", tc.expected, tag)
+			}
+		})
+	}
+
+	_, err := NextTag(0, "semver", now)
+	if schemerErr, ok := err.(*er.SchemerErr); !ok || schemerErr.Code != "0177" {
+		t.Fatalf("expected unknown versioning to fail with 0177, got %v", err)
+	}
+}
+
+func TestIsTimestampTag(t *testing.T) {
+	for tag, expected := range map[int64]bool{
+		0:              false,
+		15:             false,
+		20261017143000: true,
+		20261399143000: false,
+	} {
+		if IsTimestampTag(tag) != expected {
+			t.Errorf("expected IsTimestampTag(%d) to be %v", tag, expected)
+		}
+		if tag == 15 && ToPrefix(tag) != "015" {
+			t.Errorf("expected legacy tags to stay zero padded, got %s", ToPrefix(tag))
+		}
+	}
+	if ToPrefix(20261017143000) != "20261017143000" {
+		t.Errorf("expected timestamp tags to be printed in full")
+	}
+}
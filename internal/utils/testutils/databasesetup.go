@@ -52,8 +52,14 @@ func SetupTestDatabase() error {
 	}
 
 	ctx := context.Background()
-	dns, ok := os.LookupEnv("DATABASE_URL")
-	if !ok || dns == "" {
+	dns, err := scratchTestURL()
+	if err != nil {
+		return fmt.Errorf("failed to setup database: scratch: %v", err)
+	}
+	if dns == "" {
+		dns = os.Getenv("DATABASE_URL")
+	}
+	if dns == "" {
 		return fmt.Errorf("failed to find env var for mock database.")
 	}
 
@@ -79,7 +85,7 @@ func SetupTestTable(t *testing.T) {
 	_, err := SharedConnection.Exec(ctx, `
 		DROP TABLE IF EXISTS schemer;
 		CREATE TABLE schemer (
-			tag INT PRIMARY KEY,
+			tag BIGINT PRIMARY KEY,
 			post_status INT DEFAULT 0,
 			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)
@@ -101,5 +107,10 @@ func TearDown() error {
 	if err := SharedConnection.Close(context.Background()); err != nil {
 		return fmt.Errorf("%s", err.Error())
 	}
+	if sharedScratch != nil {
+		if err := sharedScratch.Drop(); err != nil {
+			return fmt.Errorf("%s", err.Error())
+		}
+	}
 	return nil
 }
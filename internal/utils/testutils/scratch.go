@@ -0,0 +1,59 @@
+package testutils
+
+import (
+	"os"
+	"testing"
+
+	"github.com/inskribe/schemer/internal/scratch"
+)
+
+// AdminURLKey names the environment variable holding an admin connection string.
+// When it is set, SetupTestDatabase runs each test package against its own scratch
+// database instead of the shared DATABASE_URL.
+const AdminURLKey = "SCHEMER_TEST_ADMIN_URL"
+
+// sharedScratch is the scratch database behind SharedConnection, nil when DATABASE_URL is used.
+var sharedScratch *scratch.Database
+
+// NewScratchDatabase creates a uniquely named database for a single test and drops it
+// when the test finishes. Pass apply.MigrateAll as options.Setup to run every delta and
+// options.Template to clone an already migrated database.
+//
+// The test is skipped if adminConnString is empty, so it can be read straight from the environment:
+//
+//	connString := testutils.NewScratchDatabase(t, os.Getenv(testutils.AdminURLKey), scratch.Options{})
+func NewScratchDatabase(t testing.TB, adminConnString string, options scratch.Options) string {
+	t.Helper()
+	if adminConnString == "" {
+		t.Skip("no admin connection string, set " + AdminURLKey + " to run scratch database tests")
+	}
+
+	database, err := scratch.Create(adminConnString, options)
+	if err != nil {
+		t.Fatalf("failed to create scratch database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := database.Drop(); err != nil {
+			t.Errorf("failed to drop scratch database: %v", err)
+		}
+	})
+	return database.ConnString
+}
+
+// scratchTestURL creates the package wide scratch database when AdminURLKey is set.
+//
+// Returns:
+//   - string: connection string of the scratch database, empty if AdminURLKey is not set
+//   - error: non-nil if the database cannot be created
+func scratchTestURL() (string, error) {
+	admin := os.Getenv(AdminURLKey)
+	if admin == "" {
+		return "", nil
+	}
+	database, err := scratch.Create(admin, scratch.Options{})
+	if err != nil {
+		return "", err
+	}
+	sharedScratch = database
+	return database.ConnString, nil
+}
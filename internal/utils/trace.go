@@ -0,0 +1,284 @@
+/*
+Copyright © 2025 Roy Sowers <inskribe@inskribestudio.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package utils
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/inskribe/schemer/internal/glog"
+	"github.com/inskribe/schemer/internal/sqltoken"
+)
+
+// TraceSettings control --trace-sql.
+type TraceSettings struct {
+	Enabled   bool // log every statement sent to the database
+	MaxLength int  // statements are truncated to this many characters, 0 logs them in full
+}
+
+// Trace holds the settings of --trace-sql, set from the command line.
+var Trace = TraceSettings{MaxLength: 200}
+
+// traceSummarySize is the number of statements listed by LogTraceSummary.
+const traceSummarySize = 10
+
+// TracedStatement is a statement logged by --trace-sql.
+type TracedStatement struct {
+	Scope     TraceScope
+	SQL       string // whitespace collapsed, not truncated
+	Duration  time.Duration
+	Command   string // command tag, e.g. INSERT 0 5, empty if the statement failed
+	Rows      int64  // rows affected or returned
+	Failed    bool
+	startedAt time.Time
+}
+
+// TraceScope names the delta a statement belongs to. The zero scope stands for the statements
+// schemer runs itself, e.g. on the tracking table.
+type TraceScope struct {
+	Direction Direction
+	Tag       *int64 // nil for repeatable deltas and seeds
+	File      string
+}
+
+type traceStartKey struct{}
+
+var (
+	tracedMu sync.Mutex
+	traced   []TracedStatement
+)
+
+// sqlTracer logs the statements schemer sends through pgx.Conn, installed by WithConn
+// when --trace-sql is set. Delta files bypass it, see execDelta.
+type sqlTracer struct{}
+
+func (sqlTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, traceStartKey{}, TracedStatement{
+		SQL:       data.SQL,
+		startedAt: time.Now(),
+	})
+}
+
+func (sqlTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	statement, ok := ctx.Value(traceStartKey{}).(TracedStatement)
+	if !ok {
+		return
+	}
+	statement.Duration = time.Since(statement.startedAt)
+	statement.finish(data.CommandTag, data.Err)
+	recordTrace(statement)
+}
+
+// finish sets the outcome of a statement.
+func (statement *TracedStatement) finish(tag pgconn.CommandTag, err error) {
+	statement.Failed = err != nil
+	if !statement.Failed {
+		statement.Command = tag.String()
+		statement.Rows = tag.RowsAffected()
+	}
+}
+
+// ExecDelta executes the SQL of a delta file and gathers the notices the server raises meanwhile.
+// With --trace-sql each statement of the file is logged as the server completes it; the file is
+// still sent as a single simple query, so it keeps running in one implicit transaction.
+// With --fail-on-warning a WARNING notice fails the delta after it ran.
+//
+// Params:
+//   - connection: pointer to an open pgx.Conn
+//   - ctx: context for executing the statements
+//   - scope: the delta being executed
+//   - sql: rendered SQL of the delta file
+//
+// Returns:
+//   - []Notice: notices raised by the delta
+//   - error: the execution error, or a SchemerErr for a warning with --fail-on-warning
+func ExecDelta(connection *pgx.Conn, ctx context.Context, scope TraceScope, sql string) ([]Notice, error) {
+	stop := collectNotices(connection.PgConn(), scope)
+	err := execDelta(connection, ctx, scope, sql)
+	notices := stop()
+	if err == nil {
+		err = checkWarnings(scope, notices)
+	}
+	return notices, err
+}
+
+// execDelta executes the SQL of a delta file, tracing each statement with --trace-sql.
+func execDelta(connection *pgx.Conn, ctx context.Context, scope TraceScope, sql string) error {
+	if !Trace.Enabled {
+		_, err := connection.Exec(ctx, sql)
+		return err
+	}
+
+	// Mirrors pgx.Conn.Exec without arguments, reading the results one at a time. A statement
+	// that fails before returning rows has no result, it is the one after the last result read.
+	statements := splitStatements(sql)
+	results := connection.PgConn().Exec(ctx, sql)
+	last := time.Now()
+	index := 0
+	for ; results.NextResult(); index++ {
+		tag, err := results.ResultReader().Close()
+		traceResult(scope, statements, index, &last, tag, err)
+		if err != nil {
+			return results.Close()
+		}
+	}
+	err := results.Close()
+	if err != nil {
+		traceResult(scope, statements, index, &last, pgconn.CommandTag{}, err)
+	}
+	return err
+}
+
+// traceResult records the statement at index of a delta file.
+func traceResult(scope TraceScope, statements []string, index int, last *time.Time, tag pgconn.CommandTag, err error) {
+	statement := TracedStatement{Scope: scope, Duration: time.Since(*last)}
+	if index < len(statements) {
+		statement.SQL = statements[index]
+	}
+	statement.finish(tag, err)
+	recordTrace(statement)
+	*last = time.Now()
+}
+
+// splitStatements splits a delta file into statements the way the server does, with comments
+// dropped and whitespace collapsed.
+func splitStatements(sql string) []string {
+	var statements []string
+	var current strings.Builder
+	flush := func() {
+		if text := strings.TrimSpace(current.String()); text != "" {
+			statements = append(statements, text)
+		}
+		current.Reset()
+	}
+	for _, token := range sqltoken.Tokenize(sql) {
+		switch {
+		case token.Kind == sqltoken.Semicolon:
+			flush()
+		case token.IsTrivia():
+			current.WriteByte(' ')
+		default:
+			current.WriteString(token.Text)
+		}
+	}
+	flush()
+	return statements
+}
+
+// recordTrace logs a statement and keeps it for the summary.
+func recordTrace(statement TracedStatement) {
+	statement.SQL = strings.Join(strings.Fields(statement.SQL), " ")
+
+	attrs := statement.Scope.attrs()
+	attrs = append(attrs,
+		slog.String("sql", truncateStatement(statement.SQL)),
+		slog.Float64("duration_ms", float64(statement.Duration.Microseconds())/1000),
+	)
+	level := slog.LevelInfo
+	if statement.Failed {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.Bool("failed", true))
+	} else {
+		attrs = append(attrs, slog.String("command", statement.Command), slog.Int64("rows", statement.Rows))
+	}
+	glog.LogAttrs(level, "sql", attrs...)
+
+	tracedMu.Lock()
+	traced = append(traced, statement)
+	tracedMu.Unlock()
+}
+
+// attrs returns the log attributes naming the scope.
+func (scope TraceScope) attrs() []slog.Attr {
+	if scope.File == "" {
+		return []slog.Attr{slog.String("source", "schemer")}
+	}
+	attrs := []slog.Attr{slog.String("source", string(scope.Direction))}
+	if scope.Tag != nil {
+		attrs = append(attrs, slog.Int64("tag", *scope.Tag))
+	}
+	return append(attrs, slog.String("file", scope.File))
+}
+
+// String names the scope in the summary, e.g. up 004_add_users.up.sql.
+func (scope TraceScope) String() string {
+	if scope.File == "" {
+		return "schemer"
+	}
+	return string(scope.Direction) + " " + scope.File
+}
+
+// truncateStatement shortens a statement to Trace.MaxLength characters.
+func truncateStatement(sql string) string {
+	runes := []rune(sql)
+	if Trace.MaxLength <= 0 || len(runes) <= Trace.MaxLength {
+		return sql
+	}
+	return string(runes[:Trace.MaxLength]) + "..."
+}
+
+// TracedStatements returns the statements logged by --trace-sql so far, slowest first.
+//
+// Returns:
+//   - []TracedStatement: a copy of the traced statements
+func TracedStatements() []TracedStatement {
+	tracedMu.Lock()
+	statements := slices.Clone(traced)
+	tracedMu.Unlock()
+
+	slices.SortStableFunc(statements, func(a, b TracedStatement) int {
+		return cmp.Compare(b.Duration, a.Duration)
+	})
+	return statements
+}
+
+// LogTraceSummary logs the slowest statements of the run when --trace-sql is set.
+func LogTraceSummary() {
+	if !Trace.Enabled {
+		return
+	}
+	statements := TracedStatements()
+	if len(statements) == 0 {
+		return
+	}
+	var total time.Duration
+	for _, statement := range statements {
+		total += statement.Duration
+	}
+
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "Traced %d statement(s) in %s, slowest:", len(statements), total.Round(time.Millisecond))
+	for _, statement := range statements[:min(len(statements), traceSummarySize)] {
+		fmt.Fprintf(&summary, "\n  %10s  %s  %s", statement.Duration.Round(time.Microsecond), statement.Scope, truncateStatement(statement.SQL))
+	}
+	glog.Info("%s", summary.String())
+}
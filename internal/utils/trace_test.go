@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/inskribe/schemer/internal/glog"
+)
+
+func TestSplitStatements(t *testing.T) {
+	sql := `-- add users
+CREATE TABLE users (
+	id   bigint, -- key
+	name text
+);;
+INSERT INTO users VALUES (1, 'a;b');
+DO $$ BEGIN RAISE NOTICE 'x;y'; END $$;
+/* trailing */`
+
+	want := []string{
+		"CREATE TABLE users ( id bigint, name text )",
+		"INSERT INTO users VALUES (1, 'a;b')",
+		"DO $$ BEGIN RAISE NOTICE 'x;y'; END $$",
+	}
+	got := splitStatements(sql)
+	for i := range got {
+		got[i] = strings.Join(strings.Fields(got[i]), " ")
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTruncateStatement(t *testing.T) {
+	original := Trace
+	t.Cleanup(func() { Trace = original })
+
+	Trace.MaxLength = 6
+	if got := truncateStatement("SELECT 1"); got != "SELECT..." {
+		t.Errorf("got %q", got)
+	}
+	Trace.MaxLength = 0
+	if got := truncateStatement("SELECT 1"); got != "SELECT 1" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestRecordTrace(t *testing.T) {
+	original := traced
+	t.Cleanup(func() {
+		traced = original
+		glog.InitializeLogger(true)
+	})
+	traced = nil
+
+	var buf bytes.Buffer
+	glog.Configure(glog.Options{Level: slog.LevelInfo, Format: glog.FormatText, Writer: &buf})
+
+	tag := int64(4)
+	scope := TraceScope{Direction: DirectionUp, Tag: &tag, File: "004_users.up.sql"}
+
+	fast := TracedStatement{Scope: scope, SQL: "INSERT INTO users\n  VALUES (1)", Duration: time.Millisecond}
+	fast.finish(pgconn.NewCommandTag("INSERT 0 1"), nil)
+	recordTrace(fast)
+
+	slow := TracedStatement{SQL: "UPDATE schemer SET post_status = 2", Duration: time.Second}
+	slow.finish(pgconn.CommandTag{}, errors.New("boom"))
+	recordTrace(slow)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if want := `[Info]: sql source=up tag=4 file=004_users.up.sql sql="INSERT INTO users VALUES (1)" duration_ms=1 command="INSERT 0 1" rows=1`; lines[0] != want {
+		t.Errorf("got  %s\nwant %s", lines[0], want)
+	}
+	if !strings.HasPrefix(lines[1], "[Warn]: sql source=schemer ") || !strings.HasSuffix(lines[1], "failed=true") {
+		t.Errorf("got %s", lines[1])
+	}
+
+	statements := TracedStatements()
+	if len(statements) != 2 || statements[0].SQL != slow.SQL {
+		t.Errorf("statements are not sorted slowest first: %+v", statements)
+	}
+}
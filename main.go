@@ -26,7 +26,10 @@ import (
 
 	_ "github.com/inskribe/schemer/cmd/apply"
 	_ "github.com/inskribe/schemer/cmd/create"
+	_ "github.com/inskribe/schemer/cmd/explain"
 	_ "github.com/inskribe/schemer/cmd/init"
+	_ "github.com/inskribe/schemer/cmd/lint"
+	_ "github.com/inskribe/schemer/cmd/validate"
 )
 
 func main() {